@@ -0,0 +1,136 @@
+package catsapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Log level read and changed through the admin API
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+func (body LogLevel) Validate() error {
+	if !slices.Contains(logLevels, body.Level) {
+		return badRequest(fmt.Sprintf("Invalid input, the level must be one of %s", strings.Join(logLevels, ", ")))
+	}
+	return nil
+}
+
+// Build of the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Reads the build of the binary, the VCS settings being missing when built outside of a repository
+func readBuildInfo() BuildInfo {
+	build := BuildInfo{Version: version}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	build.GoVersion = info.GoVersion
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.time":
+			build.Time = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+}
+
+func init() {
+	expvar.Publish("build", expvar.Func(func() any { return readBuildInfo() }))
+	expvar.Publish("logLevel", expvar.Func(func() any { return logLevel() }))
+	expvar.Publish("catsCache", expvar.Func(func() any { return catsCacheStats() }))
+	expvar.Publish("replication", expvar.Func(func() any {
+		if replication == nil {
+			return nil
+		}
+		return replication.status()
+	}))
+}
+
+func getLogLevel(ctx context.Context, req *http.Request, body struct{}) (LogLevel, error) {
+	return LogLevel{logLevel()}, nil
+}
+
+func putLogLevel(ctx context.Context, req *http.Request, body LogLevel) (LogLevel, error) {
+	if err := setLogLevel(body.Level); err != nil {
+		return LogLevel{}, err
+	}
+	Logger.Warnf("Log level set to %s", body.Level)
+	return body, nil
+}
+
+func getBuildInfo(ctx context.Context, req *http.Request, body struct{}) (BuildInfo, error) {
+	return readBuildInfo(), nil
+}
+
+// Makes the handler of the admin API, which requires the token as a bearer one
+func newAdminHandler(token string) http.Handler {
+	router := http.NewServeMux()
+	router.HandleFunc("GET /log-level", Handle(http.StatusOK, getLogLevel))
+	router.HandleFunc("PUT /log-level", Handle(http.StatusOK, putLogLevel))
+	router.HandleFunc("GET /build-info", Handle(http.StatusOK, getBuildInfo))
+	router.Handle("GET /debug/vars", expvar.Handler())
+	// The profiles are on this router only, not on the default one of net/http/pprof
+	router.HandleFunc("/debug/pprof/", pprof.Index)
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		given, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			res.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeJSON(res, http.StatusUnauthorized, "Invalid admin token")
+			return
+		}
+		router.ServeHTTP(res, req)
+	})
+}
+
+// Serves the admin API on its own port, when its token is set.
+// The TLS configuration, if any, does not ask for client certificates: the operators have the token.
+func serveAdmin(port, token string, tlsConfig *tls.Config) *http.Server {
+	if token == "" {
+		Logger.Info("Admin API disabled, ADMIN_TOKEN is not set")
+		return nil
+	}
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           newAdminHandler(token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if tlsConfig != nil {
+		server.TLSConfig = tlsConfig.Clone()
+		server.TLSConfig.ClientAuth, server.TLSConfig.ClientCAs = tls.NoClientCert, nil
+	}
+	go func() {
+		Logger.Infof("Admin server listening on %v", server.Addr)
+		if err := listenAndServe(server); !errors.Is(err, http.ErrServerClosed) {
+			Logger.Error("Admin server stopped: ", err)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,118 @@
+package catsapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func doAdminRequest(handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// Test that the admin routes require the token
+func TestAdminAuth(t *testing.T) {
+	handler := newAdminHandler("admin-token")
+	for _, path := range []string{"/log-level", "/build-info", "/debug/vars", "/debug/pprof/"} {
+		for _, token := range []string{"", "wrong-token"} {
+			rr := doAdminRequest(handler, "GET", path, token, "")
+			if rr.Code != http.StatusUnauthorized || rr.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("Expected %s to refuse the token %q, got %d", path, token, rr.Code)
+			}
+		}
+		if rr := doAdminRequest(handler, "GET", path, "admin-token", ""); rr.Code != http.StatusOK {
+			t.Errorf("Expected %s to answer, got %d", path, rr.Code)
+		}
+	}
+}
+
+// Test the change of the log level at runtime
+func TestAdminLogLevel(t *testing.T) {
+	t.Cleanup(func() { setLogLevel("debug") })
+	handler := newAdminHandler("admin-token")
+
+	var level LogLevel
+	rr := doAdminRequest(handler, "GET", "/log-level", "admin-token", "")
+	if json.Unmarshal(rr.Body.Bytes(), &level); level.Level != "debug" {
+		t.Errorf("Expected the debug level by default, got %s", rr.Body.String())
+	}
+
+	rr = doAdminRequest(handler, "PUT", "/log-level", "admin-token", `{"level": "warning"}`)
+	if rr.Code != http.StatusOK || logLevel() != "warning" {
+		t.Errorf("Expected the level to change, got %d with %s", rr.Code, logLevel())
+	}
+	rr = doAdminRequest(handler, "PUT", "/log-level", "admin-token", `{"level": "verbose"}`)
+	if rr.Code != http.StatusBadRequest || logLevel() != "warning" {
+		t.Errorf("Expected an unknown level to be refused, got %d with %s", rr.Code, logLevel())
+	}
+
+	rr = doAdminRequest(handler, "GET", "/debug/vars", "admin-token", "")
+	if !strings.Contains(rr.Body.String(), `"logLevel": "warning"`) {
+		t.Errorf("Expected the level in the published variables, got %s", rr.Body.String())
+	}
+}
+
+// Argument counting its formatting
+type countingStringer struct {
+	count *int
+}
+
+func (stringer countingStringer) String() string {
+	*stringer.count++
+	return "formatted"
+}
+
+// Test that the lines above the level are dropped before being formatted, whatever the version in the lines
+func TestLogLevelFilter(t *testing.T) {
+	originalVersion := version
+	version = "10.20.300-rc.1+local"
+	t.Cleanup(func() {
+		version = originalVersion
+		setLogLevel("debug")
+	})
+	var out bytes.Buffer
+	logger := initLogging(&out)
+
+	formatted := 0
+	setLogLevel("warning")
+	logger.Infof("hidden %v", countingStringer{&formatted})
+	logger.Warn("shown")
+	setLogLevel("info")
+	logger.Info("shown again ", countingStringer{&formatted})
+	logger.Debug("hidden again ", countingStringer{&formatted})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " shown") || !strings.HasSuffix(lines[1], " shown again formatted") {
+		t.Fatalf("Expected the warning and info lines only, got %q", out.String())
+	}
+	if !strings.Contains(lines[0], " 10.20.300-rc.1+local W adminServer_test.go:") {
+		t.Errorf("Expected the version, the level and the caller in the line, got %q", lines[0])
+	}
+	if formatted != 1 {
+		t.Errorf("Expected only the written line to be formatted, got %d", formatted)
+	}
+}
+
+// Test the build information, without the VCS settings of the test binary
+func TestAdminBuildInfo(t *testing.T) {
+	var build BuildInfo
+	rr := doAdminRequest(newAdminHandler("admin-token"), "GET", "/build-info", "admin-token", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &build); err != nil {
+		t.Fatal(err)
+	}
+	if build.Version != version || !strings.HasPrefix(build.GoVersion, "go") {
+		t.Errorf("Unexpected build information %+v", build)
+	}
+}
@@ -0,0 +1,209 @@
+package catsapi
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stored cat entity, shown as CatV1 or CatV2 depending on the API version
+type Cat struct {
+	Name      string `json:"name"`
+	ID        string `json:"id,omitempty"`
+	BirthDate string `json:"birthDate,omitempty"`
+	Color     string `json:"color,omitempty"`
+	OwnerID   string `json:"ownerId,omitempty"`
+
+	// Since the v2 API
+	Sex         string     `json:"sex,omitempty"`
+	Breed       string     `json:"breed,omitempty"`
+	MicrochipID string     `json:"microchipId,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+
+	// Set when the cat is in the trash
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+// Cat representation of the v1 API, without the fields added since
+type CatV1 struct {
+	Name      string     `json:"name"`
+	ID        string     `json:"id,omitempty"`
+	BirthDate string     `json:"birthDate,omitempty"`
+	Color     string     `json:"color,omitempty"`
+	OwnerID   string     `json:"ownerId,omitempty"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+func (cat Cat) toV1() CatV1 {
+	return CatV1{
+		Name:      cat.Name,
+		ID:        cat.ID,
+		BirthDate: cat.BirthDate,
+		Color:     cat.Color,
+		OwnerID:   cat.OwnerID,
+		DeletedAt: cat.DeletedAt,
+	}
+}
+
+// Guards the in-memory databases of all the tenants against concurrent requests
+var dbLock sync.RWMutex
+
+// Lists the IDs of the cats which are not in the trash
+func listMapKeys(aMap map[string]Cat) []string {
+	results := []string{}
+
+	for catID, cat := range aMap {
+		if cat.DeletedAt == nil {
+			results = append(results, catID)
+		}
+	}
+
+	return results
+}
+
+// Gets a cat which is not in the trash, the caller must hold the DB lock
+func (db *tenantDB) findCat(catID string) (Cat, bool) {
+	cat, found := db.Cats[catID]
+	return cat, found && cat.DeletedAt == nil
+}
+
+// Size of the cats pages when the client does not tell, and its maximum
+const (
+	defaultCatsPageSize = 20
+	maxCatsPageSize     = 100
+)
+
+// Gets a page of the live cats matching the filter, ordered by ID and starting after afterID.
+// Also returns the count of the matching cats, and whether there is a next page.
+func (db *tenantDB) pageCats(matches func(Cat) bool, afterID string, size int) ([]Cat, int, bool) {
+	dbLock.RLock()
+	cats := []Cat{}
+	for catID, cat := range db.Cats {
+		cat.ID = catID
+		if cat.DeletedAt == nil && matches(cat) {
+			cats = append(cats, cat)
+		}
+	}
+	dbLock.RUnlock()
+	slices.SortFunc(cats, func(a, b Cat) int { return strings.Compare(a.ID, b.ID) })
+
+	start, found := slices.BinarySearchFunc(cats, afterID, func(cat Cat, id string) int { return strings.Compare(cat.ID, id) })
+	if found {
+		start++
+	}
+	end := min(start+size, len(cats))
+	return cats[start:end], len(cats), end < len(cats)
+}
+
+// Opaque cursor of the cats pages
+func encodeCatCursor(catID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(catID))
+}
+
+func decodeCatCursor(cursor string) (string, bool) {
+	catID, err := base64.RawURLEncoding.DecodeString(cursor)
+	return string(catID), err == nil
+}
+
+func listCats(ctx context.Context, req *http.Request, _ struct{}) ([]string, error) {
+	Logger.Info("Listing the cats")
+	return requestDB(ctx).readCatIDs()
+}
+
+func createCat(ctx context.Context, req *http.Request, catCreationData CatV1) (string, error) {
+	Logger.Info("Creating the cat: ", catCreationData)
+
+	cat, err := requestDB(ctx).insertCat(ctx, Cat{
+		Name:      catCreationData.Name,
+		BirthDate: catCreationData.BirthDate,
+		Color:     catCreationData.Color,
+		OwnerID:   catCreationData.OwnerID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return cat.ID, nil
+}
+
+// Validates and stores a new cat within the quota of the tenant, shared by all the APIs
+func (db *tenantDB) insertCat(ctx context.Context, cat Cat) (Cat, error) {
+
+	// Creating the new cat's ID and storing the Cat
+	newCatID := uuid.New().String()
+	cat.ID = newCatID
+	cat.DeletedAt = nil
+
+	dbLock.Lock()
+	if _, found := db.Owners[cat.OwnerID]; cat.OwnerID != "" && !found {
+		dbLock.Unlock()
+		Logger.Infof("Owner '%s' not found in the DB", cat.OwnerID)
+		return cat, badRequest("Owner not found")
+	}
+	if err := db.checkCatsQuota(1); err != nil {
+		dbLock.Unlock()
+		return cat, err
+	}
+	cat, err := db.saveCat(ctx, "create", newCatID, nil, cat)
+	dbLock.Unlock()
+	if err != nil {
+		return cat, err
+	}
+
+	Logger.Infof("Cat '%s' saved into the DB", newCatID)
+	return cat, nil
+}
+
+// Stores a new or changed cat with its change times, and reports the change.
+// The caller must hold the DB write lock.
+func (db *tenantDB) saveCat(ctx context.Context, action string, catID string, before *Cat, cat Cat) (Cat, error) {
+	now := time.Now().UTC()
+	if before == nil {
+		cat.CreatedAt = &now
+	}
+	cat.UpdatedAt = &now
+	if err := db.storeCat(catID, &cat); err != nil {
+		return cat, err
+	}
+	db.Cats[catID] = cat
+	db.catChanged(ctx, action, catID, before, &cat)
+	return cat, nil
+}
+
+// Moves the cat to the trash, it is permanently deleted after the retention period
+func deleteCat(ctx context.Context, req *http.Request, _ struct{}) (struct{}, error) {
+	catID := req.PathValue("catId")
+	Logger.Infof("Deleting the cat: %s", catID)
+
+	dbLock.Lock()
+	defer dbLock.Unlock()
+
+	trashed, err := requestDB(ctx).trashCat(ctx, catID, time.Now())
+	if err != nil {
+		return struct{}{}, err
+	}
+	if !trashed {
+		Logger.Infof("Cat '%s' not found in the DB", catID)
+		return struct{}{}, notFound("Cat not found")
+	}
+
+	Logger.Infof("Cat '%s' moved to the trash", catID)
+	return struct{}{}, nil
+}
+
+// Permanently removes the cat and its dependent data, the caller must hold the DB write lock.
+// The returned photos still have to be deleted from the blob store.
+func (db *tenantDB) removeCat(catID string) ([]Photo, error) {
+	if err := db.storeCat(catID, nil); err != nil {
+		return nil, err
+	}
+	delete(db.Cats, catID)
+	db.deleteCatRecords(catID)
+	return db.detachCatPhotos(catID), nil
+}
@@ -1,4 +1,4 @@
-package main
+package catsapi
 
 import (
 	"encoding/json"
@@ -0,0 +1,184 @@
+package catsapi
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+//go:embed swagger-ui
+var content embed.FS
+
+func logReq(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Get server identification
+		port := getEnv("PORT", "8080")
+		hostname, _ := os.Hostname()
+		serverID := hostname + ":" + port
+
+		// Add server identification to response headers
+		w.Header().Set("X-Server-ID", serverID)
+		w.Header().Set("X-Container-Name", hostname)
+		w.Header().Set("X-Server-Port", port)
+
+		Logger.Infof("🌐 [Server: %s] New request to: '%s %s' from %s",
+			serverID, r.Method, r.RequestURI, r.RemoteAddr)
+		next.ServeHTTP(w, r)
+	})
+}
+
+type contextKey string
+
+const requestIDKey contextKey = "requestID"
+
+// Tags each request with an ID, reusing the one set by the proxy if any
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, requestID)))
+	})
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// NewApp creates the handler of all the routes, serving the databases of the package
+func NewApp() http.Handler {
+	Logger.Info("Init the backend")
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /{$}", uiListCats)
+	router.HandleFunc("GET /cats/new", uiNewCat)
+	router.HandleFunc("POST /cats", uiCreateCat)
+	router.HandleFunc("GET /cats/{catId}", uiShowCat)
+	router.HandleFunc("GET /cats/{catId}/edit", uiEditCat)
+	router.HandleFunc("POST /cats/{catId}", uiUpdateCat)
+	router.HandleFunc("POST /cats/{catId}/delete", uiDeleteCat)
+	router.HandleFunc("POST /api/cats", deprecatedV1("/api/v2/cats", Handle(http.StatusCreated, createCat)))
+	router.HandleFunc("GET /api/cats", deprecatedV1("/api/v2/cats", Handle(http.StatusOK, listCats)))
+	router.HandleFunc("GET /api/cats/trash", Handle(http.StatusOK, listTrash))
+	router.HandleFunc("GET /api/cats/events", streamCatEvents)
+	router.HandleFunc("GET /api/cats/{catId}", deprecatedV1("/api/v2/cats/{catId}", Handle(http.StatusOK, getCat)))
+	router.HandleFunc("POST /api/cats/{catAction}", Handle(http.StatusOK, catCustomMethod))
+	router.HandleFunc("GET /api/cats/{catId}/history", Handle(http.StatusOK, getCatHistory))
+	router.HandleFunc("GET /api/audit", Handle(http.StatusOK, listAudit))
+	router.HandleFunc("DELETE /api/cats/{catId}", deprecatedV1("/api/v2/cats/{catId}", Handle(http.StatusNoContent, deleteCat)))
+	router.HandleFunc("PUT /api/cats/{catId}/owner", deprecatedV1("/api/v2/cats/{catId}", Handle(http.StatusOK, transferCat)))
+	router.HandleFunc("POST /api/cats/{catId}/photos", Handle(http.StatusCreated, uploadCatPhoto))
+	router.HandleFunc("GET /api/cats/{catId}/photos", Handle(http.StatusOK, listCatPhotos))
+	router.HandleFunc("GET /api/cats/{catId}/photos/{photoId}", getCatPhoto)
+	router.HandleFunc("POST /api/cats/{catId}/records", Handle(http.StatusCreated, createCatRecord))
+	router.HandleFunc("GET /api/cats/{catId}/records", Handle(http.StatusOK, listCatRecords))
+	router.HandleFunc("GET /api/cats/{catId}/records/{recordId}", Handle(http.StatusOK, getCatRecord))
+	router.HandleFunc("DELETE /api/cats/{catId}/records/{recordId}", Handle(http.StatusNoContent, deleteCatRecord))
+	router.HandleFunc("GET /api/records/due", Handle(http.StatusOK, listDueVaccinations))
+	router.HandleFunc("POST /api/webhooks", Handle(http.StatusCreated, createWebhook))
+	router.HandleFunc("GET /api/webhooks", Handle(http.StatusOK, listWebhooks))
+	router.HandleFunc("GET /api/webhooks/dead-letters", Handle(http.StatusOK, listDeadLetters))
+	router.HandleFunc("GET /api/webhooks/{webhookId}", Handle(http.StatusOK, getWebhook))
+	router.HandleFunc("DELETE /api/webhooks/{webhookId}", Handle(http.StatusNoContent, deleteWebhook))
+	router.HandleFunc("GET /api/webhooks/{webhookId}/deliveries", Handle(http.StatusOK, listWebhookDeliveries))
+	router.HandleFunc("POST /api/owners", Handle(http.StatusCreated, createOwner))
+	router.HandleFunc("GET /api/owners", Handle(http.StatusOK, listOwners))
+	router.HandleFunc("GET /api/owners/{ownerId}", Handle(http.StatusOK, getOwner))
+	router.HandleFunc("PUT /api/owners/{ownerId}", Handle(http.StatusOK, updateOwner))
+	router.HandleFunc("DELETE /api/owners/{ownerId}", Handle(http.StatusNoContent, deleteOwner))
+	router.HandleFunc("GET /api/owners/{ownerId}/cats", Handle(http.StatusOK, listOwnerCats))
+	router.HandleFunc("POST /api/v2/cats", Handle(http.StatusCreated, createCatV2))
+	router.HandleFunc("GET /api/v2/cats", Handle(http.StatusOK, listCatsV2))
+	router.HandleFunc("GET /api/v2/cats/{catId}", Handle(http.StatusOK, getCatV2))
+	router.HandleFunc("PUT /api/v2/cats/{catId}", Handle(http.StatusOK, updateCatV2))
+	router.HandleFunc("DELETE /api/v2/cats/{catId}", Handle(http.StatusNoContent, deleteCat))
+	router.HandleFunc("POST /graphql", withBodyLimit(graphqlMaxBodySize, Handle(http.StatusOK, serveGraphql)))
+
+	fsys, _ := fs.Sub(content, "swagger-ui")
+	router.Handle("GET /swagger/", http.StripPrefix("/swagger", http.FileServer(http.FS(fsys))))
+
+	return withHealthCheck(withReplication(withRequestID(logReq(withSecurityHeaders(withCORS(loadCorsPolicy(), withCompression(withTenant(router))))))))
+}
+
+// Path of the health check of the containers and load balancers
+const healthCheckPath = "/healthz"
+
+// Answers the health check before the other middlewares: it needs no tenant nor token, and a follower
+// without leader is still healthy, the routes answering again once a leader is elected
+func withHealthCheck(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == healthCheckPath && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+			res.Header().Set("Cache-Control", "no-store")
+			writeJSON(res, http.StatusOK, "ok")
+			return
+		}
+		next.ServeHTTP(res, req)
+	})
+}
+
+// Announces the deprecation of a v1 route replaced by the v2 API (RFC 9745 and RFC 8594).
+// The successor path may use the wildcards of the route, like {catId}.
+func deprecatedV1(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		link := successor
+		if catID := req.PathValue("catId"); catID != "" {
+			link = strings.ReplaceAll(link, "{catId}", url.PathEscape(catID))
+		}
+		res.Header().Set("Deprecation", "@"+strconv.FormatInt(apiV1Deprecation.Unix(), 10))
+		res.Header().Set("Sunset", apiV1Sunset.Format(http.TimeFormat))
+		res.Header().Add("Link", "<"+link+`>; rel="successor-version"`)
+		next(res, req)
+	}
+}
+
+// Simpler way to handle requests
+type ServiceFunc func(*http.Request) (int, any)
+
+// Wraps the ServiceFunc to make a http.HandlerFunc with panic handling and the response encoding negotiated from the Accept header
+func makeHandlerFunc(svcFunc ServiceFunc) http.HandlerFunc {
+
+	return func(res http.ResponseWriter, req *http.Request) {
+
+		// Rejected before running the service, which may change the data
+		codec := negotiateCodec(req.Header.Get("Accept"))
+		if codec == nil {
+			res.Header().Add("Vary", "Accept")
+			writeJSON(res, http.StatusNotAcceptable, "Not acceptable, expecting one of "+supportedMediaTypes())
+			return
+		}
+
+		code, body := func(req *http.Request) (code int, body any) {
+			// General panic/error handler to keep the server up
+			defer func() {
+				if recov := recover(); recov != nil {
+					Logger.Error("Recovering from a panic: ", recov)
+					// Using the named return values
+					code = http.StatusInternalServerError
+					body = http.StatusText(code)
+				}
+			}()
+			return svcFunc(req)
+		}(req)
+
+		// Single response
+		writeResponse(res, codec, code, body)
+	}
+}
+
+// Writes a JSON encoded response
+func writeJSON(res http.ResponseWriter, code int, body any) {
+	res.Header().Set("content-type", "application/json")
+	res.WriteHeader(code)
+	json.NewEncoder(res).Encode(body)
+}
@@ -0,0 +1,50 @@
+package catsapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// History of a cat, including after it was purged from the trash
+func getCatHistory(ctx context.Context, req *http.Request, _ struct{}) ([]AuditEntry, error) {
+	catID := req.PathValue("catId")
+	Logger.Info("Getting the history of the cat: ", catID)
+
+	db := requestDB(ctx)
+	entries := db.audit.List(AuditFilter{CatID: catID})
+	if len(entries) > 0 {
+		return entries, nil
+	}
+
+	// The cats of the fixtures and the imports have no history yet, the ones in the trash included
+	dbLock.RLock()
+	_, catExists := db.Cats[catID]
+	dbLock.RUnlock()
+	if !catExists {
+		Logger.Info("No history for the cat")
+		return nil, notFound("Cat not found")
+	}
+	return entries, nil
+}
+
+// Lists the audit entries, optionally between the "from" (inclusive) and "to" (exclusive) RFC 3339 times
+func listAudit(ctx context.Context, req *http.Request, _ struct{}) ([]AuditEntry, error) {
+	var filter AuditFilter
+	var err error
+
+	query := req.URL.Query()
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return nil, badRequest("Invalid from time, expecting RFC 3339")
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return nil, badRequest("Invalid to time, expecting RFC 3339")
+		}
+	}
+	Logger.Infof("Listing the audit entries from '%s' to '%s'", query.Get("from"), query.Get("to"))
+
+	return requestDB(ctx).audit.List(filter), nil
+}
@@ -0,0 +1,159 @@
+package catsapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test the history recorded along the life of a cat
+func TestCatHistory(t *testing.T) {
+	app := setupOwnerTest(t)
+	originalAudit := testDB().audit
+	testDB().audit = newMemoryAuditStore(0)
+	defer func() { testDB().audit = originalAudit }()
+
+	// Creation with unverified credentials, and a request ID set by the proxy
+	req := httptest.NewRequest("POST", "/api/cats", strings.NewReader(`{"name": "Toto", "color": "Grey"}`))
+	req.SetBasicAuth("alice", "secret")
+	req.Header.Set("X-Request-ID", "req-42")
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	if rr.Header().Get("X-Request-ID") != "req-42" {
+		t.Errorf("Expected the request ID to be echoed, got %s", rr.Header().Get("X-Request-ID"))
+	}
+	catID := strings.Trim(strings.TrimSpace(rr.Body.String()), `"`)
+
+	var ownerID string
+	doJSON(t, app, "POST", "/api/owners", `{"name": "Bob"}`, &ownerID)
+	doJSON(t, app, "PUT", "/api/cats/"+catID+"/owner", `{"ownerId": "`+ownerID+`"}`, nil)
+	doJSON(t, app, "DELETE", "/api/cats/"+catID, "", nil)
+	doJSON(t, app, "POST", "/api/cats/"+catID+":restore", "", nil)
+
+	var history []AuditEntry
+	if code := doJSON(t, app, "GET", "/api/cats/"+catID+"/history", "", &history); code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+
+	expectedActions := []string{"create", "update", "delete", "restore"}
+	if len(history) != len(expectedActions) {
+		t.Fatalf("Expected %d entries, got %+v", len(expectedActions), history)
+	}
+	for i, action := range expectedActions {
+		if history[i].Action != action {
+			t.Errorf("Entry %d: expected action %s, got %s", i, action, history[i].Action)
+		}
+	}
+
+	creation := history[0]
+	if creation.Actor != "" || creation.RequestID != "req-42" || creation.Before != nil {
+		t.Errorf("Unexpected creation entry: %+v", creation)
+	}
+	if change := creation.Changes["color"]; change.Before != nil || change.After != "Grey" {
+		t.Errorf("Expected the color to be set, got %+v", change)
+	}
+
+	transfer := history[1]
+	if len(transfer.Changes) != 1 || transfer.Changes["ownerId"].After != ownerID {
+		t.Errorf("Expected only the owner to change, got %+v", transfer.Changes)
+	}
+	if transfer.RequestID == "" || transfer.RequestID == creation.RequestID {
+		t.Errorf("Expected a generated request ID, got '%s'", transfer.RequestID)
+	}
+	if _, found := history[2].Changes["deletedAt"]; !found {
+		t.Errorf("Expected the deletion time in the changes, got %+v", history[2].Changes)
+	}
+
+	if code := doJSON(t, app, "GET", "/api/cats/unknown/history", "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, code)
+	}
+
+	// A cat loaded without going through the API has an empty history
+	testDB().Cats["seeded"] = Cat{ID: "seeded", Name: "Felix"}
+	if code := doJSON(t, app, "GET", "/api/cats/seeded/history", "", &history); code != http.StatusOK || len(history) != 0 {
+		t.Errorf("Expected an empty history, got %d %+v", code, history)
+	}
+}
+
+// Test that the actor of the changes is the subject of the verified bearer token
+func TestAuditActor(t *testing.T) {
+	secret := "test-secret"
+	app := setupTenantsTest(t, tenantSettings{maxCats: map[string]int{DefaultTenant: 0}, tokenSecret: []byte(secret)})
+	originalAudit := testDB().audit
+	testDB().audit = newMemoryAuditStore(0)
+	defer func() { testDB().audit = originalAudit }()
+
+	exp := time.Now().Add(time.Hour).Unix()
+	for _, claims := range []string{
+		fmt.Sprintf(`{"tenant": "default", "sub": "alice", "exp": %d}`, exp),
+		fmt.Sprintf(`{"tenant": "default", "exp": %d}`, exp),
+	} {
+		headers := map[string]string{"Authorization": "Bearer " + signTestToken(secret, claims)}
+		if rr := doTenantRequest(app, "POST", "/api/cats", headers, `{"name": "Toto"}`); rr.Code != http.StatusCreated {
+			t.Fatalf("Expected the cat to be created, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	entries := testDB().audit.List(AuditFilter{})
+	if len(entries) != 2 || entries[0].Actor != "alice" || entries[1].Actor != "" {
+		t.Errorf("Expected the actors alice and none, got %+v", entries)
+	}
+}
+
+// Test the time range filtering of the global audit
+func TestListAudit(t *testing.T) {
+	app := setupOwnerTest(t)
+	originalAudit := testDB().audit
+	testDB().audit = newMemoryAuditStore(0)
+	defer func() { testDB().audit = originalAudit }()
+
+	start := time.Now().Add(-time.Second)
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Toto"}`, nil)
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Felix"}`, nil)
+
+	var entries []AuditEntry
+	doJSON(t, app, "GET", "/api/audit", "", &entries)
+	if len(entries) != 2 || entries[0].Sequence != 1 || entries[1].Sequence != 2 {
+		t.Errorf("Expected 2 sequenced entries, got %+v", entries)
+	}
+
+	query := url.Values{"from": {start.Format(time.RFC3339)}, "to": {start.Add(time.Hour).Format(time.RFC3339)}}
+	doJSON(t, app, "GET", "/api/audit?"+query.Encode(), "", &entries)
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries in the range, got %d", len(entries))
+	}
+
+	query = url.Values{"from": {start.Add(time.Hour).Format(time.RFC3339)}}
+	doJSON(t, app, "GET", "/api/audit?"+query.Encode(), "", &entries)
+	if len(entries) != 0 {
+		t.Errorf("Expected no entry in the future, got %d", len(entries))
+	}
+
+	if code := doJSON(t, app, "GET", "/api/audit?to=yesterday", "", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, code)
+	}
+}
+
+// Test that the oldest entries are dropped beyond the limit, the sequence numbers still increasing
+func TestAuditRetention(t *testing.T) {
+	store := newMemoryAuditStore(3)
+	for i := range 5 {
+		if entry := store.Append(AuditEntry{CatID: fmt.Sprint(i)}); entry.Sequence != int64(i+1) {
+			t.Errorf("Expected the sequence %d, got %d", i+1, entry.Sequence)
+		}
+	}
+
+	entries := store.List(AuditFilter{})
+	if len(entries) != 3 {
+		t.Fatalf("Expected the last 3 entries, got %+v", entries)
+	}
+	for i, entry := range entries {
+		if entry.Sequence != int64(i+3) || entry.CatID != fmt.Sprint(i+2) {
+			t.Errorf("Entry %d: unexpected %+v", i, entry)
+		}
+	}
+}
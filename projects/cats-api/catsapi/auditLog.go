@@ -0,0 +1,144 @@
+package catsapi
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Change of a single field, in an audit entry
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Trace of a change made to a cat
+type AuditEntry struct {
+	Sequence  int64                  `json:"sequence"`
+	Time      time.Time              `json:"time"`
+	Action    string                 `json:"action"`
+	CatID     string                 `json:"catId"`
+	Actor     string                 `json:"actor,omitempty"`
+	RequestID string                 `json:"requestId,omitempty"`
+	Before    *Cat                   `json:"before,omitempty"`
+	After     *Cat                   `json:"after,omitempty"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+}
+
+// Selection of audit entries, zero values match everything
+type AuditFilter struct {
+	CatID string
+	From  time.Time
+	To    time.Time
+}
+
+func (filter AuditFilter) matches(entry AuditEntry) bool {
+	return (filter.CatID == "" || entry.CatID == filter.CatID) &&
+		(filter.From.IsZero() || !entry.Time.Before(filter.From)) &&
+		(filter.To.IsZero() || entry.Time.Before(filter.To))
+}
+
+// Append-only storage of the audit entries, entries can never be altered or removed
+type AuditStore interface {
+	Append(entry AuditEntry) AuditEntry
+	List(filter AuditFilter) []AuditEntry
+}
+
+// Number of entries kept in the audit trail of each tenant, the oldest ones being dropped beyond, 0 for no limit
+var auditMaxEntries = int(getEnvInt64("AUDIT_MAX_ENTRIES", 100_000))
+
+// AuditStore kept in memory, with its own lock so it stays independent of the cats database
+type memoryAuditStore struct {
+	lock         sync.RWMutex
+	entries      []AuditEntry
+	maxEntries   int
+	lastSequence int64
+}
+
+// Creates a store keeping the last maxEntries entries, all of them when 0
+func newMemoryAuditStore(maxEntries int) *memoryAuditStore {
+	return &memoryAuditStore{maxEntries: maxEntries}
+}
+
+// Stores the entry with the next sequence number, which keeps increasing when the oldest entries are dropped
+func (store *memoryAuditStore) Append(entry AuditEntry) AuditEntry {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.lastSequence++
+	entry.Sequence = store.lastSequence
+	store.entries = append(store.entries, entry)
+	if store.maxEntries > 0 && len(store.entries) > store.maxEntries {
+		// The dropped entries are released when append moves the remaining ones to a new array
+		store.entries = store.entries[len(store.entries)-store.maxEntries:]
+	}
+	return entry
+}
+
+// Lists the matching entries in chronological order
+func (store *memoryAuditStore) List(filter AuditFilter) []AuditEntry {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	results := []AuditEntry{}
+	for _, entry := range store.entries {
+		if filter.matches(entry) {
+			results = append(results, entry)
+		}
+	}
+	return results
+}
+
+// Records a cat change in the audit trail of the tenant, the context of the changes made by the server itself
+// not being bound to a tenant
+func (db *tenantDB) auditCat(ctx context.Context, action string, catID string, before, after *Cat) {
+	entry := AuditEntry{
+		Time:    time.Now().UTC(),
+		Action:  action,
+		CatID:   catID,
+		Actor:   "system",
+		Before:  before,
+		After:   after,
+		Changes: diffCats(before, after),
+	}
+	// The actor is the subject of the verified bearer token, if any: the credentials which are not verified,
+	// like the Basic auth user, would let a client write any name
+	if bound, found := ctx.Value(tenantContextKey{}).(requestTenant); found {
+		entry.Actor = bound.subject
+		entry.RequestID = requestIDFromContext(ctx)
+	}
+	// The trail only tells the committed changes
+	afterCommit(func() { db.audit.Append(entry) })
+}
+
+// Lists the fields differing between the two versions of a cat, compared by their JSON form
+func diffCats(before, after *Cat) map[string]FieldChange {
+	beforeFields, afterFields := catFields(before), catFields(after)
+
+	changes := map[string]FieldChange{}
+	for name, value := range afterFields {
+		if previous := beforeFields[name]; !reflect.DeepEqual(previous, value) {
+			changes[name] = FieldChange{Before: previous, After: value}
+		}
+	}
+	for name, previous := range beforeFields {
+		if _, found := afterFields[name]; !found {
+			changes[name] = FieldChange{Before: previous}
+		}
+	}
+	return changes
+}
+
+// The change times are left out, the entry already has its own
+func catFields(cat *Cat) map[string]any {
+	fields := map[string]any{}
+	if cat != nil {
+		encoded, _ := json.Marshal(cat)
+		json.Unmarshal(encoded, &fields)
+	}
+	delete(fields, "createdAt")
+	delete(fields, "updatedAt")
+	return fields
+}
@@ -0,0 +1,86 @@
+package catsapi
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errBlobNotFound = errors.New("blob not found")
+
+// Storage for binary objects (photos, thumbnails...), addressed by a slash separated key
+type BlobStore interface {
+	Put(key string, data io.Reader) error
+	Open(key string) (io.ReadSeekCloser, error)
+	Delete(key string) error
+}
+
+// BlobStore keeping each blob as a file under a root directory
+type diskBlobStore struct {
+	root string
+}
+
+func newDiskBlobStore(root string) *diskBlobStore {
+	return &diskBlobStore{root: root}
+}
+
+// Maps a key to a file path, refusing keys escaping the root directory
+func (store *diskBlobStore) path(key string) (string, error) {
+	if !fs.ValidPath(key) || strings.Contains(key, "\\") {
+		return "", errors.New("invalid blob key: " + key)
+	}
+	return filepath.Join(store.root, filepath.FromSlash(key)), nil
+}
+
+func (store *diskBlobStore) Put(key string, data io.Reader) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (store *diskBlobStore) Open(key string) (io.ReadSeekCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errBlobNotFound
+	}
+	return file, err
+}
+
+func (store *diskBlobStore) Delete(key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,192 @@
+package catsapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Maximum size of the decoded request bodies, for the routes not setting theirs
+var maxBodySize = getEnvInt64("MAX_BODY_SIZE", 64<<10)
+
+const bodyLimitKey contextKey = "bodyLimit"
+
+// Sets the maximum size of the request bodies of a route, instead of maxBodySize
+func withBodyLimit(limit int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		next(res, req.WithContext(context.WithValue(req.Context(), bodyLimitKey, limit)))
+	}
+}
+
+// Bounds the request body to the limit of its route, failing early on a too large announced length
+func limitBody(req *http.Request) io.Reader {
+	limit, found := req.Context().Value(bodyLimitKey).(int64)
+	if !found {
+		limit = maxBodySize
+	}
+	if req.ContentLength > limit {
+		return errorReader{&http.MaxBytesError{Limit: limit}}
+	}
+	return http.MaxBytesReader(nil, req.Body, limit)
+}
+
+type errorReader struct {
+	err error
+}
+
+func (reader errorReader) Read([]byte) (int, error) {
+	return 0, reader.err
+}
+
+// Invalid JSON input, located by its byte offset in the body when known (else -1)
+type jsonInputError struct {
+	message string
+	offset  int64
+}
+
+func (err *jsonInputError) Error() string {
+	if err.offset < 0 {
+		return err.message
+	}
+	return fmt.Sprintf("%s at offset %d", err.message, err.offset)
+}
+
+// Decodes a single JSON value, rejecting the unknown fields, the duplicate keys and the trailing data
+func decodeStrictJSON(data []byte, target any) error {
+	if err := findDuplicateKey(data); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return describeJSONError(err, data)
+	}
+
+	end := decoder.InputOffset()
+	if _, err := decoder.Token(); err != io.EOF {
+		trailing := int64(len(data[end:]) - len(bytes.TrimLeft(data[end:], " \t\r\n")))
+		return &jsonInputError{"unexpected data after the JSON value", end + trailing}
+	}
+	return nil
+}
+
+// Makes the decoding errors name the field and the offset, when they are known
+func describeJSONError(err error, data []byte) *jsonInputError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &jsonInputError{syntaxErr.Error(), syntaxErr.Offset}
+	case errors.As(err, &typeErr):
+		return &jsonInputError{fmt.Sprintf("field %q expects %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value), typeErr.Offset}
+	case errors.Is(err, io.EOF):
+		return &jsonInputError{"empty body", -1}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &jsonInputError{"unexpected end of JSON input", int64(len(data))}
+	}
+
+	// encoding/json has no error type for them
+	if quoted, found := strings.CutPrefix(err.Error(), "json: unknown field "); found {
+		field, _ := strconv.Unquote(quoted)
+		return &jsonInputError{fmt.Sprintf("unknown field %q", field), findKeyOffset(data, field)}
+	}
+	// Like the errors of the types decoding themselves, the offset being at the end of their value
+	return &jsonInputError{err.Error(), -1}
+}
+
+// Locates the first occurrence of the key, -1 when not found
+func findKeyOffset(data []byte, key string) int64 {
+	found := int64(-1)
+	walkJSONKeys(data, func(candidate string, offset int64, _ map[string]bool) bool {
+		if candidate == key {
+			found = offset
+		}
+		return found < 0
+	})
+	return found
+}
+
+// Rejects the objects repeating a key, which encoding/json silently accepts by keeping the last value.
+// Like encoding/json matching the field names, the keys differing only by their case are the same.
+func findDuplicateKey(data []byte) error {
+	var duplicate error
+	walkJSONKeys(data, func(key string, offset int64, seen map[string]bool) bool {
+		if seen[foldKey(key)] {
+			duplicate = &jsonInputError{fmt.Sprintf("duplicate field %q", key), offset}
+		}
+		return duplicate == nil
+	})
+	return duplicate
+}
+
+// Walks the object keys of the first JSON value with their offset and the keys already seen in their object,
+// folded by foldKey, until visit returns false. The syntax errors end the walk, they are left to the decoding.
+func walkJSONKeys(data []byte, visit func(key string, offset int64, seen map[string]bool) bool) {
+	type object struct {
+		keys         map[string]bool
+		expectingKey bool
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	stack := []*object{} // nil for the arrays
+
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return
+		}
+		var current *object
+		if len(stack) > 0 {
+			current = stack[len(stack)-1]
+		}
+
+		if key, isKey := token.(string); isKey && current != nil && current.expectingKey {
+			keyOffset := offset + int64(bytes.IndexByte(data[offset:], '"'))
+			if !visit(key, keyOffset, current.keys) {
+				return
+			}
+			current.keys[foldKey(key)] = true
+			current.expectingKey = false
+			continue
+		}
+
+		switch token {
+		case json.Delim('{'):
+			stack = append(stack, &object{keys: map[string]bool{}, expectingKey: true})
+			continue
+		case json.Delim('['):
+			stack = append(stack, nil)
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+
+		// A value ended, the next token of its object is a key
+		if len(stack) == 0 {
+			return
+		}
+		if parent := stack[len(stack)-1]; parent != nil {
+			parent.expectingKey = true
+		}
+	}
+}
+
+// Maps each rune of the key to the smallest one of its case folding orbit, so the keys equal for
+// strings.EqualFold have the same folded form
+func foldKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		smallest := r
+		for folded := unicode.SimpleFold(r); folded != r; folded = unicode.SimpleFold(folded) {
+			smallest = min(smallest, folded)
+		}
+		return smallest
+	}, key)
+}
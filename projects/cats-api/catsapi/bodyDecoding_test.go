@@ -0,0 +1,112 @@
+package catsapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test the strict decoding errors, naming the field and the offset
+func TestDecodeStrictJSON(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{`{"name": "Felix", "ownerId": "o1"}`, ""},
+		{`  {"name": "Felix"}  ` + "\n", ""},
+		{``, "empty body"},
+		{`{"name": "Felix"`, "unexpected end of JSON input at offset 16"},
+		{`{"name": Felix}`, "invalid character 'F' looking for beginning of value at offset 10"},
+		{`{"name": "Felix"} {"name": "Tom"}`, "unexpected data after the JSON value at offset 18"},
+		{`{"name": "Felix"}garbage`, "unexpected data after the JSON value at offset 17"},
+		{`{"name": "Felix", "name": "Tom"}`, `duplicate field "name" at offset 18`},
+		{`{"name": "Felix", "color": "Grey", "colour": "Gray"}`, `unknown field "colour" at offset 35`},
+		{`{"name": 42}`, `field "name" expects string, got number at offset 11`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			var cat CatV1
+			err := decodeStrictJSON([]byte(tt.body), &cat)
+			if message := errorMessage(err); message != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, message)
+			}
+		})
+	}
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Test the duplicate keys in the nested objects, the same key being allowed in different objects
+func TestFindDuplicateKey(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{`{"a": {"b": 1}, "c": {"b": 2}, "d": [{"b": 1}, {"b": 2}]}`, ""},
+		{`{"a": {"b": 1, "c": [1, {"x": 1}], "b": 2}}`, `duplicate field "b" at offset 35`},
+		{`[{"a": 1}, {"a": 1, "a": 2}]`, `duplicate field "a" at offset 20`},
+		{`{"a": "\"b\": 1", "b": 2}`, ""},
+		{`{"name": "a", "Name": "b"}`, `duplicate field "Name" at offset 14`},
+		{`{"NAME": "a", "naMe": "b"}`, `duplicate field "naMe" at offset 14`},
+		{`{"ſ": 1, "S": 2}`, `duplicate field "S" at offset 10`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			if message := errorMessage(findDuplicateKey([]byte(tt.body))); message != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, message)
+			}
+		})
+	}
+}
+
+// Test the strict decoding and the size limits through the routes
+func TestRequestBodyLimits(t *testing.T) {
+	app := setupOwnerTest(t)
+	originalMax := maxBodySize
+	maxBodySize = 100
+	t.Cleanup(func() { maxBodySize = originalMax })
+
+	bigOwner := `{"name": "` + strings.Repeat("A", 200) + `"}`
+	tests := []struct {
+		name         string
+		path         string
+		body         io.Reader
+		expectedCode int
+		expectedBody string
+	}{
+		{"announced length", "/api/owners", strings.NewReader(bigOwner), http.StatusRequestEntityTooLarge, "the limit is 100 bytes"},
+		{"unknown length", "/api/owners", io.MultiReader(strings.NewReader(bigOwner)), http.StatusRequestEntityTooLarge, "the limit is 100 bytes"},
+		{"route limit", "/graphql", strings.NewReader(`{"query": "{ owners { name } }", "operationName": "` + strings.Repeat("A", 200) + `"}`), http.StatusOK, "data"},
+		{"unknown field", "/api/owners", strings.NewReader(`{"name": "Alice", "age": 42}`), http.StatusBadRequest, `Invalid JSON input: unknown field \"age\" at offset 18`},
+		{"duplicate field", "/api/owners", strings.NewReader(`{"name": "Alice", "name": "Bob"}`), http.StatusBadRequest, `duplicate field \"name\"`},
+		{"duplicate field case", "/api/owners", strings.NewReader(`{"name": "Alice", "Name": "Bob"}`), http.StatusBadRequest, `duplicate field \"Name\"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			app.ServeHTTP(rr, httptest.NewRequest("POST", tt.path, tt.body))
+			if rr.Code != tt.expectedCode || !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected %d with %q, got %d: %s", tt.expectedCode, tt.expectedBody, rr.Code, rr.Body.String())
+			}
+		})
+	}
+	if len(testDB().Owners) != 0 {
+		t.Errorf("No owner should have been created, got %v", testDB().Owners)
+	}
+
+	// The fields of the other formats are checked too, without an offset
+	rr := doNegotiated(app, "POST", "/api/owners", "application/yaml", []byte("name: Alice\nage: 42\n"), "")
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `Invalid YAML input: unknown field \"age\""`) {
+		t.Errorf("Expected the unknown field to be rejected, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
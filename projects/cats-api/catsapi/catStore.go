@@ -0,0 +1,449 @@
+package catsapi
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Persistent store of the cats of each tenant, for the deployments keeping them outside of the process.
+// The handlers write the changes of the cats through it before changing the databases, failing when it does.
+type CatStore interface {
+	Get(tenant, catID string) (Cat, bool, error)
+	// Lists all the cats of the tenant, the ones in the trash included
+	List(tenant string) ([]Cat, error)
+	Put(tenant string, cat Cat) error
+	Delete(tenant, catID string) error
+}
+
+// Cats store of the server, nil when CATS_STORE_DIR is not set: getCat and listCats then read the databases.
+// Each replica has its own, following the entries it applies.
+var catStore = openCatStore(getEnv("CATS_STORE_DIR", ""))
+
+func openCatStore(dir string) CatStore {
+	if dir == "" {
+		return nil
+	}
+	return newCachedCatStore(newDiskCatStore(dir),
+		int(getEnvInt64("CATS_CACHE_SIZE", 1000)), getEnvDuration("CATS_CACHE_TTL", 30*time.Second))
+}
+
+// CatStore keeping each cat as a JSON file, in a directory by tenant
+type diskCatStore struct {
+	root string
+}
+
+func newDiskCatStore(root string) *diskCatStore {
+	return &diskCatStore{root: root}
+}
+
+// The tenant IDs are safe file names, the cat IDs are escaped
+func (store *diskCatStore) path(tenant, catID string) string {
+	return filepath.Join(store.root, tenant, url.PathEscape(catID)+".json")
+}
+
+func (store *diskCatStore) Get(tenant, catID string) (Cat, bool, error) {
+	var cat Cat
+	data, err := os.ReadFile(store.path(tenant, catID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return cat, false, nil
+	}
+	if err != nil {
+		return cat, false, err
+	}
+	err = json.Unmarshal(data, &cat)
+	return cat, err == nil, err
+}
+
+func (store *diskCatStore) List(tenant string) ([]Cat, error) {
+	files, err := os.ReadDir(filepath.Join(store.root, tenant))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Cat{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cats := []Cat{}
+	for _, file := range files {
+		escapedID, isCat := strings.CutSuffix(file.Name(), ".json")
+		catID, err := url.PathUnescape(escapedID)
+		if !isCat || err != nil {
+			continue
+		}
+		cat, found, err := store.Get(tenant, catID)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			cats = append(cats, cat)
+		}
+	}
+	return cats, nil
+}
+
+func (store *diskCatStore) Put(tenant string, cat Cat) error {
+	data, err := json.Marshal(cat)
+	if err != nil {
+		return err
+	}
+	path := store.path(tenant, cat.ID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial cat
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cat-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (store *diskCatStore) Delete(tenant, catID string) error {
+	err := os.Remove(store.path(tenant, catID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Counters of the cats cache, exposed in /debug/vars
+type CatsCacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+}
+
+// CatStore reading through an in-process cache in front of a slower store, with a bounded size and a TTL.
+// The writes drop the entry of their cat and the list of its tenant, and the concurrent misses of a same
+// entry share a single read.
+type cachedCatStore struct {
+	store CatStore
+	size  int
+	ttl   time.Duration
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	// Most recently used entries first
+	recency *list.List
+	// Dropped by the writes, for the reads started before not to cache what they got
+	loads map[string]*catsCacheLoad
+
+	hits, misses, evictions atomic.Int64
+}
+
+type catsCacheEntry struct {
+	key     string
+	value   any
+	expires time.Time
+}
+
+// Read of the store shared by the concurrent misses of an entry
+type catsCacheLoad struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+// Result of a Get, not found included so the unknown cats are cached too
+type cachedCat struct {
+	cat   Cat
+	found bool
+}
+
+func newCachedCatStore(store CatStore, size int, ttl time.Duration) *cachedCatStore {
+	return &cachedCatStore{
+		store:   store,
+		size:    max(size, 1),
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		recency: list.New(),
+		loads:   map[string]*catsCacheLoad{},
+	}
+}
+
+func (cache *cachedCatStore) Get(tenant, catID string) (Cat, bool, error) {
+	value, err := cache.read(catCacheKey(tenant, catID), func() (any, error) {
+		cat, found, err := cache.store.Get(tenant, catID)
+		return cachedCat{cat, found}, err
+	})
+	if err != nil {
+		return Cat{}, false, err
+	}
+	result := value.(cachedCat)
+	return result.cat, result.found, nil
+}
+
+// The cached list is shared by the callers, which must not change it
+func (cache *cachedCatStore) List(tenant string) ([]Cat, error) {
+	value, err := cache.read(catsListCacheKey(tenant), func() (any, error) {
+		return cache.store.List(tenant)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]Cat), nil
+}
+
+// Also invalidates when failing, the store may have been changed anyway
+func (cache *cachedCatStore) Put(tenant string, cat Cat) error {
+	defer cache.invalidate(tenant, cat.ID)
+	return cache.store.Put(tenant, cat)
+}
+
+func (cache *cachedCatStore) Delete(tenant, catID string) error {
+	defer cache.invalidate(tenant, catID)
+	return cache.store.Delete(tenant, catID)
+}
+
+func catCacheKey(tenant, catID string) string {
+	return tenant + "/cats/" + catID
+}
+
+func catsListCacheKey(tenant string) string {
+	return tenant + "/list"
+}
+
+func (cache *cachedCatStore) stats() CatsCacheStats {
+	return CatsCacheStats{Hits: cache.hits.Load(), Misses: cache.misses.Load(), Evictions: cache.evictions.Load()}
+}
+
+// Gets the entry from the cache, or from the store through a load shared with the other callers missing it
+func (cache *cachedCatStore) read(key string, load func() (any, error)) (any, error) {
+	cache.lock.Lock()
+	if element, found := cache.entries[key]; found {
+		entry := element.Value.(*catsCacheEntry)
+		if time.Now().Before(entry.expires) {
+			cache.recency.MoveToFront(element)
+			cache.lock.Unlock()
+			cache.hits.Add(1)
+			return entry.value, nil
+		}
+		cache.remove(element)
+	}
+	cache.misses.Add(1)
+	if running, found := cache.loads[key]; found {
+		cache.lock.Unlock()
+		<-running.done
+		return running.value, running.err
+	}
+	running := &catsCacheLoad{done: make(chan struct{})}
+	cache.loads[key] = running
+	cache.lock.Unlock()
+
+	running.value, running.err = load()
+
+	cache.lock.Lock()
+	// Not cached when a write dropped the load
+	if cache.loads[key] == running {
+		delete(cache.loads, key)
+		if running.err == nil {
+			cache.add(key, running.value)
+		}
+	}
+	cache.lock.Unlock()
+	close(running.done)
+	return running.value, running.err
+}
+
+// Stores the entry, evicting the least recently used one when the cache is full. The caller must hold the lock.
+func (cache *cachedCatStore) add(key string, value any) {
+	if element, found := cache.entries[key]; found {
+		cache.remove(element)
+	}
+	entry := &catsCacheEntry{key: key, value: value, expires: time.Now().Add(cache.ttl)}
+	cache.entries[key] = cache.recency.PushFront(entry)
+	for cache.recency.Len() > cache.size {
+		cache.remove(cache.recency.Back())
+		cache.evictions.Add(1)
+	}
+}
+
+func (cache *cachedCatStore) remove(element *list.Element) {
+	delete(cache.entries, element.Value.(*catsCacheEntry).key)
+	cache.recency.Remove(element)
+}
+
+// Drops the entry of the cat and the list of its tenant. Their running loads keep their callers but no new
+// one joins them, nor are their results cached.
+func (cache *cachedCatStore) invalidate(tenant, catID string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	for _, key := range []string{catCacheKey(tenant, catID), catsListCacheKey(tenant)} {
+		if element, found := cache.entries[key]; found {
+			cache.remove(element)
+		}
+		delete(cache.loads, key)
+	}
+}
+
+// Statistics of the cats cache, nil when there is none
+func catsCacheStats() *CatsCacheStats {
+	cache, cached := catStore.(*cachedCatStore)
+	if !cached {
+		return nil
+	}
+	stats := cache.stats()
+	return &stats
+}
+
+// Writes the change of a cat to the cats store, nil after removing it, before the databases are changed.
+// The caller must hold the DB write lock.
+func (db *tenantDB) storeCat(catID string, after *Cat) error {
+	if catStore == nil {
+		return nil
+	}
+	if err := writeCatChange(db.tenant, catID, after); err != nil {
+		Logger.Errorf("Unable to store the cat '%s': %v", catID, err)
+		return &APIError{http.StatusInternalServerError, "Unable to store the cat"}
+	}
+	return nil
+}
+
+func writeCatChange(tenant, catID string, after *Cat) error {
+	if after == nil {
+		return catStore.Delete(tenant, catID)
+	}
+	cat := *after
+	cat.ID = catID
+	return catStore.Put(tenant, cat)
+}
+
+// Changed cats of a tenant by ID, nil for the removed ones
+type catChanges map[string]*Cat
+
+// Lists the cats which differ between the two versions of the cats of a tenant
+func changedCats(before, after map[string]Cat) catChanges {
+	changes := catChanges{}
+	for catID, cat := range after {
+		if previous, found := before[catID]; !found || !sameCat(previous, cat) {
+			changes[catID] = &cat
+		}
+	}
+	for catID := range before {
+		if _, found := after[catID]; !found {
+			changes[catID] = nil
+		}
+	}
+	return changes
+}
+
+// Compares the cats, their times as instants
+func sameCat(a, b Cat) bool {
+	sameTime := func(x, y *time.Time) bool {
+		return x == y || (x != nil && y != nil && x.Equal(*y))
+	}
+	if !sameTime(a.CreatedAt, b.CreatedAt) || !sameTime(a.UpdatedAt, b.UpdatedAt) || !sameTime(a.DeletedAt, b.DeletedAt) {
+		return false
+	}
+	a.CreatedAt, a.UpdatedAt, a.DeletedAt = nil, nil, nil
+	b.CreatedAt, b.UpdatedAt, b.DeletedAt = nil, nil, nil
+	return a == b
+}
+
+// Replaces the databases of the tenant, recording the changes of its cats for storeCatChanges.
+// The caller must hold the DB write lock.
+func (db *tenantDB) replaceData(data TenantData, changes map[string]catChanges) {
+	if catStore != nil {
+		changes[db.tenant] = changedCats(db.Cats, data.Cats)
+	}
+	db.TenantData = data
+}
+
+// Writes the changes of the databases replaced at once, by the replication or a snapshot, once the DB lock is
+// released. The store is not the reference there: a failure is only logged.
+func storeCatChanges(changes map[string]catChanges) {
+	for tenant, cats := range changes {
+		for catID, after := range cats {
+			if err := writeCatChange(tenant, catID, after); err != nil {
+				Logger.Errorf("Unable to store the cat '%s' of the tenant '%s': %v", catID, tenant, err)
+			}
+		}
+	}
+}
+
+// Brings the cats store to the databases at startup, writing the cats which differ
+func syncCatStore() error {
+	if catStore == nil {
+		return nil
+	}
+	snapshot := TakeSnapshot()
+	for tenant := range tenants.maxCats {
+		if _, found := snapshot.Tenants[tenant]; !found {
+			snapshot.Tenants[tenant] = TenantData{}.orEmpty()
+		}
+	}
+	for tenant, data := range snapshot.Tenants {
+		stored, err := catStore.List(tenant)
+		if err != nil {
+			return err
+		}
+		before := map[string]Cat{}
+		for _, cat := range stored {
+			before[cat.ID] = cat
+		}
+		current := map[string]Cat{}
+		for catID, cat := range data.Cats {
+			cat.ID = catID
+			current[catID] = cat
+		}
+		for catID, after := range changedCats(before, current) {
+			if err := writeCatChange(tenant, catID, after); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Gets a cat which is not in the trash, from the cats store when there is one
+func (db *tenantDB) readCat(catID string) (Cat, bool, error) {
+	if catStore == nil {
+		dbLock.RLock()
+		defer dbLock.RUnlock()
+		cat, found := db.findCat(catID)
+		return cat, found, nil
+	}
+	cat, found, err := catStore.Get(db.tenant, catID)
+	return cat, found && cat.DeletedAt == nil, err
+}
+
+// Lists the IDs of the cats which are not in the trash, from the cats store when there is one
+func (db *tenantDB) readCatIDs() ([]string, error) {
+	if catStore == nil {
+		dbLock.RLock()
+		defer dbLock.RUnlock()
+		return listMapKeys(db.Cats), nil
+	}
+	cats, err := catStore.List(db.tenant)
+	if err != nil {
+		return nil, err
+	}
+	catIDs := []string{}
+	for _, cat := range cats {
+		if cat.DeletedAt == nil {
+			catIDs = append(catIDs, cat.ID)
+		}
+	}
+	return catIDs, nil
+}
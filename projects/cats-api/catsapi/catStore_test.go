@@ -0,0 +1,287 @@
+package catsapi
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Slow store counting its reads, which wait for the gate when there is one, and its writes, which fail when told
+type countingCatStore struct {
+	*diskCatStore
+	reads  atomic.Int64
+	writes atomic.Int64
+	fail   atomic.Bool
+	gate   chan struct{}
+}
+
+func (store *countingCatStore) Get(tenant, catID string) (Cat, bool, error) {
+	store.reads.Add(1)
+	if store.gate != nil {
+		<-store.gate
+	}
+	return store.diskCatStore.Get(tenant, catID)
+}
+
+func (store *countingCatStore) List(tenant string) ([]Cat, error) {
+	store.reads.Add(1)
+	return store.diskCatStore.List(tenant)
+}
+
+func (store *countingCatStore) Put(tenant string, cat Cat) error {
+	store.writes.Add(1)
+	if store.fail.Load() {
+		return errors.New("disk full")
+	}
+	return store.diskCatStore.Put(tenant, cat)
+}
+
+func (store *countingCatStore) Delete(tenant, catID string) error {
+	store.writes.Add(1)
+	if store.fail.Load() {
+		return errors.New("disk full")
+	}
+	return store.diskCatStore.Delete(tenant, catID)
+}
+
+func newCountingCatStore(t *testing.T) *countingCatStore {
+	return &countingCatStore{diskCatStore: newDiskCatStore(t.TempDir())}
+}
+
+// Test the hits, the invalidation by the writes, the expiration and the eviction of the cache
+func TestCachedCatStore(t *testing.T) {
+	store := newCountingCatStore(t)
+	cache := newCachedCatStore(store, 2, time.Hour)
+	cache.Put("t1", Cat{ID: "felix", Name: "Felix"})
+
+	for range 3 {
+		if cat, found, err := cache.Get("t1", "felix"); err != nil || !found || cat.Name != "Felix" {
+			t.Fatalf("Expected Felix, got %+v, %v, %v", cat, found, err)
+		}
+	}
+	if reads := store.reads.Load(); reads != 1 {
+		t.Errorf("Expected a single read of the store, got %d", reads)
+	}
+	if stats := cache.stats(); stats != (CatsCacheStats{Hits: 2, Misses: 1}) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	cache.Put("t1", Cat{ID: "felix", Name: "Felix II"})
+	if cat, _, _ := cache.Get("t1", "felix"); cat.Name != "Felix II" {
+		t.Errorf("Expected the written cat, got %+v", cat)
+	}
+	cache.Delete("t1", "felix")
+	if _, found, _ := cache.Get("t1", "felix"); found {
+		t.Error("Expected the deleted cat to be missing")
+	}
+	if cats, _ := cache.List("t1"); len(cats) != 0 {
+		t.Errorf("Expected no cat listed, got %+v", cats)
+	}
+
+	// The least recently used entry leaves first
+	cache.Get("t1", "a")
+	cache.Get("t1", "b")
+	cache.Get("t1", "a")
+	cache.Get("t1", "c")
+	before := store.reads.Load()
+	cache.Get("t1", "a")
+	if store.reads.Load() != before {
+		t.Error("Expected the recently used entry to stay cached")
+	}
+	cache.Get("t1", "b")
+	if store.reads.Load() != before+1 {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if evictions := cache.stats().Evictions; evictions != 4 {
+		t.Errorf("Expected 4 evictions, got %d", evictions)
+	}
+
+	expiring := newCachedCatStore(store, 10, time.Millisecond)
+	expiring.Get("t1", "a")
+	time.Sleep(5 * time.Millisecond)
+	before = store.reads.Load()
+	expiring.Get("t1", "a")
+	if store.reads.Load() != before+1 {
+		t.Error("Expected the expired entry to be read again")
+	}
+}
+
+// Test that a write only drops the entry of its cat and the list of its tenant
+func TestCachedCatStoreInvalidation(t *testing.T) {
+	store := newCountingCatStore(t)
+	cache := newCachedCatStore(store, 10, time.Hour)
+	cache.Get("t1", "a")
+	cache.Get("t1", "b")
+	cache.Get("t2", "a")
+	cache.List("t1")
+	cache.List("t2")
+
+	cache.Put("t1", Cat{ID: "a", Name: "A"})
+	before := store.reads.Load()
+	cache.Get("t1", "b")
+	cache.Get("t2", "a")
+	cache.List("t2")
+	if reads := store.reads.Load() - before; reads != 0 {
+		t.Errorf("Expected the other entries to stay cached, got %d reads", reads)
+	}
+	cache.Get("t1", "a")
+	if cats, _ := cache.List("t1"); len(cats) != 1 || cats[0].Name != "A" {
+		t.Errorf("Expected the list to be read again, got %+v", cats)
+	}
+	if reads := store.reads.Load() - before; reads != 2 {
+		t.Errorf("Expected the written cat and the list to be read again, got %d reads", reads)
+	}
+}
+
+// Test that the concurrent misses of a cat share a single read of the store
+func TestCachedCatStoreCollapsesMisses(t *testing.T) {
+	store := newCountingCatStore(t)
+	store.Put("t1", Cat{ID: "felix", Name: "Felix"})
+	store.gate = make(chan struct{})
+	cache := newCachedCatStore(store, 10, time.Hour)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if cat, found, err := cache.Get("t1", "felix"); err != nil || !found || cat.Name != "Felix" {
+				t.Errorf("Expected Felix, got %+v, %v, %v", cat, found, err)
+			}
+		}()
+	}
+	for cache.stats().Misses < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	close(store.gate)
+	wg.Wait()
+
+	if reads := store.reads.Load(); reads != 1 {
+		t.Errorf("Expected a single read of the store, got %d", reads)
+	}
+}
+
+// Test that a read started before a write does not cache what it got
+func TestCachedCatStoreWriteDuringRead(t *testing.T) {
+	store := newCountingCatStore(t)
+	store.Put("t1", Cat{ID: "felix", Name: "Felix"})
+	store.gate = make(chan struct{})
+	cache := newCachedCatStore(store, 10, time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.Get("t1", "felix")
+	}()
+	for cache.stats().Misses < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cache.Put("t1", Cat{ID: "felix", Name: "Felix II"})
+	close(store.gate)
+	<-done
+
+	if cat, _, _ := cache.Get("t1", "felix"); cat.Name != "Felix II" {
+		t.Errorf("Expected the written cat, got %+v", cat)
+	}
+}
+
+// Sets a cats store for the test, written through by the handlers
+func setupCatStoreTest(t *testing.T) (http.Handler, *countingCatStore) {
+	store := newCountingCatStore(t)
+	original := catStore
+	catStore = newCachedCatStore(store, 10, time.Hour)
+	t.Cleanup(func() { catStore = original })
+	app := setupOwnerTest(t)
+	snapshot := TakeSnapshot()
+	t.Cleanup(func() { RestoreSnapshot(snapshot) })
+	return app, store
+}
+
+// Test the cats API writing and reading through the cats store
+func TestCatStoreHandlers(t *testing.T) {
+	app, store := setupCatStoreTest(t)
+
+	var catID string
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Toto"}`, &catID)
+	if _, err := os.Stat(filepath.Join(store.root, DefaultTenant, catID+".json")); err != nil {
+		t.Fatalf("Expected the cat in the store: %v", err)
+	}
+
+	var cat CatWithOwner
+	if code := doJSON(t, app, "GET", "/api/cats/"+catID, "", &cat); code != http.StatusOK || cat.Name != "Toto" {
+		t.Fatalf("Expected Toto, got %d %+v", code, cat)
+	}
+	var catIDs []string
+	doJSON(t, app, "GET", "/api/cats", "", &catIDs)
+	if len(catIDs) != 1 || catIDs[0] != catID {
+		t.Errorf("Expected the cat listed, got %v", catIDs)
+	}
+
+	doJSON(t, app, "DELETE", "/api/cats/"+catID, "", nil)
+	if code := doJSON(t, app, "GET", "/api/cats/"+catID, "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status code %d after the deletion, got %d", http.StatusNotFound, code)
+	}
+	doJSON(t, app, "GET", "/api/cats", "", &catIDs)
+	if len(catIDs) != 0 {
+		t.Errorf("Expected the trashed cat to be hidden, got %v", catIDs)
+	}
+
+	RestoreSnapshot(Snapshot{SchemaVersion: snapshotSchemaVersion, Tenants: map[string]TenantData{
+		DefaultTenant: {Cats: map[string]Cat{"felix": {Name: "Felix"}}},
+	}})
+	doJSON(t, app, "GET", "/api/cats", "", &catIDs)
+	if len(catIDs) != 1 || catIDs[0] != "felix" {
+		t.Errorf("Expected the restored cat listed, got %v", catIDs)
+	}
+}
+
+// Test that the writes fail without changing the databases when the cats store fails
+func TestCatStoreFailure(t *testing.T) {
+	app, store := setupCatStoreTest(t)
+	var catID string
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Toto"}`, &catID)
+
+	store.fail.Store(true)
+	if code := doJSON(t, app, "POST", "/api/cats", `{"name": "Zaza"}`, nil); code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for the creation, got %d", http.StatusInternalServerError, code)
+	}
+	if code := doJSON(t, app, "DELETE", "/api/cats/"+catID, "", nil); code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for the deletion, got %d", http.StatusInternalServerError, code)
+	}
+	store.fail.Store(false)
+
+	if cats := testDB().Cats; len(cats) != 1 || cats[catID].DeletedAt != nil {
+		t.Errorf("Expected the databases to be left unchanged, got %+v", cats)
+	}
+	var catIDs []string
+	doJSON(t, app, "GET", "/api/cats", "", &catIDs)
+	if len(catIDs) != 1 || catIDs[0] != catID {
+		t.Errorf("Expected the store to match the databases, got %v", catIDs)
+	}
+}
+
+// Test that replacing the databases only writes the cats which changed
+func TestCatStoreReplaceWritesChanges(t *testing.T) {
+	_, store := setupCatStoreTest(t)
+	cats := map[string]Cat{"felix": {Name: "Felix"}, "zaza": {Name: "Zaza"}, "toto": {Name: "Toto"}}
+	RestoreSnapshot(Snapshot{SchemaVersion: snapshotSchemaVersion, Tenants: map[string]TenantData{DefaultTenant: {Cats: cats}}})
+
+	before := store.writes.Load()
+	cats = map[string]Cat{"felix": {Name: "Felix"}, "zaza": {Name: "Zaza II"}}
+	tenantsStore{}.apply(map[string]TenantData{DefaultTenant: TenantData{Cats: cats}.orEmpty()})
+	if writes := store.writes.Load() - before; writes != 2 {
+		t.Errorf("Expected the changed and the removed cats to be written, got %d writes", writes)
+	}
+	stored, _ := store.List(DefaultTenant)
+	slices.SortFunc(stored, func(a, b Cat) int { return strings.Compare(a.ID, b.ID) })
+	if len(stored) != 2 || stored[0].Name != "Felix" || stored[1].Name != "Zaza II" {
+		t.Errorf("Unexpected stored cats %+v", stored)
+	}
+}
@@ -0,0 +1,241 @@
+package catsapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Calendar date, encoded as YYYY-MM-DD
+type Date struct {
+	time.Time
+}
+
+func (date Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(date.Format(dateLayout))
+}
+
+func (date *Date) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	parsed, err := time.Parse(dateLayout, text)
+	if err != nil {
+		return err
+	}
+	date.Time = parsed
+	return nil
+}
+
+// Date of the deprecation of the v1 cat routes, and date of their planned removal
+var (
+	apiV1Deprecation = time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	apiV1Sunset      = getEnvDate("API_V1_SUNSET", time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC))
+)
+
+var catSexes = []string{"female", "male", "unknown"}
+
+// ISO 11784 microchips have 15 digits
+var microchipPattern = regexp.MustCompile(`^[0-9]{15}$`)
+
+// Cat representation of the v2 API
+type CatV2 struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	BirthDate   *Date      `json:"birthDate,omitempty"`
+	AgeMonths   *int       `json:"ageMonths,omitempty"`
+	Color       string     `json:"color,omitempty"`
+	Sex         string     `json:"sex"`
+	Breed       string     `json:"breed,omitempty"`
+	MicrochipID string     `json:"microchipId,omitempty"`
+	OwnerID     string     `json:"ownerId,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// Writable fields of a v2 cat
+type CatV2Input struct {
+	Name        string `json:"name"`
+	BirthDate   *Date  `json:"birthDate"`
+	Color       string `json:"color"`
+	Sex         string `json:"sex"`
+	Breed       string `json:"breed"`
+	MicrochipID string `json:"microchipId"`
+	OwnerID     string `json:"ownerId"`
+}
+
+// Page of the v2 cats list
+type CatsV2Page struct {
+	Cats          []CatV2 `json:"cats"`
+	NextPageToken string  `json:"nextPageToken,omitempty"`
+	TotalSize     int     `json:"totalSize"`
+}
+
+// Builds the v2 representation of a stored cat, the birth dates of the v1 API not always being valid dates
+func catToV2(catID string, cat Cat, today time.Time) CatV2 {
+	result := CatV2{
+		ID:          catID,
+		Name:        cat.Name,
+		Color:       cat.Color,
+		Sex:         cat.Sex,
+		Breed:       cat.Breed,
+		MicrochipID: cat.MicrochipID,
+		OwnerID:     cat.OwnerID,
+		CreatedAt:   cat.CreatedAt,
+		UpdatedAt:   cat.UpdatedAt,
+	}
+	if result.Sex == "" {
+		result.Sex = "unknown"
+	}
+	if birthDate, err := time.Parse(dateLayout, cat.BirthDate); err == nil {
+		result.BirthDate = &Date{birthDate}
+		ageMonths := ageInMonths(birthDate, today)
+		result.AgeMonths = &ageMonths
+	}
+	return result
+}
+
+// Counts the full months elapsed between the two dates
+func ageInMonths(birthDate time.Time, today time.Time) int {
+	months := (today.Year()-birthDate.Year())*12 + int(today.Month()-birthDate.Month())
+	if today.Day() < birthDate.Day() {
+		months--
+	}
+	return max(months, 0)
+}
+
+// Checks the input and applies it to the cat, returning the validation error message
+func (input CatV2Input) applyTo(cat *Cat, today time.Time) string {
+	if input.Name == "" {
+		return "Missing the cat name"
+	}
+	if input.BirthDate != nil && input.BirthDate.After(today) {
+		return "Invalid birth date, it is in the future"
+	}
+	if input.Sex != "" && !slices.Contains(catSexes, input.Sex) {
+		return "Invalid sex, expecting female, male or unknown"
+	}
+	if input.MicrochipID != "" && !microchipPattern.MatchString(input.MicrochipID) {
+		return "Invalid microchip ID, expecting 15 digits"
+	}
+
+	cat.Name = input.Name
+	cat.BirthDate = ""
+	if input.BirthDate != nil {
+		cat.BirthDate = input.BirthDate.Format(dateLayout)
+	}
+	cat.Color = input.Color
+	cat.Sex = input.Sex
+	cat.Breed = input.Breed
+	cat.MicrochipID = input.MicrochipID
+	cat.OwnerID = input.OwnerID
+	return ""
+}
+
+func createCatV2(ctx context.Context, req *http.Request, input CatV2Input) (CatV2, error) {
+	Logger.Info("Creating the v2 cat: ", input)
+
+	today := time.Now()
+	cat, err := requestDB(ctx).insertCatV2(ctx, input, today)
+	if err != nil {
+		return CatV2{}, err
+	}
+	return catToV2(cat.ID, cat, today), nil
+}
+
+// Validates and stores a new cat, shared by the v2 API and the UI
+func (db *tenantDB) insertCatV2(ctx context.Context, input CatV2Input, today time.Time) (Cat, error) {
+	var cat Cat
+	if msg := input.applyTo(&cat, today); msg != "" {
+		return Cat{}, badRequest(msg)
+	}
+	return db.insertCat(ctx, cat)
+}
+
+func listCatsV2(ctx context.Context, req *http.Request, _ struct{}) (CatsV2Page, error) {
+	Logger.Info("Listing the v2 cats")
+
+	pageSize := defaultCatsPageSize
+	if param := req.URL.Query().Get("pageSize"); param != "" {
+		var err error
+		if pageSize, err = strconv.Atoi(param); err != nil || pageSize < 1 || pageSize > maxCatsPageSize {
+			return CatsV2Page{}, badRequest("Invalid pageSize, expecting 1 to 100")
+		}
+	}
+	afterID, valid := decodeCatCursor(req.URL.Query().Get("pageToken"))
+	if !valid {
+		return CatsV2Page{}, badRequest("Invalid pageToken")
+	}
+
+	cats, total, hasNext := requestDB(ctx).pageCats(func(Cat) bool { return true }, afterID, pageSize)
+
+	today := time.Now()
+	page := CatsV2Page{Cats: []CatV2{}, TotalSize: total}
+	for _, cat := range cats {
+		page.Cats = append(page.Cats, catToV2(cat.ID, cat, today))
+	}
+	if hasNext {
+		page.NextPageToken = encodeCatCursor(cats[len(cats)-1].ID)
+	}
+	return page, nil
+}
+
+func getCatV2(ctx context.Context, req *http.Request, _ struct{}) (CatV2, error) {
+	catID := req.PathValue("catId")
+	Logger.Info("Getting the v2 cat: ", catID)
+
+	dbLock.RLock()
+	cat, found := requestDB(ctx).findCat(catID)
+	dbLock.RUnlock()
+
+	if !found {
+		Logger.Info("Cat not found")
+		return CatV2{}, notFound("Cat not found")
+	}
+	return catToV2(catID, cat, time.Now()), nil
+}
+
+// Replaces the writable fields of the cat
+func updateCatV2(ctx context.Context, req *http.Request, input CatV2Input) (CatV2, error) {
+	catID := req.PathValue("catId")
+	Logger.Info("Updating the v2 cat: ", catID)
+
+	today := time.Now()
+	cat, err := requestDB(ctx).replaceCat(ctx, catID, input, today)
+	if err != nil {
+		return CatV2{}, err
+	}
+	return catToV2(catID, cat, today), nil
+}
+
+// Validates and stores the writable fields of the cat, shared by the v2 API and the UI
+func (db *tenantDB) replaceCat(ctx context.Context, catID string, input CatV2Input, today time.Time) (Cat, error) {
+	dbLock.Lock()
+	defer dbLock.Unlock()
+
+	cat, found := db.findCat(catID)
+	if !found {
+		Logger.Info("Cat not found")
+		return Cat{}, notFound("Cat not found")
+	}
+	before := cat
+	if msg := input.applyTo(&cat, today); msg != "" {
+		return Cat{}, badRequest(msg)
+	}
+	if _, found := db.Owners[cat.OwnerID]; cat.OwnerID != "" && !found {
+		Logger.Info("Owner not found")
+		return Cat{}, badRequest("Owner not found")
+	}
+	cat, err := db.saveCat(ctx, "update", catID, &before, cat)
+	if err != nil {
+		return Cat{}, err
+	}
+
+	Logger.Infof("Cat '%s' updated", catID)
+	return cat, nil
+}
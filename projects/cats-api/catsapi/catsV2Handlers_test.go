@@ -0,0 +1,157 @@
+package catsapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test the computation of the age in full months
+func TestAgeInMonths(t *testing.T) {
+	tests := []struct {
+		birthDate string
+		today     string
+		expected  int
+	}{
+		{"2024-01-15", "2024-01-20", 0},
+		{"2024-01-15", "2024-02-14", 0},
+		{"2024-01-15", "2024-02-15", 1},
+		{"2023-11-30", "2025-01-02", 13},
+		{"2025-01-02", "2024-01-02", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.birthDate+" "+tt.today, func(t *testing.T) {
+			birthDate, _ := time.Parse(dateLayout, tt.birthDate)
+			today, _ := time.Parse(dateLayout, tt.today)
+			if months := ageInMonths(birthDate, today); months != tt.expected {
+				t.Errorf("Expected %d months, got %d", tt.expected, months)
+			}
+		})
+	}
+}
+
+// Test the v2 cats CRUD operations, and their v1 view of the same cats
+func TestCatsV2CRUD(t *testing.T) {
+	app := setupOwnerTest(t)
+	testDB().Owners["owner1"] = Owner{ID: "owner1", Name: "Alice"}
+
+	var cat CatV2
+	body := `{"name": "Felix", "birthDate": "2023-04-16", "sex": "male", "breed": "Maine Coon", "microchipId": "250269604123456", "ownerId": "owner1"}`
+	if code := doJSON(t, app, "POST", "/api/v2/cats", body, &cat); code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, code)
+	}
+	if cat.ID == "" || cat.Sex != "male" || cat.BirthDate == nil || cat.AgeMonths == nil || cat.CreatedAt == nil || cat.UpdatedAt == nil {
+		t.Errorf("Unexpected created cat: %+v", cat)
+	}
+
+	var v1Cat map[string]any
+	doJSON(t, app, "GET", "/api/cats/"+cat.ID, "", &v1Cat)
+	if v1Cat["birthDate"] != "2023-04-16" || v1Cat["breed"] != nil || v1Cat["createdAt"] != nil {
+		t.Errorf("Unexpected v1 cat: %v", v1Cat)
+	}
+
+	var updated CatV2
+	body = `{"name": "Felix", "sex": "male", "color": "Black"}`
+	if code := doJSON(t, app, "PUT", "/api/v2/cats/"+cat.ID, body, &updated); code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+	if updated.Color != "Black" || updated.BirthDate != nil || updated.Breed != "" || !updated.CreatedAt.Equal(*cat.CreatedAt) {
+		t.Errorf("Unexpected updated cat: %+v", updated)
+	}
+
+	var page CatsV2Page
+	doJSON(t, app, "GET", "/api/v2/cats?pageSize=1", "", &page)
+	if len(page.Cats) != 1 || page.TotalSize != 1 || page.NextPageToken != "" {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	if code := doJSON(t, app, "DELETE", "/api/v2/cats/"+cat.ID, "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, code)
+	}
+	if code := doJSON(t, app, "GET", "/api/v2/cats/"+cat.ID, "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, code)
+	}
+}
+
+// Test the v2 representation of the cats created through v1
+func TestCatV2FromV1(t *testing.T) {
+	app := setupOwnerTest(t)
+	testDB().Cats["id1"] = Cat{Name: "Toto", BirthDate: "sometime in 2020"}
+
+	var cat CatV2
+	doJSON(t, app, "GET", "/api/v2/cats/id1", "", &cat)
+	if cat.ID != "id1" || cat.Sex != "unknown" || cat.BirthDate != nil || cat.AgeMonths != nil {
+		t.Errorf("Unexpected cat: %+v", cat)
+	}
+}
+
+// Test the validation of the v2 cats
+func TestCatsV2Validation(t *testing.T) {
+	app := setupOwnerTest(t)
+	testDB().Cats["id1"] = Cat{Name: "Toto"}
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(dateLayout)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing name", `{"sex": "female"}`},
+		{"invalid date", `{"name": "Felix", "birthDate": "2023-02-30"}`},
+		{"future date", `{"name": "Felix", "birthDate": "` + tomorrow + `"}`},
+		{"invalid sex", `{"name": "Felix", "sex": "tom"}`},
+		{"invalid microchip", `{"name": "Felix", "microchipId": "12345"}`},
+		{"unknown owner", `{"name": "Felix", "ownerId": "nobody"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := doJSON(t, app, "POST", "/api/v2/cats", tt.body, nil); code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d on creation, got %d", http.StatusBadRequest, code)
+			}
+			if code := doJSON(t, app, "PUT", "/api/v2/cats/id1", tt.body, nil); code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d on update, got %d", http.StatusBadRequest, code)
+			}
+		})
+	}
+	if testDB().Cats["id1"].Name != "Toto" || len(testDB().Cats) != 1 {
+		t.Errorf("The cats should not have changed: %v", testDB().Cats)
+	}
+}
+
+// Test the deprecation headers of the v1 routes replaced by v2
+func TestV1Deprecation(t *testing.T) {
+	app := setupOwnerTest(t)
+	testDB().Cats["id1"] = Cat{Name: "Toto"}
+
+	tests := []struct {
+		path      string
+		successor string
+	}{
+		{"/api/cats", "</api/v2/cats>; rel=\"successor-version\""},
+		{"/api/cats/id1", "</api/v2/cats/id1>; rel=\"successor-version\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			app.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+			if rr.Header().Get("Deprecation") != "@1790812800" {
+				t.Errorf("Unexpected Deprecation header: %q", rr.Header().Get("Deprecation"))
+			}
+			if _, err := http.ParseTime(rr.Header().Get("Sunset")); err != nil {
+				t.Errorf("Unexpected Sunset header: %q", rr.Header().Get("Sunset"))
+			}
+			if rr.Header().Get("Link") != tt.successor {
+				t.Errorf("Expected the link %q, got %q", tt.successor, rr.Header().Get("Link"))
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v2/cats/id1", nil))
+	if rr.Header().Get("Deprecation") != "" {
+		t.Error("The v2 routes should not be deprecated")
+	}
+}
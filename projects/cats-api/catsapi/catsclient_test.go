@@ -0,0 +1,48 @@
+package catsapi
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"backend/catsclient"
+)
+
+// Test the Go client against the server, keeping them in sync
+func TestCatsClient(t *testing.T) {
+	server := httptest.NewServer(setupOwnerTest(t))
+	defer server.Close()
+	client := catsclient.New(server.URL + "/api")
+	ctx := context.Background()
+
+	for _, name := range []string{"Felix", "Zaza", "Tom"} {
+		if _, err := client.CreateCat(ctx, catsclient.CatInput{Name: name, BirthDate: "2020-05-01", Sex: "male"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.CreateCat(ctx, catsclient.CatInput{Name: "Felix", Sex: "tomcat"}); !errors.Is(err, catsclient.ErrBadRequest) {
+		t.Errorf("Expected a bad request, got %v", err)
+	}
+
+	cats := []catsclient.Cat{}
+	for cat, err := range client.ListCats(ctx, catsclient.ListOptions{PageSize: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		cats = append(cats, cat)
+	}
+	if len(cats) != 3 || cats[0].BirthDate != "2020-05-01" || cats[0].CreatedAt == nil {
+		t.Fatalf("Expected the 3 cats, got %+v", cats)
+	}
+
+	if err := client.DeleteCat(ctx, cats[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetCat(ctx, cats[0].ID); !errors.Is(err, catsclient.ErrNotFound) {
+		t.Errorf("Expected the deleted cat to be not found, got %v", err)
+	}
+	if cat, err := client.GetCat(ctx, cats[1].ID); err != nil || cat.Name != cats[1].Name {
+		t.Errorf("Expected %+v, got %+v: %v", cats[1], cat, err)
+	}
+}
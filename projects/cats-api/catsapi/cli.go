@@ -0,0 +1,466 @@
+package catsapi
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Version of the binary, set at build time with -ldflags "-X backend/catsapi.version=..."
+var version string = "0.0.0-local"
+
+// Exit codes of the commands
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// Command of the CLI, running with its own arguments and returning its exit code
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "Start the HTTP and gRPC servers (default)", serveCommand},
+		{"seed", "Add the owners and cats of fixtures files to the store", seedCommand},
+		{"export", "Write the content of the store as JSON", exportCommand},
+		{"import", "Add or replace the content of the store from an export", importCommand},
+		{"migrate", "Upgrade the data file to the current schema version", migrateCommand},
+		{"health", "Check that the local server answers, for the container health checks", healthCommand},
+	}
+}
+
+// RunCLI runs the command named by the first argument, serving when there is none
+func RunCLI(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		return serveCommand(nil, stdout, stderr)
+	}
+
+	name, args := args[0], args[1:]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		printUsage(stdout)
+		return exitOK
+	case "-health-check", "--health-check":
+		// Flag kept for the existing container images
+		name = "health"
+	}
+
+	index := slices.IndexFunc(commands, func(cmd command) bool { return cmd.name == name })
+	if index < 0 {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n", name)
+		printUsage(stderr)
+		return exitUsage
+	}
+	if name != "serve" {
+		// The logs must not mix with the output of the command
+		originalLogger := Logger
+		Logger = initLogging(stderr)
+		defer func() { Logger = originalLogger }()
+	}
+	return commands[index].run(args, stdout, stderr)
+}
+
+func printUsage(out io.Writer) {
+	fmt.Fprintf(out, "Usage: backend [command] [options]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(out, "\nRun 'backend <command> -h' for the options of a command.\n")
+}
+
+// Creates the flags of a command, with its usage message
+func newFlagSet(name, arguments, description string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: backend %s [options]%s\n\n%s\n", name, arguments, description)
+		if hasFlags(flags) {
+			fmt.Fprintf(stderr, "\nOptions:\n")
+			flags.PrintDefaults()
+		}
+	}
+	return flags
+}
+
+func hasFlags(flags *flag.FlagSet) bool {
+	found := false
+	flags.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// Parses the arguments of a command, expecting the given number of positional ones.
+// Returns the exit code when the command must stop, -1 when it can go on.
+func parseArgs(flags *flag.FlagSet, args []string, positional int) int {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() != positional {
+		fmt.Fprintf(flags.Output(), "Expecting %d argument(s), got %d\n\n", positional, flags.NArg())
+		flags.Usage()
+		return exitUsage
+	}
+	return -1
+}
+
+// Adds the data file option, the commands working on the store without the server
+func dataFileFlag(flags *flag.FlagSet) *string {
+	return flags.String("data-file", getEnv("DATA_FILE", ""), "JSON file storing the databases (env DATA_FILE)")
+}
+
+// Adds the tenant option, checking the tenant ID when one is given
+func tenantFlag(flags *flag.FlagSet, defaultTenant, usage string) func() (string, int) {
+	tenant := flags.String("tenant", defaultTenant, usage)
+	return func() (string, int) {
+		if *tenant != "" && !tenantIDPattern.MatchString(*tenant) {
+			fmt.Fprintf(flags.Output(), "Invalid tenant %q, expecting lowercase letters, digits and dashes\n", *tenant)
+			return "", exitUsage
+		}
+		return *tenant, -1
+	}
+}
+
+func requireDataFile(flags *flag.FlagSet, dataFile string) int {
+	if dataFile == "" {
+		fmt.Fprintf(flags.Output(), "Missing the data file, set -data-file or DATA_FILE\n")
+		return exitUsage
+	}
+	return -1
+}
+
+func fail(stderr io.Writer, format string, args ...any) int {
+	fmt.Fprintf(stderr, "Error: "+format+"\n", args...)
+	return exitFailure
+}
+
+// Serves the APIs until interrupted, saving the databases into the data file when there is one
+func serveCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("serve", "", "Starts the HTTP and gRPC servers, until SIGINT or SIGTERM.", stderr)
+	dataFile := dataFileFlag(flags)
+	snapshotInterval := flags.Duration("snapshot-interval", getEnvDuration("SNAPSHOT_INTERVAL", time.Minute),
+		"Period of the saving of the data file (env SNAPSHOT_INTERVAL)")
+	fixtures := flags.String("fixtures", getEnv("FIXTURES", ""),
+		"JSON or YAML fixtures file or directory, loaded at startup instead of the demo data (env FIXTURES)")
+	empty := flags.Bool("empty", getEnvBool("START_EMPTY", false),
+		"Start without the demo data nor fixtures, for production (env START_EMPTY)")
+	if code := parseArgs(flags, args, 0); code >= 0 {
+		return code
+	}
+	if *empty && *fixtures != "" {
+		fmt.Fprintf(stderr, "The fixtures and the empty start are exclusive\n")
+		return exitUsage
+	}
+
+	Logger.Info("Starting the server")
+	if *dataFile != "" {
+		if err := loadDataFile(*dataFile); err != nil {
+			return fail(stderr, "%v", err)
+		}
+		Logger.Infof("Databases loaded from %s", *dataFile)
+	}
+	if err := loadStartupFixtures(*fixtures, *empty); err != nil {
+		return fail(stderr, "%v", err)
+	}
+	if err := syncCatStore(); err != nil {
+		return fail(stderr, "unable to sync the cats store: %v", err)
+	}
+
+	app := NewApp()
+	port := getEnv("PORT", "8080")
+
+	// Background jobs, the certificate and the replication first for the others to find them
+	stop := make(chan struct{})
+	defer close(stop)
+	tlsConfig, err := startTLS(loadTLSSettings(), stop)
+	if err != nil {
+		return fail(stderr, "%v", err)
+	}
+	if err := startReplication(loadReplicationSettings(), port, stop); err != nil {
+		return fail(stderr, "%v", err)
+	}
+	go runTrashPurge(stop)
+	go webhooks.Run(4, stop)
+	if *dataFile != "" {
+		go runSnapshots(*dataFile, *snapshotInterval, stop)
+	}
+
+	// The gRPC API is served on its own port, next to the REST one
+	go serveGrpc(getEnv("GRPC_PORT", "9090"), tlsConfig)
+	// The admin API too, away from the public traffic
+	admin := serveAdmin(getEnv("ADMIN_PORT", "9091"), os.Getenv("ADMIN_TOKEN"), tlsConfig)
+
+	// No ReadTimeout, which would end the event streams: the writes bound the reading of their body instead
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           app,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: getEnvDuration("READ_HEADER_TIMEOUT", 10*time.Second),
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	go func() {
+		<-ctx.Done()
+		Logger.Info("Stopping the server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if admin != nil {
+			admin.Shutdown(shutdownCtx)
+		}
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("HTTP server listening on %v", server.Addr)
+	if err := listenAndServe(server); !errors.Is(err, http.ErrServerClosed) {
+		return fail(stderr, "%v", err)
+	}
+
+	if *dataFile != "" {
+		if err := writeSnapshot(*dataFile, TakeSnapshot()); err != nil {
+			return fail(stderr, "unable to save the data file: %v", err)
+		}
+		Logger.Infof("Databases saved into %s", *dataFile)
+	}
+	return exitOK
+}
+
+// Saves the databases into the data file periodically, until stopped
+func runSnapshots(dataFile string, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := writeSnapshot(dataFile, TakeSnapshot()); err != nil {
+				Logger.Error("Unable to save the data file: ", err)
+			}
+		}
+	}
+}
+
+// Opens the store of the data file, which must be at the current schema version
+func openStore(flags *flag.FlagSet, dataFile string, stderr io.Writer) int {
+	if code := requireDataFile(flags, dataFile); code >= 0 {
+		return code
+	}
+	if err := loadDataFile(dataFile); err != nil {
+		return fail(stderr, "%v", err)
+	}
+	return -1
+}
+
+// Adds the fixtures, all of them or none when one is invalid, skipping the ones already present
+func seedCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("seed", " PATH",
+		"Adds the owners and cats of a JSON or YAML fixtures file, or of the fixtures files of a directory.\n"+
+			"The entities already present are skipped, so that seeding twice adds nothing.", stderr)
+	dataFile := dataFileFlag(flags)
+	getTenant := tenantFlag(flags, DefaultTenant, "Tenant receiving the fixtures")
+	if code := parseArgs(flags, args, 1); code >= 0 {
+		return code
+	}
+	tenant, code := getTenant()
+	if code >= 0 {
+		return code
+	}
+	if code := openStore(flags, *dataFile, stderr); code >= 0 {
+		return code
+	}
+
+	fixtures, err := readFixtures(flags.Arg(0))
+	if err != nil {
+		return fail(stderr, "%v", err)
+	}
+	result, err := openTenantDB(tenant).loadFixtures(fixtures, time.Now())
+	if err != nil {
+		return fail(stderr, "%v", err)
+	}
+
+	if err := writeSnapshot(*dataFile, TakeSnapshot()); err != nil {
+		return fail(stderr, "%v", err)
+	}
+	fmt.Fprintln(stdout, result)
+	return exitOK
+}
+
+// Writes the content of the store, in the format of the data file
+func exportCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("export", "", "Writes the content of the store as JSON, on the standard output by default.", stderr)
+	dataFile := dataFileFlag(flags)
+	output := flags.String("o", "", "File to write the export into")
+	getTenant := tenantFlag(flags, "", "Only export the data of this tenant")
+	if code := parseArgs(flags, args, 0); code >= 0 {
+		return code
+	}
+	tenant, code := getTenant()
+	if code >= 0 {
+		return code
+	}
+	if code := openStore(flags, *dataFile, stderr); code >= 0 {
+		return code
+	}
+
+	snapshot := TakeSnapshot()
+	if tenant != "" {
+		snapshot.Tenants = map[string]TenantData{tenant: snapshot.Tenants[tenant].orEmpty()}
+	}
+	if *output != "" {
+		if err := writeSnapshot(*output, snapshot); err != nil {
+			return fail(stderr, "%v", err)
+		}
+		return exitOK
+	}
+	if err := encodeSnapshot(stdout, snapshot); err != nil {
+		return fail(stderr, "%v", err)
+	}
+	return exitOK
+}
+
+// Merges an export into the store, or replaces its content
+func importCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("import", " FILE",
+		"Adds the content of an export to the store, the entities with the same IDs being overwritten.", stderr)
+	dataFile := dataFileFlag(flags)
+	replace := flags.Bool("replace", false, "Replace the whole content of the store")
+	if code := parseArgs(flags, args, 1); code >= 0 {
+		return code
+	}
+	if code := openStore(flags, *dataFile, stderr); code >= 0 {
+		return code
+	}
+
+	imported, found, err := readSnapshot(flags.Arg(0))
+	if err == nil && !found {
+		err = fmt.Errorf("file %s not found", flags.Arg(0))
+	}
+	if err != nil {
+		return fail(stderr, "%v", err)
+	}
+	if _, err := migrateSnapshot(&imported); err != nil {
+		return fail(stderr, "%v", err)
+	}
+
+	snapshot := imported
+	if !*replace {
+		snapshot = TakeSnapshot()
+	}
+	var cats, owners, records, photos int
+	for tenant, data := range imported.Tenants {
+		cats, owners, records, photos = cats+len(data.Cats), owners+len(data.Owners), records+len(data.Records), photos+len(data.Photos)
+		if !*replace {
+			target := snapshot.Tenants[tenant].orEmpty()
+			mergeInto(target.Cats, data.Cats)
+			mergeInto(target.Owners, data.Owners)
+			mergeInto(target.Records, data.Records)
+			mergeInto(target.Photos, data.Photos)
+			snapshot.Tenants[tenant] = target
+		}
+	}
+	if err := writeSnapshot(*dataFile, snapshot); err != nil {
+		return fail(stderr, "%v", err)
+	}
+	fmt.Fprintf(stdout, "Imported %d cats, %d owners, %d records and %d photos\n", cats, owners, records, photos)
+	return exitOK
+}
+
+func mergeInto[V any](target, source map[string]V) {
+	for id, value := range source {
+		target[id] = value
+	}
+}
+
+// Upgrades the data file to the current schema version
+func migrateCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("migrate", "", "Upgrades the data file to the current schema version.", stderr)
+	dataFile := dataFileFlag(flags)
+	dryRun := flags.Bool("dry-run", false, "List the migrations without applying them")
+	if code := parseArgs(flags, args, 0); code >= 0 {
+		return code
+	}
+	if code := requireDataFile(flags, *dataFile); code >= 0 {
+		return code
+	}
+
+	snapshot, found, err := readSnapshot(*dataFile)
+	if err != nil {
+		return fail(stderr, "%v", err)
+	}
+	if !found {
+		fmt.Fprintf(stdout, "No data file yet, nothing to migrate\n")
+		return exitOK
+	}
+	applied, err := migrateSnapshot(&snapshot)
+	if err != nil {
+		return fail(stderr, "%v", err)
+	}
+	if len(applied) == 0 {
+		fmt.Fprintf(stdout, "Already at schema version %d\n", snapshotSchemaVersion)
+		return exitOK
+	}
+
+	fmt.Fprintf(stdout, "Migrations to schema version %d:\n  %s\n", snapshotSchemaVersion, strings.Join(applied, "\n  "))
+	if *dryRun {
+		return exitOK
+	}
+	if err := writeSnapshot(*dataFile, snapshot); err != nil {
+		return fail(stderr, "%v", err)
+	}
+	return exitOK
+}
+
+// Checks that the server of this container answers
+func healthCommand(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("health", "", "Checks that the local server answers, exiting with 1 when it does not.", stderr)
+	timeout := flags.Duration("timeout", 3*time.Second, "Maximum duration of the check")
+	if code := parseArgs(flags, args, 0); code >= 0 {
+		return code
+	}
+
+	client := http.Client{Timeout: *timeout}
+	scheme := "http"
+	if settings := loadTLSSettings(); settings.enabled() {
+		reloader, err := newCertificateReloader(settings.certFile, settings.keyFile)
+		if err != nil {
+			return fail(stderr, "%v", err)
+		}
+		// The local server is trusted, its certificate being for its public names; it may ask for a client one
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{
+			InsecureSkipVerify:   true,
+			GetClientCertificate: reloader.getClientCertificate,
+		}}
+		scheme = "https"
+	}
+	res, err := client.Get(scheme + "://localhost:" + getEnv("PORT", "8080") + healthCheckPath)
+	if err != nil {
+		return fail(stderr, "%v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fail(stderr, "unexpected status %s", res.Status)
+	}
+	return exitOK
+}
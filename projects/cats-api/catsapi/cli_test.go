@@ -0,0 +1,138 @@
+package catsapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Runs the CLI on databases restored after the test, returning the exit code and the outputs
+func runTestCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	snapshot := TakeSnapshot()
+	t.Cleanup(func() { RestoreSnapshot(snapshot) })
+
+	var stdout, stderr bytes.Buffer
+	code := RunCLI(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Test the usage errors and the help of the commands
+func TestCLIUsage(t *testing.T) {
+	t.Setenv("DATA_FILE", "")
+	tests := []struct {
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{[]string{"--help"}, exitOK, "Commands:"},
+		{[]string{"seed", "-h"}, exitOK, "Usage: backend seed"},
+		{[]string{"purr"}, exitUsage, `Unknown command "purr"`},
+		{[]string{"export", "-unknown"}, exitUsage, "flag provided but not defined"},
+		{[]string{"export"}, exitUsage, "Missing the data file"},
+		{[]string{"seed", "-data-file", "data.json"}, exitUsage, "Expecting 1 argument(s), got 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			code, stdout, stderr := runTestCLI(t, tt.args...)
+			if code != tt.expectedCode || !strings.Contains(stdout+stderr, tt.expectedOut) {
+				t.Errorf("Expected %d with %q, got %d: %s%s", tt.expectedCode, tt.expectedOut, code, stdout, stderr)
+			}
+		})
+	}
+}
+
+// Test the seeding and the export, then the import into another store
+func TestCLISeedExportImport(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	fixtures := writeTestFile(t, "cats.json", `[
+		{"name": "Zaza", "sex": "female", "birthDate": "2020-05-01"},
+		{"name": "Felix", "microchipId": "250269812345678"}
+	]`)
+
+	code, stdout, stderr := runTestCLI(t, "seed", "-data-file", dataFile, fixtures)
+	if code != exitOK || stdout != "Added 2 cats and 0 owners, 0 already present\n" {
+		t.Fatalf("Unexpected seeding %d: %s%s", code, stdout, stderr)
+	}
+	code, stdout, stderr = runTestCLI(t, "seed", "-data-file", dataFile, fixtures)
+	if code != exitOK || stdout != "Added 0 cats and 0 owners, 2 already present\n" {
+		t.Fatalf("Expected the second seeding to add nothing, got %d: %s%s", code, stdout, stderr)
+	}
+
+	code, stdout, stderr = runTestCLI(t, "export", "-data-file", dataFile)
+	var exported Snapshot
+	if err := json.Unmarshal([]byte(stdout), &exported); code != exitOK || err != nil {
+		t.Fatalf("Unexpected export %d %v: %s", code, err, stderr)
+	}
+	names := map[string]bool{}
+	for _, cat := range exported.Tenants[DefaultTenant].Cats {
+		names[cat.Name] = true
+	}
+	if exported.SchemaVersion != snapshotSchemaVersion || !names["Zaza"] || !names["Felix"] {
+		t.Errorf("Expected the seeded cats in the export, got %+v", exported)
+	}
+
+	exportFile := writeTestFile(t, "export.json", stdout)
+	otherFile := filepath.Join(t.TempDir(), "other.json")
+	code, stdout, stderr = runTestCLI(t, "import", "-data-file", otherFile, "-replace", exportFile)
+	if code != exitOK {
+		t.Fatalf("Unexpected import %d: %s%s", code, stdout, stderr)
+	}
+	imported, _, err := readSnapshot(otherFile)
+	if err != nil || len(imported.Tenants[DefaultTenant].Cats) != len(exported.Tenants[DefaultTenant].Cats) {
+		t.Errorf("Expected %d cats imported, got %d: %v", len(exported.Tenants[DefaultTenant].Cats), len(imported.Tenants[DefaultTenant].Cats), err)
+	}
+}
+
+// Test that an invalid fixture adds no cat
+func TestCLISeedInvalid(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	fixtures := writeTestFile(t, "cats.json", `[{"name": "Zaza"}, {"name": "Felix", "sex": "tomcat"}]`)
+
+	code, _, stderr := runTestCLI(t, "seed", "-data-file", dataFile, fixtures)
+	if code != exitFailure || !strings.Contains(stderr, "cat #2: Invalid sex") {
+		t.Errorf("Expected the second cat to be rejected, got %d: %s", code, stderr)
+	}
+	if _, err := os.Stat(dataFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no data file to be written, got %v", err)
+	}
+}
+
+// Test the migration of a data file written before the schema versions
+func TestCLIMigrate(t *testing.T) {
+	dataFile := writeTestFile(t, "data.json", `{"cats": {"c1": {"id": "c1", "name": "Zaza"}}}`)
+
+	code, _, stderr := runTestCLI(t, "export", "-data-file", dataFile)
+	if code != exitFailure || !strings.Contains(stderr, "run the migrate command") {
+		t.Errorf("Expected the outdated data file to be refused, got %d: %s", code, stderr)
+	}
+
+	code, stdout, _ := runTestCLI(t, "migrate", "-data-file", dataFile, "-dry-run")
+	if snapshot, _, _ := readSnapshot(dataFile); code != exitOK || !strings.Contains(stdout, "2: ") || snapshot.SchemaVersion != 1 {
+		t.Errorf("Expected the migrations to be listed only, got %d: %s", code, stdout)
+	}
+
+	code, stdout, _ = runTestCLI(t, "migrate", "-data-file", dataFile)
+	snapshot, _, _ := readSnapshot(dataFile)
+	if code != exitOK || snapshot.SchemaVersion != snapshotSchemaVersion || snapshot.Tenants[DefaultTenant].Cats["c1"].CreatedAt == nil {
+		t.Errorf("Expected the data file to be migrated, got %d %+v: %s", code, snapshot, stdout)
+	}
+
+	dataFile = writeTestFile(t, "future.json", `{"schemaVersion": 99}`)
+	if code, _, stderr := runTestCLI(t, "migrate", "-data-file", dataFile); code != exitFailure || !strings.Contains(stderr, "newer than the supported") {
+		t.Errorf("Expected the newer data file to be refused, got %d: %s", code, stderr)
+	}
+}
@@ -0,0 +1,248 @@
+package catsapi
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Smallest response body worth compressing, the smaller ones going out as they are
+var compressionMinSize = int(getEnvInt64("COMPRESSION_MIN_SIZE", 1024))
+
+// Content types worth compressing, the images and archives being already compressed
+var compressibleTypes = []string{"text/", "application/json", "application/xml", "application/yaml",
+	"application/javascript", "application/x-ndjson", "image/svg+xml"}
+
+func isCompressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	// The event streams are flushed event by event
+	if mediaType == "text/event-stream" {
+		return false
+	}
+	if strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Picks the response encoding from the Accept-Encoding header, "" for none.
+// Per HTTP, "deflate" is the zlib format.
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQuality := "", 0.0
+	for _, encoding := range []string{"gzip", "deflate"} {
+		if quality := encodingQuality(acceptEncoding, encoding); quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// Returns the quality given to the encoding, by its name or else by the "*" wildcard
+func encodingQuality(acceptEncoding string, encoding string) float64 {
+	quality, wildcardQuality := -1.0, 0.0
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(item, ";")
+		itemQuality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			var err error
+			if itemQuality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case encoding:
+			quality = itemQuality
+		case "*":
+			wildcardQuality = itemQuality
+		}
+	}
+	if quality < 0 {
+		return wildcardQuality
+	}
+	return quality
+}
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+var zlibWriters = sync.Pool{New: func() any { return zlib.NewWriter(nil) }}
+
+// Buffers the start of the response to decide on its compression, once big enough or complete
+type compressWriter struct {
+	http.ResponseWriter
+	encoding   string
+	code       int
+	buffer     []byte
+	committed  bool
+	compressor compressor
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if code < http.StatusOK {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if w.committed {
+		if w.compressor != nil {
+			return w.compressor.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buffer = append(w.buffer, data...)
+	if len(w.buffer) >= compressionMinSize {
+		if err := w.commit(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// Sends the headers, with the compression if worth it, then the buffered start of the body
+func (w *compressWriter) commit() error {
+	w.committed = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buffer))
+	}
+
+	if header.Get("Content-Encoding") == "" && isCompressible(header.Get("Content-Type")) {
+		// The caches must not serve a compressed response to a client not supporting it
+		header.Add("Vary", "Accept-Encoding")
+		if w.encoding != "" && len(w.buffer) >= compressionMinSize && w.code != http.StatusPartialContent {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			// The compressed representation is not byte for byte the same
+			if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+				header.Set("ETag", "W/"+etag)
+			}
+			w.compressor = w.newCompressor()
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.code)
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+	var err error
+	if w.compressor != nil {
+		_, err = w.compressor.Write(buffer)
+	} else {
+		_, err = w.ResponseWriter.Write(buffer)
+	}
+	return err
+}
+
+func (w *compressWriter) newCompressor() compressor {
+	if w.encoding == "gzip" {
+		writer := gzipWriters.Get().(*gzip.Writer)
+		writer.Reset(w.ResponseWriter)
+		return writer
+	}
+	writer := zlibWriters.Get().(*zlib.Writer)
+	writer.Reset(w.ResponseWriter)
+	return writer
+}
+
+// Sends what remains once the handler returned
+func (w *compressWriter) finish() {
+	if !w.committed && w.code != 0 {
+		w.commit()
+	}
+	if w.compressor == nil {
+		return
+	}
+	w.compressor.Close()
+	switch writer := w.compressor.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(writer)
+	case *zlib.Writer:
+		zlibWriters.Put(writer)
+	}
+}
+
+// Used by http.ResponseController, the small flushed responses going out uncompressed
+func (w *compressWriter) FlushError() error {
+	if !w.committed {
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		if err := w.commit(); err != nil {
+			return err
+		}
+	}
+	if w.compressor != nil {
+		if err := w.compressor.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Compresses the responses as negotiated from the Accept-Encoding header,
+// and decompresses the gzip or deflate encoded request bodies
+func withCompression(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch encoding := strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding"))); encoding {
+		case "", "identity":
+		case "gzip", "deflate":
+			var reader io.ReadCloser
+			var err error
+			if encoding == "gzip" {
+				reader, err = gzip.NewReader(req.Body)
+			} else {
+				reader, err = zlib.NewReader(req.Body)
+			}
+			if err != nil {
+				Logger.Info("Unable to decompress the request body: ", err)
+				writeJSON(res, http.StatusBadRequest, "Invalid "+encoding+" request body")
+				return
+			}
+			defer reader.Close()
+			req.Body = reader
+			req.Header.Del("Content-Encoding")
+			req.Header.Del("Content-Length")
+			req.ContentLength = -1
+		default:
+			res.Header().Set("Accept-Encoding", "gzip, deflate")
+			writeJSON(res, http.StatusUnsupportedMediaType, "Unsupported content encoding, expecting gzip or deflate")
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: res, encoding: negotiateEncoding(req.Header.Get("Accept-Encoding"))}
+		next.ServeHTTP(writer, req)
+		writer.finish()
+	})
+}
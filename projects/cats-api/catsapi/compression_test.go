@@ -0,0 +1,160 @@
+package catsapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test the choice of the response encoding
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip, deflate, br", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"*", "gzip"},
+		{"*, gzip;q=0", "deflate"},
+		{"gzip;q=0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			if encoding := negotiateEncoding(tt.acceptEncoding); encoding != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, encoding)
+			}
+		})
+	}
+}
+
+func doCompressed(app http.Handler, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	return rr
+}
+
+// Test the compression of the big responses only
+func TestResponseCompression(t *testing.T) {
+	app := setupOwnerTest(t)
+	for i := range 200 {
+		testDB().Cats[fmt.Sprintf("id%03d", i)] = Cat{Name: "Felix"}
+	}
+	var expected []string
+	doJSON(t, app, "GET", "/api/cats", "", &expected)
+
+	readers := map[string]func(io.Reader) (io.Reader, error){
+		"gzip":    func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"deflate": func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+	}
+	for encoding, newReader := range readers {
+		t.Run(encoding, func(t *testing.T) {
+			rr := doCompressed(app, "/api/cats", encoding)
+			if rr.Header().Get("Content-Encoding") != encoding {
+				t.Fatalf("Expected the %s encoding, got %q", encoding, rr.Header().Get("Content-Encoding"))
+			}
+			if !strings.Contains(strings.Join(rr.Header().Values("Vary"), ","), "Accept-Encoding") {
+				t.Errorf("Expected the response to vary on Accept-Encoding, got %v", rr.Header().Values("Vary"))
+			}
+			reader, err := newReader(rr.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cats []string
+			if err := json.NewDecoder(reader).Decode(&cats); err != nil || len(cats) != len(expected) {
+				t.Errorf("Unexpected decompressed cats: %v, %v", cats, err)
+			}
+		})
+	}
+
+	rr := doCompressed(app, "/api/cats/id001", "gzip")
+	if rr.Header().Get("Content-Encoding") != "" || !strings.Contains(rr.Body.String(), "Felix") {
+		t.Errorf("The small responses should not be compressed: %q", rr.Body.String())
+	}
+	if rr.Header().Values("Vary")[len(rr.Header().Values("Vary"))-1] != "Accept-Encoding" {
+		t.Errorf("Expected the small response to vary on Accept-Encoding, got %v", rr.Header().Values("Vary"))
+	}
+
+	rr = doCompressed(app, "/api/cats", "identity")
+	if rr.Header().Get("Content-Encoding") != "" {
+		t.Error("The response should not be compressed without an accepted encoding")
+	}
+}
+
+// Test the already compressed and streamed contents going out as they are
+func TestCompressionSkippedTypes(t *testing.T) {
+	tests := []struct {
+		contentType string
+		compressed  bool
+	}{
+		{"application/json", true},
+		{"application/problem+json", true},
+		{"text/csv; charset=utf-8", true},
+		{"image/jpeg", false},
+		{"application/zip", false},
+		{"text/event-stream", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			handler := withCompression(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Set("Content-Type", tt.contentType)
+				res.Write(bytes.Repeat([]byte("a"), compressionMinSize*2))
+			}))
+			rr := doCompressed(handler, "/", "gzip")
+			if compressed := rr.Header().Get("Content-Encoding") == "gzip"; compressed != tt.compressed {
+				t.Errorf("Expected compressed %v, got %v", tt.compressed, compressed)
+			}
+		})
+	}
+}
+
+// Test the decompression of the request bodies
+func TestCompressedRequestBody(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	var body bytes.Buffer
+	writer := gzip.NewWriter(&body)
+	writer.Write([]byte(`{"name": "Alice"}`))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/api/owners", &body)
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/owners", strings.NewReader(`{"name": "Alice"}`))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/api/owners", strings.NewReader(`{"name": "Alice"}`))
+	req.Header.Set("Content-Encoding", "br")
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnsupportedMediaType || rr.Header().Get("Accept-Encoding") == "" {
+		t.Errorf("Expected status code %d with the accepted encodings, got %d", http.StatusUnsupportedMediaType, rr.Code)
+	}
+	if len(testDB().Owners) != 1 {
+		t.Errorf("Expected a single owner, got %d", len(testDB().Owners))
+	}
+}
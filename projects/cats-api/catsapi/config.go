@@ -0,0 +1,71 @@
+package catsapi
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Reads a string setting from the environment, with a fallback value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Reads an integer setting from the environment, with a fallback value
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		Logger.Warnf("Invalid value '%s' for %s, using %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// Reads a duration setting (e.g. "90m", "720h") from the environment, with a fallback value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		Logger.Warnf("Invalid value '%s' for %s, using %v", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// Reads a date setting (e.g. "2027-04-30") from the environment, with a fallback value
+func getEnvDate(key string, defaultValue time.Time) time.Time {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.Parse(dateLayout, value)
+	if err != nil {
+		Logger.Warnf("Invalid value '%s' for %s, using %s", value, key, defaultValue.Format(dateLayout))
+		return defaultValue
+	}
+	return parsed
+}
+
+// Reads a boolean setting (e.g. "true", "0") from the environment, with a fallback value
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		Logger.Warnf("Invalid value '%s' for %s, using %v", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,190 @@
+package catsapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Picks the codec of the response from the Accept header, nil when none is acceptable.
+// The ranges are weighted by their quality, then by their specificity, then by the codecs order.
+func negotiateCodec(accept string) *mediaCodec {
+	if strings.TrimSpace(accept) == "" {
+		return jsonCodec
+	}
+
+	var best *mediaCodec
+	bestQuality, bestSpecificity := 0.0, -1
+	for _, codec := range mediaCodecs {
+		quality, specificity := acceptQuality(accept, codec)
+		if quality > bestQuality || (quality == bestQuality && quality > 0 && specificity > bestSpecificity) {
+			best, bestQuality, bestSpecificity = codec, quality, specificity
+		}
+	}
+	return best
+}
+
+// Returns the quality given to the codec by its most specific matching range, and that specificity:
+// 2 for "type/subtype", 1 for "type/*" and 0 for "*/*"
+func acceptQuality(accept string, codec *mediaCodec) (float64, int) {
+	quality, specificity := 0.0, -1
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+		rangeQuality := 1.0
+		if q, found := params["q"]; found {
+			if rangeQuality, err = strconv.ParseFloat(q, 64); err != nil || rangeQuality < 0 || rangeQuality > 1 {
+				continue
+			}
+		}
+
+		for _, codecType := range codec.mediaTypes {
+			rangeSpecificity := -1
+			switch mainType, _, _ := strings.Cut(codecType, "/"); mediaType {
+			case codecType:
+				rangeSpecificity = 2
+			case mainType + "/*":
+				rangeSpecificity = 1
+			case "*/*":
+				rangeSpecificity = 0
+			}
+			if rangeSpecificity > specificity {
+				quality, specificity = rangeQuality, rangeSpecificity
+			}
+		}
+	}
+	return quality, specificity
+}
+
+func supportedMediaTypes() string {
+	types := []string{}
+	for _, codec := range mediaCodecs {
+		types = append(types, codec.mediaTypes[0])
+	}
+	return strings.Join(types, ", ")
+}
+
+// Writes the response in the negotiated format, JSON being the fallback when the encoding fails
+func writeResponse(res http.ResponseWriter, codec *mediaCodec, code int, body any) {
+	res.Header().Add("Vary", "Accept")
+
+	var buffer bytes.Buffer
+	tree, err := toJSONTree(body)
+	if err == nil {
+		err = codec.encode(&buffer, tree)
+	}
+	if err != nil {
+		Logger.Error("Unable to encode the response in ", codec.name, ": ", err)
+		writeJSON(res, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	res.Header().Set("content-type", codec.contentType)
+	res.WriteHeader(code)
+	res.Write(buffer.Bytes())
+}
+
+// Request body in an unsupported media type
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// Request body which could not be decoded in its media type
+type bodyError struct {
+	format string
+	err    error
+}
+
+func (err *bodyError) Error() string {
+	return "invalid " + err.format + " body: " + err.err.Error()
+}
+
+func (err *bodyError) Unwrap() error {
+	return err.err
+}
+
+// Decodes the request body into the target according to its Content-Type, JSON by default.
+// The body is bounded by the limit of the route, and must not have unknown fields.
+func decodeBody(req *http.Request, target any) error {
+	codec := jsonCodec
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return errUnsupportedMediaType
+		}
+		codec = nil
+		for _, candidate := range mediaCodecs {
+			for _, codecType := range candidate.mediaTypes {
+				if codecType == mediaType {
+					codec = candidate
+				}
+			}
+		}
+		if codec == nil {
+			return errUnsupportedMediaType
+		}
+	}
+	body := limitBody(req)
+
+	// The JSON bodies are decoded directly, for the errors to locate the invalid input
+	if codec == jsonCodec {
+		data, err := io.ReadAll(body)
+		if err == nil {
+			err = decodeStrictJSON(data, target)
+		}
+		if err != nil {
+			return &bodyError{codec.name, err}
+		}
+		return nil
+	}
+
+	tree, err := codec.decode(body)
+	if err != nil {
+		return &bodyError{codec.name, err}
+	}
+	if codec.untyped {
+		tree = coerceTree(tree, reflect.TypeOf(target))
+	}
+	if err := decodeTreeStrictly(tree, target); err != nil {
+		return &bodyError{codec.name, err}
+	}
+	return nil
+}
+
+// Decodes the tree of a format other than JSON, rejecting the unknown fields like for JSON
+func decodeTreeStrictly(tree any, target any) error {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		// The offsets in the re-encoded JSON would not match the input
+		inputErr := describeJSONError(err, data)
+		inputErr.offset = -1
+		return inputErr
+	}
+	return nil
+}
+
+// Response to a request body which could not be decoded, naming what is invalid
+func bodyErrorResponse(err error) (int, any) {
+	var maxBytesErr *http.MaxBytesError
+	var decodeErr *bodyError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body too large, the limit is %d bytes", maxBytesErr.Limit)
+	case errors.As(err, &decodeErr):
+		return http.StatusBadRequest, "Invalid " + decodeErr.format + " input: " + decodeErr.err.Error()
+	}
+	return http.StatusUnsupportedMediaType, "Unsupported content type, expecting one of " + supportedMediaTypes()
+}
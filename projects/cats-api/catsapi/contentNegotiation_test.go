@@ -0,0 +1,174 @@
+package catsapi
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Test the choice of the codec from the Accept header
+func TestNegotiateCodec(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", "JSON"},
+		{"*/*", "JSON"},
+		{"application/xml", "XML"},
+		{"text/xml", "XML"},
+		{"application/xml;q=0.5, application/yaml", "YAML"},
+		{"text/*", "XML"},
+		{"text/csv, */*;q=0.1", "CSV"},
+		{"application/msgpack;q=0.9, application/json;q=0.8", "MessagePack"},
+		{"*/*, application/json;q=0", "XML"},
+		{"text/html, application/xml;q=0.9", "XML"},
+		{"image/png", ""},
+		{"application/json;q=0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			name := ""
+			if codec := negotiateCodec(tt.accept); codec != nil {
+				name = codec.name
+			}
+			if name != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, name)
+			}
+		})
+	}
+}
+
+func doNegotiated(app http.Handler, method, path, contentType string, body []byte, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", accept)
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	return rr
+}
+
+// Test the rendering of the responses in every format
+func TestNegotiatedResponses(t *testing.T) {
+	app := setupOwnerTest(t)
+	testDB().Cats["id1"] = Cat{Name: "Toto", Color: "Grey", BirthDate: "2023-04-16"}
+
+	tests := []struct {
+		accept      string
+		path        string
+		contentType string
+		expected    string
+	}{
+		{"application/xml", "/api/cats/id1", "application/xml; charset=utf-8",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n<response><name>Toto</name><birthDate>2023-04-16</birthDate><color>Grey</color></response>"},
+		{"application/xml", "/api/cats", "application/xml; charset=utf-8",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n<response><item>id1</item></response>"},
+		{"application/yaml", "/api/cats/id1", "application/yaml", "name: Toto\nbirthDate: \"2023-04-16\"\ncolor: Grey\n"},
+		{"text/csv", "/api/cats/id1", "text/csv; charset=utf-8", "name,birthDate,color\nToto,2023-04-16,Grey\n"},
+		{"text/csv", "/api/cats/unknown", "text/csv; charset=utf-8", "value\nCat not found\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept+" "+tt.path, func(t *testing.T) {
+			rr := doNegotiated(app, "GET", tt.path, "", nil, tt.accept)
+			if rr.Header().Get("Content-Type") != tt.contentType {
+				t.Errorf("Expected content type %s, got %s", tt.contentType, rr.Header().Get("Content-Type"))
+			}
+			if rr.Header().Get("Vary") != "Accept" {
+				t.Errorf("Expected the response to vary on Accept, got %q", rr.Header().Get("Vary"))
+			}
+			if rr.Body.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, rr.Body.String())
+			}
+		})
+	}
+
+	rr := doNegotiated(app, "GET", "/api/cats/id1", "", nil, "application/msgpack")
+	var cat map[string]any
+	if err := msgpack.Unmarshal(rr.Body.Bytes(), &cat); err != nil || cat["name"] != "Toto" {
+		t.Errorf("Unexpected MessagePack cat: %v, %v", cat, err)
+	}
+}
+
+// Test the refusal of the unsupported types, before running the service
+func TestNotAcceptable(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	rr := doNegotiated(app, "POST", "/api/cats", "application/json", []byte(`{"name": "Felix"}`), "text/html")
+	if rr.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotAcceptable, rr.Code)
+	}
+	if len(listMapKeys(testDB().Cats)) != 0 {
+		t.Error("The cat should not have been created")
+	}
+}
+
+// Test the decoding of the request bodies in every format
+func TestNegotiatedRequestBodies(t *testing.T) {
+	app := setupWebhookTest(t, 1)
+	testDB().Cats["id1"] = Cat{Name: "Toto"}
+
+	msgpackBody, _ := msgpack.Marshal(map[string]any{"type": "vaccination", "date": "2024-01-02", "vaccine": "rabies", "validMonths": 24})
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		{"application/xml", "<record><type>vaccination</type><date>2024-01-02</date><vaccine>rabies</vaccine><validMonths>24</validMonths></record>"},
+		{"application/yaml", "type: vaccination\ndate: \"2024-01-02\"\nvaccine: rabies\nvalidMonths: 24\n"},
+		{"text/csv", "type,date,vaccine,validMonths\nvaccination,2024-01-02,rabies,24\n"},
+		{"application/msgpack", string(msgpackBody)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			rr := doNegotiated(app, "POST", "/api/cats/id1/records", tt.contentType, []byte(tt.body), "application/json")
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+			}
+			recordID := strings.Trim(strings.TrimSpace(rr.Body.String()), `"`)
+			if record := testDB().Records[recordID]; record.ValidMonths != 24 || record.DueDate != "2026-01-02" {
+				t.Errorf("Unexpected record: %+v", record)
+			}
+		})
+	}
+
+	// The lists of the untyped formats
+	xmlWebhook := "<webhook><url>https://example.com/hook</url><events><item>created</item><item>deleted</item></events><secret>" + testWebhookSecret + "</secret></webhook>"
+	if rr := doNegotiated(app, "POST", "/api/webhooks", "application/xml", []byte(xmlWebhook), ""); rr.Code != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	if rr := doNegotiated(app, "POST", "/api/cats", "application/xml", []byte("<cat><name>Felix"), ""); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "Invalid XML input") {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+	if rr := doNegotiated(app, "POST", "/api/cats", "text/plain", []byte("Felix"), ""); rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnsupportedMediaType, rr.Code)
+	}
+}
+
+// Test that the unquoted YAML dates and times are kept as text
+func TestYAMLUnquotedDates(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	rr := doNegotiated(app, "POST", "/api/cats", "application/yaml", []byte("name: Felix\nbirthDate: 2020-05-01\n"), "application/json")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	catID := strings.Trim(strings.TrimSpace(rr.Body.String()), `"`)
+	if rr := doNegotiated(app, "GET", "/api/cats/"+catID, "", nil, "application/yaml"); !strings.Contains(rr.Body.String(), `birthDate: "2020-05-01"`) {
+		t.Errorf("Expected the birth date to be kept, got %q", rr.Body.String())
+	}
+
+	tree, err := decodeYAMLTree(strings.NewReader("at: 2024-01-02T03:04:05Z\ndates: [2024-01-02]\n"))
+	expected := map[string]any{"at": "2024-01-02T03:04:05Z", "dates": []any{"2024-01-02"}}
+	if err != nil || !reflect.DeepEqual(tree, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, tree, err)
+	}
+}
@@ -0,0 +1,124 @@
+package catsapi
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cross-origin access granted to the browser apps, none when no origin is allowed
+type corsPolicy struct {
+	allowedOrigins   []string // "*" allowing any origin
+	allowedMethods   []string
+	allowedHeaders   []string // "*" allowing any requested header
+	allowCredentials bool
+	maxAge           time.Duration
+}
+
+// Response headers readable by the browser apps, on top of the CORS-safelisted ones
+var corsExposedHeaders = "X-Request-ID, Location, Link, Deprecation, Sunset"
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Reads the CORS policy from the environment
+func loadCorsPolicy() corsPolicy {
+	policy := corsPolicy{
+		allowedOrigins:   splitList(getEnv("CORS_ALLOWED_ORIGINS", "")),
+		allowedMethods:   splitList(strings.ToUpper(getEnv("CORS_ALLOWED_METHODS", "GET, POST, PUT, DELETE"))),
+		allowedHeaders:   splitList(getEnv("CORS_ALLOWED_HEADERS", "Content-Type, Content-Encoding, Accept, Authorization, X-Request-ID, X-Tenant-ID, Last-Event-ID")),
+		allowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
+		maxAge:           getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+	}
+	// Any website could then make requests with the cookies and credentials of its visitors
+	if policy.allowCredentials && slices.Contains(policy.allowedOrigins, "*") {
+		Logger.Warn("CORS_ALLOW_CREDENTIALS is refused with any origin allowed by CORS_ALLOWED_ORIGINS=*, list the origins instead")
+		policy.allowCredentials = false
+	}
+	return policy
+}
+
+func (policy corsPolicy) allowsOrigin(origin string) bool {
+	return slices.Contains(policy.allowedOrigins, "*") || slices.Contains(policy.allowedOrigins, origin)
+}
+
+// Checks the headers requested by a preflight, comparing them case-insensitively
+func (policy corsPolicy) allowsHeaders(requested string) bool {
+	if slices.Contains(policy.allowedHeaders, "*") {
+		return true
+	}
+	for _, header := range splitList(requested) {
+		if !slices.ContainsFunc(policy.allowedHeaders, func(allowed string) bool { return strings.EqualFold(allowed, header) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// Answers the preflight requests and adds the CORS headers to the responses of the allowed origins
+func withCORS(policy corsPolicy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if origin == "" || len(policy.allowedOrigins) == 0 {
+			next.ServeHTTP(res, req)
+			return
+		}
+		// The allowed origin is echoed, so the cached responses depend on it
+		res.Header().Add("Vary", "Origin")
+		preflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+
+		if !policy.allowsOrigin(origin) {
+			if preflight {
+				Logger.Infof("CORS preflight from the origin '%s' rejected", origin)
+				writeJSON(res, http.StatusForbidden, "Origin not allowed")
+				return
+			}
+			// Served without the CORS headers, the browser hiding the response from the app
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		res.Header().Set("Access-Control-Allow-Origin", origin)
+		// Only for the origins listed one by one, never for the ones matching the wildcard
+		if policy.allowCredentials && slices.Contains(policy.allowedOrigins, origin) {
+			res.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			res.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		res.Header().Add("Vary", "Access-Control-Request-Method")
+		res.Header().Add("Vary", "Access-Control-Request-Headers")
+		method := req.Header.Get("Access-Control-Request-Method")
+		requestedHeaders := req.Header.Get("Access-Control-Request-Headers")
+		if !slices.Contains(policy.allowedMethods, method) || !policy.allowsHeaders(requestedHeaders) {
+			Logger.Infof("CORS preflight of '%s' with the headers '%s' rejected", method, requestedHeaders)
+			writeJSON(res, http.StatusForbidden, "Method or headers not allowed")
+			return
+		}
+
+		res.Header().Set("Access-Control-Allow-Methods", strings.Join(policy.allowedMethods, ", "))
+		if slices.Contains(policy.allowedHeaders, "*") {
+			// The wildcard is not honored with credentials, so the requested headers are echoed
+			if requestedHeaders != "" {
+				res.Header().Set("Access-Control-Allow-Headers", requestedHeaders)
+			}
+		} else {
+			res.Header().Set("Access-Control-Allow-Headers", strings.Join(policy.allowedHeaders, ", "))
+		}
+		res.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.maxAge.Seconds())))
+		res.WriteHeader(http.StatusNoContent)
+	})
+}
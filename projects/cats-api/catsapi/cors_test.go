@@ -0,0 +1,131 @@
+package catsapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testCorsPolicy = corsPolicy{
+	allowedOrigins:   []string{"https://app.example.com"},
+	allowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+	allowedHeaders:   []string{"Content-Type", "X-Request-ID"},
+	allowCredentials: true,
+	maxAge:           10 * time.Minute,
+}
+
+func doCors(handler http.Handler, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/cats", nil)
+	req.Header.Set("Origin", origin)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// Test the preflight requests
+func TestCorsPreflight(t *testing.T) {
+	setupOwnerTest(t)
+	handler := withCORS(testCorsPolicy, NewApp())
+
+	tests := []struct {
+		name     string
+		origin   string
+		method   string
+		headers  string
+		expected int
+	}{
+		{"allowed", "https://app.example.com", "PUT", "content-type, x-request-id", http.StatusNoContent},
+		{"unknown origin", "https://evil.example.com", "PUT", "", http.StatusForbidden},
+		{"unknown method", "https://app.example.com", "PATCH", "", http.StatusForbidden},
+		{"unknown header", "https://app.example.com", "POST", "X-Custom", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doCors(handler, "OPTIONS", tt.origin, map[string]string{
+				"Access-Control-Request-Method":  tt.method,
+				"Access-Control-Request-Headers": tt.headers,
+			})
+			if rr.Code != tt.expected {
+				t.Fatalf("Expected status code %d, got %d", tt.expected, rr.Code)
+			}
+			if tt.expected != http.StatusNoContent {
+				return
+			}
+			expectedHeaders := map[string]string{
+				"Access-Control-Allow-Origin":      tt.origin,
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, DELETE",
+				"Access-Control-Allow-Headers":     "Content-Type, X-Request-ID",
+				"Access-Control-Max-Age":           "600",
+			}
+			for key, value := range expectedHeaders {
+				if rr.Header().Get(key) != value {
+					t.Errorf("Expected %s: %q, got %q", key, value, rr.Header().Get(key))
+				}
+			}
+		})
+	}
+}
+
+// Test the CORS headers of the actual requests
+func TestCorsRequests(t *testing.T) {
+	setupOwnerTest(t)
+	handler := withCORS(testCorsPolicy, NewApp())
+
+	rr := doCors(handler, "GET", "https://app.example.com", nil)
+	if rr.Code != http.StatusOK || rr.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("Expected the origin to be allowed, got %d %v", rr.Code, rr.Header())
+	}
+	if rr.Header().Get("Access-Control-Expose-Headers") == "" || rr.Header().Get("Vary") != "Origin" {
+		t.Errorf("Expected the exposed headers and Vary: Origin, got %v", rr.Header())
+	}
+
+	rr = doCors(handler, "GET", "https://evil.example.com", nil)
+	if rr.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("The unknown origins should not be allowed")
+	}
+
+	rr = doCors(withCORS(corsPolicy{}, NewApp()), "GET", "https://app.example.com", nil)
+	if rr.Header().Get("Access-Control-Allow-Origin") != "" || rr.Header().Get("Vary") == "Origin" {
+		t.Error("No origin should be allowed by default")
+	}
+}
+
+// Test the policy read from the environment
+func TestLoadCorsPolicy(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com")
+	t.Setenv("CORS_ALLOWED_HEADERS", "*")
+	t.Setenv("CORS_MAX_AGE", "1h")
+
+	policy := loadCorsPolicy()
+	if !policy.allowsOrigin("https://b.example.com") || policy.allowsOrigin("https://c.example.com") {
+		t.Errorf("Unexpected allowed origins: %v", policy.allowedOrigins)
+	}
+	if !policy.allowsHeaders("X-Anything") || policy.maxAge != time.Hour || policy.allowCredentials {
+		t.Errorf("Unexpected policy: %+v", policy)
+	}
+}
+
+// Test that the credentials are never allowed to any origin
+func TestCorsWildcardCredentials(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	if policy := loadCorsPolicy(); policy.allowCredentials {
+		t.Errorf("Expected the credentials to be refused with the wildcard origin, got %+v", policy)
+	}
+
+	policy := testCorsPolicy
+	policy.allowedOrigins = []string{"*", "https://app.example.com"}
+	handler := withCORS(policy, http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	if rr := doCors(handler, "GET", "https://evil.example.com", nil); rr.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Expected no credentials for an origin matching the wildcard, got %v", rr.Header())
+	}
+	if rr := doCors(handler, "GET", "https://app.example.com", nil); rr.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("Expected the credentials for a listed origin, got %v", rr.Header())
+	}
+}
@@ -0,0 +1,141 @@
+package catsapi
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Change notification sent to the cat events subscribers
+type CatEvent struct {
+	ID    int64     `json:"id"`
+	Type  string    `json:"type"`
+	Time  time.Time `json:"time"`
+	CatID string    `json:"catId"`
+	Cat   *Cat      `json:"cat,omitempty"`
+}
+
+// Type of the event replacing the missed ones which are no longer known, its ID being the one to resume from
+const catEventReset = "reset"
+
+// Receiving side of the events, closed by the broker when the subscriber is too slow
+type eventSubscription struct {
+	events chan CatEvent
+}
+
+// Fans out the cat events to the subscribers, keeping the latest ones for the reconnecting clients
+type eventBroker struct {
+	lock   sync.Mutex
+	lastID int64
+	replay []CatEvent
+	// Last event dropped from the replay buffer, the one preceding its first event
+	droppedID   int64
+	replaySize  int
+	bufferSize  int
+	subscribers map[*eventSubscription]struct{}
+}
+
+func newEventBroker(replaySize, bufferSize int) *eventBroker {
+	return &eventBroker{
+		replaySize:  replaySize,
+		bufferSize:  bufferSize,
+		subscribers: map[*eventSubscription]struct{}{},
+	}
+}
+
+// Number of events kept for the reconnecting clients, and buffered for each subscriber, in each tenant
+var (
+	eventsReplaySize = int(getEnvInt64("EVENTS_REPLAY_SIZE", 256))
+	eventsBufferSize = int(getEnvInt64("EVENTS_BUFFER_SIZE", 64))
+)
+
+// Numbers the event and sends it to every subscriber without ever blocking.
+// The IDs are the publication times in microseconds, bumped to stay increasing, so they never start again
+// from 1 when the server restarts or another replica takes the lead.
+func (broker *eventBroker) Publish(event CatEvent) CatEvent {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+
+	broker.lastID = max(broker.lastID+1, event.Time.UnixMicro())
+	event.ID = broker.lastID
+
+	broker.replay = append(broker.replay, event)
+	if len(broker.replay) > broker.replaySize {
+		broker.droppedID = broker.replay[len(broker.replay)-broker.replaySize-1].ID
+		broker.replay = broker.replay[len(broker.replay)-broker.replaySize:]
+	}
+
+	for sub := range broker.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			// Slow consumer: drop it rather than holding back the others
+			delete(broker.subscribers, sub)
+			close(sub.events)
+		}
+	}
+	return event
+}
+
+// Registers a subscriber, returning the buffered events following lastEventID (none when zero).
+// When that event is no longer buffered, or was published by another replica or before a restart, the missed
+// events are unknown: a single reset event then tells the subscriber to reload the cats.
+func (broker *eventBroker) Subscribe(lastEventID int64) (*eventSubscription, []CatEvent) {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+
+	missed := []CatEvent{}
+	if lastEventID > 0 {
+		resumeAt := slices.IndexFunc(broker.replay, func(event CatEvent) bool { return event.ID == lastEventID })
+		if resumeAt >= 0 || lastEventID == broker.droppedID {
+			missed = append(missed, broker.replay[resumeAt+1:]...)
+		} else {
+			missed = append(missed, CatEvent{ID: broker.lastID, Type: catEventReset, Time: time.Now().UTC()})
+		}
+	}
+
+	sub := &eventSubscription{events: make(chan CatEvent, broker.bufferSize)}
+	broker.subscribers[sub] = struct{}{}
+	return sub, missed
+}
+
+func (broker *eventBroker) Unsubscribe(sub *eventSubscription) {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+
+	if _, found := broker.subscribers[sub]; found {
+		delete(broker.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Publishes the event matching a cat change to the subscribers and webhooks of the tenant, as seen by the API clients
+func (db *tenantDB) publishCatEvent(action string, catID string, after *Cat) {
+	eventType := map[string]string{
+		"create":  "created",
+		"update":  "updated",
+		"delete":  "deleted",
+		"restore": "created",
+	}[action]
+
+	// Purged cats were already reported as deleted
+	if eventType == "" {
+		return
+	}
+
+	event := CatEvent{Type: eventType, Time: time.Now().UTC(), CatID: catID}
+	if eventType != "deleted" {
+		event.Cat = after
+	}
+	// The subscribers and webhooks are only told the committed changes
+	afterCommit(func() {
+		webhooks.Enqueue(db.tenant, db.events.Publish(event))
+	})
+}
+
+// Traces a cat change in the audit trail and notifies the events subscribers of the tenant
+func (db *tenantDB) catChanged(ctx context.Context, action string, catID string, before, after *Cat) {
+	db.auditCat(ctx, action, catID, before, after)
+	db.publishCatEvent(action, catID, after)
+}
@@ -0,0 +1,87 @@
+package catsapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Interval between the keep-alive comments sent on idle event streams
+var eventsHeartbeat = getEnvDuration("EVENTS_HEARTBEAT", 15*time.Second)
+
+// Maximum time to write an event to a client before dropping it
+const eventWriteTimeout = 10 * time.Second
+
+// Streams the cat changes of the tenant as Server-Sent Events, so it cannot go through makeHandlerFunc
+func streamCatEvents(res http.ResponseWriter, req *http.Request) {
+	// Browsers send the header when reconnecting, the query parameter allows resuming a fresh EventSource
+	lastEventID := req.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = req.URL.Query().Get("lastEventId")
+	}
+	var resumeFrom int64
+	if lastEventID != "" {
+		var err error
+		if resumeFrom, err = strconv.ParseInt(lastEventID, 10, 64); err != nil {
+			writeJSON(res, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	events := requestDB(req.Context()).events
+	sub, missed := events.Subscribe(resumeFrom)
+	defer events.Unsubscribe(sub)
+	Logger.Infof("New cat events subscriber, resuming after %d with %d missed events", resumeFrom, len(missed))
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(res)
+	send := func(chunk string) bool {
+		controller.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if _, err := fmt.Fprint(res, chunk); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+
+	if !send("retry: 3000\n\n") {
+		return
+	}
+	for _, event := range missed {
+		if !send(formatCatEvent(event)) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			if !send(": heartbeat\n\n") {
+				return
+			}
+		case event, open := <-sub.events:
+			if !open {
+				Logger.Info("Disconnecting a slow cat events subscriber")
+				return
+			}
+			if !send(formatCatEvent(event)) {
+				return
+			}
+		}
+	}
+}
+
+func formatCatEvent(event CatEvent) string {
+	data, _ := json.Marshal(event)
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
@@ -0,0 +1,148 @@
+package catsapi
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Reads the next event of a Server-Sent Events stream, skipping comments and retry hints
+func readSSEEvent(t *testing.T, reader *bufio.Reader) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read the event stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if fields["event"] != "" {
+				return fields
+			}
+			continue
+		}
+		if name, value, found := strings.Cut(line, ": "); found && name != "" {
+			fields[name] = value
+		}
+	}
+}
+
+func openEventStream(t *testing.T, server *httptest.Server, lastEventID string) *bufio.Reader {
+	t.Helper()
+	req, _ := http.NewRequest("GET", server.URL+"/api/cats/events", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open the event stream: %v", err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", res.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(res.Body)
+}
+
+// Test the live streaming and resuming of the cat events
+func TestCatEventsStream(t *testing.T) {
+	app := setupOwnerTest(t)
+	originalEvents := testDB().events
+	testDB().events = newEventBroker(10, 10)
+	defer func() { testDB().events = originalEvents }()
+
+	// Closed after the streams opened by openEventStream
+	server := httptest.NewServer(app)
+	t.Cleanup(server.Close)
+
+	stream := openEventStream(t, server, "")
+
+	var catID string
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Toto"}`, &catID)
+	doJSON(t, app, "DELETE", "/api/cats/"+catID, "", nil)
+
+	created := readSSEEvent(t, stream)
+	if created["event"] != "created" || created["id"] == "" || !strings.Contains(created["data"], `"name":"Toto"`) {
+		t.Errorf("Unexpected created event: %v", created)
+	}
+	deleted := readSSEEvent(t, stream)
+	if deleted["event"] != "deleted" || deleted["id"] <= created["id"] || !strings.Contains(deleted["data"], catID) {
+		t.Errorf("Unexpected deleted event: %v", deleted)
+	}
+
+	// A reconnecting client only gets what it missed
+	resumed := openEventStream(t, server, created["id"])
+	if event := readSSEEvent(t, resumed); event["id"] != deleted["id"] {
+		t.Errorf("Expected to resume with the deleted event, got %v", event)
+	}
+
+	// A client resuming from an unknown event, of another replica or before a restart, is told to reload the cats
+	reset := readSSEEvent(t, openEventStream(t, server, "1"))
+	if reset["event"] != "reset" || reset["id"] != deleted["id"] {
+		t.Errorf("Expected a reset event resuming after the deleted one, got %v", reset)
+	}
+}
+
+// Test the heartbeat comments on idle streams
+func TestCatEventsHeartbeat(t *testing.T) {
+	originalHeartbeat := eventsHeartbeat
+	eventsHeartbeat = 10 * time.Millisecond
+	defer func() { eventsHeartbeat = originalHeartbeat }()
+
+	server := httptest.NewServer(NewApp())
+	t.Cleanup(server.Close)
+
+	stream := openEventStream(t, server, "")
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read the event stream: %v", err)
+		}
+		if line == ": heartbeat\n" {
+			return
+		}
+	}
+}
+
+// Test the bounded replay buffer and the slow consumers eviction
+func TestEventBroker(t *testing.T) {
+	broker := newEventBroker(3, 2)
+
+	slow, _ := broker.Subscribe(0)
+	for i := 0; i < 5; i++ {
+		broker.Publish(CatEvent{Type: "created"})
+	}
+
+	// The slow subscriber got its buffer filled, then was closed
+	received := 0
+	for range slow.events {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("Expected 2 buffered events before the eviction, got %d", received)
+	}
+
+	_, missed := broker.Subscribe(2)
+	if len(missed) != 3 || missed[0].ID != 3 || missed[2].ID != 5 {
+		t.Errorf("Expected the last 3 events to be replayed, got %+v", missed)
+	}
+	if _, missed := broker.Subscribe(0); len(missed) != 0 {
+		t.Errorf("Expected no replay for a new subscriber, got %d", len(missed))
+	}
+
+	// The event 1 was dropped from the buffer, the ones following it are lost
+	if _, missed := broker.Subscribe(1); len(missed) != 1 || missed[0].Type != catEventReset || missed[0].ID != 5 {
+		t.Errorf("Expected a reset event, got %+v", missed)
+	}
+
+	// The IDs follow the publication times, so they keep increasing after a restart
+	now := time.Now()
+	if event := newEventBroker(3, 2).Publish(CatEvent{Type: "created", Time: now}); event.ID != now.UnixMicro() {
+		t.Errorf("Expected the ID %d, got %d", now.UnixMicro(), event.ID)
+	}
+}
@@ -0,0 +1,226 @@
+package catsapi
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:embed fixtures/demo.yaml
+var demoFixtures embed.FS
+
+// Fixtures are the initial data of the databases, read from JSON or YAML files.
+// A file holding a list instead of an object only lists cats.
+type Fixtures struct {
+	Owners []Owner      `json:"owners"`
+	Cats   []fixtureCat `json:"cats"`
+}
+
+// Cat of the fixtures, in the v2 API format with an optional UUID
+type fixtureCat struct {
+	ID string `json:"id"`
+	CatV2Input
+}
+
+// Namespace of the IDs derived from the content of the fixtures without an ID
+var fixturesNamespace = uuid.MustParse("3f0d7c4e-5a7b-4d0e-9c1a-2b6f8e4d9a10")
+
+// Result of the loading of fixtures
+type fixturesResult struct {
+	AddedCats, AddedOwners, Skipped int
+}
+
+func (result fixturesResult) String() string {
+	return fmt.Sprintf("Added %d cats and %d owners, %d already present", result.AddedCats, result.AddedOwners, result.Skipped)
+}
+
+// Reads the fixtures of a file, or of the .json, .yaml and .yml files of a directory in their name order
+func readFixtures(path string) (Fixtures, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Fixtures{}, err
+	}
+	if !info.IsDir() {
+		return readFixturesFile(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+	}
+
+	fsys := os.DirFS(path)
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return Fixtures{}, err
+	}
+	all := Fixtures{}
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains([]string{".json", ".yaml", ".yml"}, filepath.Ext(entry.Name())) {
+			continue
+		}
+		fixtures, err := readFixturesFile(fsys, entry.Name())
+		if err != nil {
+			return Fixtures{}, err
+		}
+		all.Owners = append(all.Owners, fixtures.Owners...)
+		all.Cats = append(all.Cats, fixtures.Cats...)
+	}
+	return all, nil
+}
+
+// Decodes a fixtures file as strictly as the request bodies, YAML unless its extension is .json
+func readFixturesFile(fsys fs.FS, name string) (Fixtures, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return Fixtures{}, err
+	}
+
+	var fixtures Fixtures
+	if filepath.Ext(name) == ".json" {
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+			err = decodeStrictJSON(data, &fixtures.Cats)
+		} else {
+			err = decodeStrictJSON(data, &fixtures)
+		}
+	} else {
+		var tree any
+		if tree, err = decodeYAMLTree(bytes.NewReader(data)); err == nil {
+			if _, isList := tree.([]any); isList {
+				err = decodeTreeStrictly(tree, &fixtures.Cats)
+			} else {
+				err = decodeTreeStrictly(tree, &fixtures)
+			}
+		}
+	}
+	if err != nil {
+		return Fixtures{}, fmt.Errorf("invalid fixtures file %s: %w", name, err)
+	}
+	return fixtures, nil
+}
+
+// Validates all the fixtures, then adds the owners and cats which are not in the databases of the tenant yet:
+// loading the same fixtures again adds nothing, and the cats in the trash are not restored.
+func (db *tenantDB) loadFixtures(fixtures Fixtures, today time.Time) (fixturesResult, error) {
+	owners, cats, err := prepareFixtures(fixtures, today)
+	if err != nil {
+		return fixturesResult{}, err
+	}
+
+	dbLock.Lock()
+	defer dbLock.Unlock()
+
+	newCats := 0
+	for _, cat := range cats {
+		if _, found := db.Cats[cat.ID]; !found {
+			newCats++
+		}
+		_, found := db.Owners[cat.OwnerID]
+		if cat.OwnerID != "" && !found && !slices.ContainsFunc(owners, func(owner Owner) bool { return owner.ID == cat.OwnerID }) {
+			return fixturesResult{}, fmt.Errorf("cat %q: owner %s not found", cat.Name, cat.OwnerID)
+		}
+	}
+	if err := db.checkCatsQuota(newCats); err != nil {
+		return fixturesResult{}, err
+	}
+
+	var result fixturesResult
+	for _, owner := range owners {
+		if _, found := db.Owners[owner.ID]; found {
+			result.Skipped++
+			continue
+		}
+		db.Owners[owner.ID] = owner
+		result.AddedOwners++
+	}
+	for _, cat := range cats {
+		if _, found := db.Cats[cat.ID]; found {
+			result.Skipped++
+			continue
+		}
+		if _, err := db.saveCat(context.Background(), "create", cat.ID, nil, cat); err != nil {
+			return result, err
+		}
+		result.AddedCats++
+	}
+	return result, nil
+}
+
+// Validates the fixtures and sets their IDs, derived from their content when missing: the entries with the
+// same content, like two cats only named Felix, are told apart by their rank among them
+func prepareFixtures(fixtures Fixtures, today time.Time) ([]Owner, []Cat, error) {
+	ids := map[string]bool{}
+	occurrences := map[string]int{}
+	checkID := func(kind string, id *string, content ...string) error {
+		if *id == "" {
+			key := kind + "\n" + strings.Join(content, "\n")
+			if occurrences[key]++; occurrences[key] > 1 {
+				key += "\n" + strconv.Itoa(occurrences[key])
+			}
+			*id = uuid.NewSHA1(fixturesNamespace, []byte(key)).String()
+		} else if _, err := uuid.Parse(*id); err != nil {
+			return fmt.Errorf("invalid ID %q, expecting a UUID", *id)
+		}
+		if ids[*id] {
+			return fmt.Errorf("duplicate ID %s", *id)
+		}
+		ids[*id] = true
+		return nil
+	}
+
+	owners := []Owner{}
+	for i, owner := range fixtures.Owners {
+		if err := owner.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("owner #%d: %w", i+1, err)
+		}
+		if err := checkID("owner", &owner.ID, owner.Name, owner.Email); err != nil {
+			return nil, nil, fmt.Errorf("owner #%d: %w", i+1, err)
+		}
+		owners = append(owners, owner)
+	}
+
+	cats := []Cat{}
+	for i, fixture := range fixtures.Cats {
+		cat := Cat{ID: fixture.ID}
+		if msg := fixture.applyTo(&cat, today); msg != "" {
+			return nil, nil, fmt.Errorf("cat #%d: %s", i+1, msg)
+		}
+		if err := checkID("cat", &cat.ID, cat.Name, cat.BirthDate, cat.MicrochipID); err != nil {
+			return nil, nil, fmt.Errorf("cat #%d: %w", i+1, err)
+		}
+		cats = append(cats, cat)
+	}
+	return owners, cats, nil
+}
+
+// Loads the initial data of the default tenant when the server starts: the fixtures of the path,
+// none in the empty mode, else the demo data
+func loadStartupFixtures(path string, empty bool) error {
+	if empty {
+		Logger.Info("Starting without fixtures")
+		return nil
+	}
+
+	var fixtures Fixtures
+	var err error
+	if path != "" {
+		fixtures, err = readFixtures(path)
+	} else {
+		path = "demo data"
+		fixtures, err = readFixturesFile(demoFixtures, "fixtures/demo.yaml")
+	}
+	if err != nil {
+		return err
+	}
+	result, err := openTenantDB(DefaultTenant).loadFixtures(fixtures, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid fixtures %s: %w", path, err)
+	}
+	Logger.Infof("Fixtures %s loaded: %v", path, result)
+	return nil
+}
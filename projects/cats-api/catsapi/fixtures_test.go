@@ -0,0 +1,127 @@
+package catsapi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFixturesDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// Test the loading of a directory mixing the formats, loaded twice like on a restart
+func TestLoadFixtures(t *testing.T) {
+	setupOwnerTest(t)
+	dir := writeFixturesDir(t, map[string]string{
+		"1-owners.yaml": "owners:\n  - id: 0b9f6a52-4c4e-4f51-9a53-6f7c1d2e3a4b\n    name: Alice\n",
+		"2-cats.yml":    "- name: Felix\n  birthDate: 2020-05-01\n  ownerId: 0b9f6a52-4c4e-4f51-9a53-6f7c1d2e3a4b\n",
+		"3-cats.json":   `{"cats": [{"id": "5d6c1f7e-8a9b-4c3d-9e2f-1a0b3c4d5e6f", "name": "Zaza", "sex": "female"}]}`,
+		"README.md":     "Not a fixtures file",
+	})
+
+	fixtures, err := readFixtures(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := testDB().loadFixtures(fixtures, time.Now())
+	if err != nil || result != (fixturesResult{AddedCats: 2, AddedOwners: 1}) {
+		t.Fatalf("Unexpected loading %+v: %v", result, err)
+	}
+	felixID := ""
+	for catID, cat := range testDB().Cats {
+		if cat.Name == "Felix" {
+			felixID = catID
+		}
+	}
+	if felix := testDB().Cats[felixID]; felix.BirthDate != "2020-05-01" || felix.OwnerID == "" || felix.CreatedAt == nil {
+		t.Errorf("Unexpected cat %+v", felix)
+	}
+
+	// The cats in the trash are not restored, and the derived IDs do not change
+	testDB().trashCat(context.Background(), felixID, time.Now())
+	fixtures, _ = readFixtures(dir)
+	result, err = testDB().loadFixtures(fixtures, time.Now())
+	if err != nil || result != (fixturesResult{Skipped: 3}) || len(testDB().Cats) != 2 {
+		t.Errorf("Expected the second loading to add nothing, got %+v: %v", result, err)
+	}
+	if _, found := testDB().findCat(felixID); found {
+		t.Error("Expected the cat to stay in the trash")
+	}
+}
+
+// Test that the invalid fixtures are rejected as a whole
+func TestLoadFixturesInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected string
+	}{
+		{"unknown field", "cats.yaml", "- name: Felix\n  colour: Grey\n", `invalid fixtures file cats.yaml: unknown field "colour"`},
+		{"JSON syntax", "cats.json", `[{"name": "Felix",}]`, "invalid fixtures file cats.json: invalid character '}' looking for beginning of object key string at offset 19"},
+		{"invalid cat", "cats.yaml", "cats:\n  - name: Felix\n  - name: Zaza\n    sex: queen\n", "cat #2: Invalid sex"},
+		{"invalid ID", "cats.yaml", "- id: id1\n  name: Toto\n", `cat #1: invalid ID "id1", expecting a UUID`},
+		{"duplicate", "cats.yaml", "- id: 5d6c1f7e-8a9b-4c3d-9e2f-1a0b3c4d5e6f\n  name: Felix\n- id: 5d6c1f7e-8a9b-4c3d-9e2f-1a0b3c4d5e6f\n  name: Zaza\n", "cat #2: duplicate ID"},
+		{"unknown owner", "cats.yaml", "- name: Felix\n  ownerId: 0b9f6a52-4c4e-4f51-9a53-6f7c1d2e3a4b\n", `cat "Felix": owner 0b9f6a52-4c4e-4f51-9a53-6f7c1d2e3a4b not found`},
+		{"invalid owner", "owners.yaml", "owners:\n  - email: alice@example.com\n", "owner #1: Invalid input, the owner name is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupOwnerTest(t)
+			dir := writeFixturesDir(t, map[string]string{tt.file: tt.content})
+
+			fixtures, err := readFixtures(dir)
+			if err == nil {
+				_, err = testDB().loadFixtures(fixtures, time.Now())
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+			if len(testDB().Cats) != 0 || len(testDB().Owners) != 0 {
+				t.Errorf("Expected nothing to be loaded, got %v %v", testDB().Cats, testDB().Owners)
+			}
+		})
+	}
+}
+
+// Test that the cats with the same content get their own IDs, kept on the next loading
+func TestLoadFixturesNamesakes(t *testing.T) {
+	setupOwnerTest(t)
+	dir := writeFixturesDir(t, map[string]string{"cats.yaml": "- name: Felix\n- name: Felix\n- name: Felix\n  color: Black\n"})
+
+	for _, expected := range []fixturesResult{{AddedCats: 3}, {Skipped: 3}} {
+		fixtures, _ := readFixtures(dir)
+		if result, err := testDB().loadFixtures(fixtures, time.Now()); err != nil || result != expected {
+			t.Errorf("Expected %+v, got %+v: %v", expected, result, err)
+		}
+	}
+	if len(testDB().Cats) != 3 {
+		t.Errorf("Expected 3 cats, got %v", testDB().Cats)
+	}
+}
+
+// Test the demo data and the empty start of the server
+func TestLoadStartupFixtures(t *testing.T) {
+	setupOwnerTest(t)
+	if err := loadStartupFixtures("", true); err != nil || len(testDB().Cats) != 0 {
+		t.Errorf("Expected an empty start, got %v: %v", testDB().Cats, err)
+	}
+	if err := loadStartupFixtures("", false); err != nil || testDB().Cats["bcd4fcf2-325b-4dd5-bc88-4a3ef94f7ec9"].Name != "Toto" {
+		t.Errorf("Expected the demo cat, got %v: %v", testDB().Cats, err)
+	}
+	if err := loadStartupFixtures(filepath.Join(t.TempDir(), "missing.yaml"), false); err == nil {
+		t.Error("Expected the missing fixtures to be reported")
+	}
+}
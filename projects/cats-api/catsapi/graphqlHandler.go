@@ -0,0 +1,192 @@
+package catsapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// Maximum nesting of the fields in a query
+var graphqlMaxDepth = int(getEnvInt64("GRAPHQL_MAX_DEPTH", 8))
+
+// Maximum estimated cost of a query: one per field, multiplied by the page sizes of the lists
+var graphqlMaxComplexity = int(getEnvInt64("GRAPHQL_MAX_COMPLEXITY", 5000))
+
+// Maximum size of a GraphQL request, the queries being bigger than the other bodies
+var graphqlMaxBodySize = getEnvInt64("GRAPHQL_MAX_BODY_SIZE", 256<<10)
+
+type graphqlQuery struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+	// Sent by some clients, like for the persisted queries, and ignored
+	Extensions map[string]any `json:"extensions"`
+}
+
+func graphqlErrors(messages ...string) *graphql.Result {
+	result := &graphql.Result{}
+	for _, message := range messages {
+		result.Errors = append(result.Errors, gqlerrors.NewFormattedError(message))
+	}
+	return result
+}
+
+func (query graphqlQuery) Validate() error {
+	if query.Query == "" {
+		return badRequest("Missing the GraphQL query")
+	}
+	return nil
+}
+
+// The errors of the operations are part of the GraphQL results, answered with 200
+func serveGraphql(ctx context.Context, req *http.Request, query graphqlQuery) (*graphql.Result, error) {
+	Logger.Info("Executing the GraphQL operation: ", query.OperationName)
+
+	document, err := parser.Parse(parser.ParseParams{Source: query.Query})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}, nil
+	}
+	if validation := graphql.ValidateDocument(&graphqlSchema, document, nil); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}, nil
+	}
+	if message := checkGraphqlLimits(document, query.Variables); message != "" {
+		Logger.Info("GraphQL query rejected: ", message)
+		return graphqlErrors(message), nil
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        graphqlSchema,
+		AST:           document,
+		OperationName: query.OperationName,
+		Args:          query.Variables,
+		Context:       ctx,
+	}), nil
+}
+
+// Tells whether a request runs a GraphQL query, which only reads the databases, rather than a mutation.
+// The body is put back for the handler; the ones which cannot be read as JSON are not queries.
+func isGraphqlQueryRequest(req *http.Request) bool {
+	if _, route, _ := cutTenantPrefix(req.URL.Path); route != "/graphql" || req.Header.Get("Content-Encoding") != "" {
+		return false
+	}
+	data, err := io.ReadAll(io.LimitReader(req.Body, graphqlMaxBodySize+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), req.Body), req.Body}
+
+	var query graphqlQuery
+	if err != nil || json.Unmarshal(data, &query) != nil {
+		return false
+	}
+	document, err := parser.Parse(parser.ParseParams{Source: query.Query})
+	if err != nil {
+		return false
+	}
+	var selected []*ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if ok && (query.OperationName == "" || (operation.Name != nil && operation.Name.Value == query.OperationName)) {
+			selected = append(selected, operation)
+		}
+	}
+	return len(selected) == 1 && selected[0].Operation == ast.OperationTypeQuery
+}
+
+// Rejects the too deep or too expensive operations, before running any resolver
+func checkGraphqlLimits(document *ast.Document, variables map[string]any) string {
+	limits := graphqlLimits{fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			limits.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		depth, complexity := limits.measure(operation.SelectionSet, 0)
+		if depth > graphqlMaxDepth {
+			return fmt.Sprintf("Query too deep: %d levels, the maximum is %d", depth, graphqlMaxDepth)
+		}
+		if complexity > graphqlMaxComplexity {
+			return fmt.Sprintf("Query too complex: %d, the maximum is %d", complexity, graphqlMaxComplexity)
+		}
+	}
+	return ""
+}
+
+type graphqlLimits struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+// Returns the depth and the complexity of a selection, the fragments being inlined.
+// The validation already rejected the fragment cycles.
+func (limits graphqlLimits) measure(selections *ast.SelectionSet, depth int) (int, int) {
+	if selections == nil {
+		return depth, 0
+	}
+	maxDepth, complexity := depth, 0
+
+	for _, selection := range selections.Selections {
+		var childDepth, childComplexity int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			// The introspection is bounded by the schema, the tools need it whole
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			childDepth, childComplexity = limits.measure(selection.SelectionSet, depth+1)
+			childComplexity = 1 + childComplexity*limits.listSize(selection)
+		case *ast.InlineFragment:
+			childDepth, childComplexity = limits.measure(selection.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			if fragment, found := limits.fragments[selection.Name.Value]; found {
+				childDepth, childComplexity = limits.measure(fragment.SelectionSet, depth)
+			}
+		}
+		maxDepth = max(maxDepth, childDepth)
+		complexity += childComplexity
+	}
+	return maxDepth, complexity
+}
+
+// Estimates how many items a field returns, from its "first" argument or the default page size
+func (limits graphqlLimits) listSize(field *ast.Field) int {
+	switch field.Name.Value {
+	case "cats", "records":
+	default:
+		return 1
+	}
+
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if size, err := strconv.Atoi(value.Value); err == nil {
+				return max(size, 1)
+			}
+		case *ast.Variable:
+			if size, ok := limits.variables[value.Name.Value].(float64); ok {
+				return max(int(size), 1)
+			}
+		}
+		return maxCatsPageSize
+	}
+	return defaultCatsPageSize
+}
@@ -0,0 +1,125 @@
+package catsapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type graphqlTestResult struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func doGraphql(t *testing.T, app http.Handler, query string, variables map[string]any) graphqlTestResult {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+
+	var result graphqlTestResult
+	if code := doJSON(t, app, "POST", "/graphql", string(body), &result); code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+	return result
+}
+
+// Test the creation, listing and deletion of cats, with their owner in the same round trip
+func TestGraphqlCats(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	var ownerID string
+	doJSON(t, app, "POST", "/api/owners", `{"name": "Alice"}`, &ownerID)
+
+	created := doGraphql(t, app, `mutation($input: CatInput!) { createCat(input: $input) { id name } }`,
+		map[string]any{"input": map[string]any{"name": "Felix", "color": "Black", "ownerId": ownerID}})
+	if len(created.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %+v", created.Errors)
+	}
+	var cat struct{ ID, Name string }
+	json.Unmarshal(created.Data["createCat"], &cat)
+	if cat.ID == "" || cat.Name != "Felix" {
+		t.Errorf("Unexpected created cat: %+v", cat)
+	}
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Garfield", "color": "Orange"}`, nil)
+
+	listed := doGraphql(t, app, `{ cats(filter: {color: "black"}) { totalCount edges { node { name owner { name } } } } }`, nil)
+	expected := `{"edges":[{"node":{"name":"Felix","owner":{"name":"Alice"}}}],"totalCount":1}`
+	if string(listed.Data["cats"]) != expected {
+		t.Errorf("Expected %s, got %s", expected, listed.Data["cats"])
+	}
+
+	deleted := doGraphql(t, app, `mutation { deleteCat(id: "`+cat.ID+`") }`, nil)
+	if string(deleted.Data["deleteCat"]) != "true" {
+		t.Errorf("Expected the cat to be deleted, got %s %+v", deleted.Data["deleteCat"], deleted.Errors)
+	}
+	if found := doGraphql(t, app, `{ cat(id: "`+cat.ID+`") { id } }`, nil); string(found.Data["cat"]) != "null" {
+		t.Errorf("Expected the deleted cat to be gone, got %s", found.Data["cat"])
+	}
+}
+
+// Test the cursor pagination of the cats
+func TestGraphqlCatsPagination(t *testing.T) {
+	app := setupOwnerTest(t)
+	for _, name := range []string{"A", "B", "C"} {
+		doJSON(t, app, "POST", "/api/cats", `{"name": "`+name+`"}`, nil)
+	}
+
+	query := `query($after: String) { cats(first: 2, after: $after) { edges { node { id } } pageInfo { hasNextPage endCursor } } }`
+	seen := map[string]bool{}
+	var after any
+	for page := 1; ; page++ {
+		result := doGraphql(t, app, query, map[string]any{"after": after})
+		var connection struct {
+			Edges []struct {
+				Node struct{ ID string }
+			}
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   string
+			}
+		}
+		json.Unmarshal(result.Data["cats"], &connection)
+		for _, edge := range connection.Edges {
+			seen[edge.Node.ID] = true
+		}
+		if !connection.PageInfo.HasNextPage {
+			if page != 2 {
+				t.Errorf("Expected 2 pages, got %d", page)
+			}
+			break
+		}
+		after = connection.PageInfo.EndCursor
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected 3 distinct cats, got %d", len(seen))
+	}
+}
+
+// Test the validation shared with the REST API, and the query limits
+func TestGraphqlErrors(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{"unknown owner", `mutation { createCat(input: {name: "Felix", ownerId: "unknown"}) { id } }`, "Owner not found"},
+		{"unknown cat", `mutation { deleteCat(id: "unknown") }`, "Cat not found"},
+		{"unknown field", `{ cats { edges { node { weight } } } }`, "Cannot query field"},
+		{"too large page", `{ cats(first: 1000) { totalCount } }`, "Invalid first"},
+		{"too deep", `{ cat(id: "id1") { owner { cats { owner { cats { owner { cats { owner { name } } } } } } } } }`, "Query too deep"},
+		{"too complex", `{ cats(first: 100) { edges { node { owner { cats { records { id } } } } } } }`, "Query too complex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := doGraphql(t, app, tt.query, nil)
+			if len(result.Errors) == 0 || !strings.Contains(result.Errors[0].Message, tt.message) {
+				t.Errorf("Expected an error containing '%s', got %+v", tt.message, result.Errors)
+			}
+		})
+	}
+}
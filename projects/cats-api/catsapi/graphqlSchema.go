@@ -0,0 +1,294 @@
+package catsapi
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+var recordType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Record",
+	Description: "Medical entry of a cat",
+	Fields: graphql.Fields{
+		"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"type":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"date":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"vetName":     &graphql.Field{Type: graphql.String},
+		"notes":       &graphql.Field{Type: graphql.String},
+		"vaccine":     &graphql.Field{Type: graphql.String},
+		"validMonths": &graphql.Field{Type: graphql.Int},
+		"dueDate":     &graphql.Field{Type: graphql.String},
+	},
+})
+
+var catFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CatFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":    &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Part of the name, case insensitive"},
+		"color":   &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Color, case insensitive"},
+		"ownerId": &graphql.InputObjectFieldConfig{Type: graphql.ID},
+	},
+})
+
+var catInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CatInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"birthDate": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"color":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"ownerId":   &graphql.InputObjectFieldConfig{Type: graphql.ID},
+	},
+})
+
+// The Cat and Owner types refer to each other, their fields are resolved when building the schema
+func newGraphqlSchema() graphql.Schema {
+	var catType, ownerType *graphql.Object
+
+	ownerType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Owner",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"email": &graphql.Field{Type: graphql.String},
+				"phone": &graphql.Field{Type: graphql.String},
+				"cats": &graphql.Field{
+					Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(catType))),
+					Resolve: resolveOwnerCats,
+				},
+			}
+		}),
+	})
+
+	catType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Cat",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"name":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"birthDate": &graphql.Field{Type: graphql.String},
+				"color":     &graphql.Field{Type: graphql.String},
+				"owner":     &graphql.Field{Type: ownerType, Resolve: resolveCatOwner},
+				"records": &graphql.Field{
+					Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(recordType))),
+					Resolve: resolveCatRecords,
+				},
+				"nextVaccinationDue": &graphql.Field{Type: graphql.String, Resolve: resolveNextVaccinationDue},
+			}
+		}),
+	})
+
+	catEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CatEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(catType)},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"endCursor":   &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	catConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CatConnection",
+		Description: "Page of cats, ordered by ID",
+		Fields: graphql.Fields{
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"edges":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(catEdgeType)))},
+			"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"cat": &graphql.Field{
+					Type:    catType,
+					Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+					Resolve: resolveCat,
+				},
+				"cats": &graphql.Field{
+					Type: graphql.NewNonNull(catConnectionType),
+					Args: graphql.FieldConfigArgument{
+						"filter": &graphql.ArgumentConfig{Type: catFilterType},
+						"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultCatsPageSize},
+						"after":  &graphql.ArgumentConfig{Type: graphql.String},
+					},
+					Resolve: resolveCats,
+				},
+				"owner": &graphql.Field{
+					Type:    ownerType,
+					Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+					Resolve: resolveOwner,
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"createCat": &graphql.Field{
+					Type:    graphql.NewNonNull(catType),
+					Args:    graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(catInputType)}},
+					Resolve: resolveCreateCat,
+				},
+				"deleteCat": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.Boolean),
+					Description: "Moves the cat to the trash",
+					Args:        graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+					Resolve:     resolveDeleteCat,
+				},
+			},
+		}),
+	})
+	// The schema is static, an error is a programming mistake
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+var graphqlSchema = newGraphqlSchema()
+
+func resolveCat(params graphql.ResolveParams) (any, error) {
+	db := requestDB(params.Context)
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	catID := params.Args["id"].(string)
+	if cat, found := db.findCat(catID); found {
+		cat.ID = catID
+		return cat, nil
+	}
+	return nil, nil
+}
+
+func resolveCats(params graphql.ResolveParams) (any, error) {
+	first := params.Args["first"].(int)
+	if first < 0 || first > maxCatsPageSize {
+		return nil, errors.New("Invalid first, expecting 0 to 100")
+	}
+	var afterID string
+	if after, given := params.Args["after"].(string); given {
+		var valid bool
+		if afterID, valid = decodeCatCursor(after); !valid {
+			return nil, errors.New("Invalid after cursor")
+		}
+	}
+	filter, _ := params.Args["filter"].(map[string]any)
+	name, _ := filter["name"].(string)
+	color, _ := filter["color"].(string)
+	ownerID, _ := filter["ownerId"].(string)
+
+	cats, total, hasNext := requestDB(params.Context).pageCats(func(cat Cat) bool {
+		return (name == "" || strings.Contains(strings.ToLower(cat.Name), strings.ToLower(name))) &&
+			(color == "" || strings.EqualFold(cat.Color, color)) &&
+			(ownerID == "" || cat.OwnerID == ownerID)
+	}, afterID, first)
+
+	edges := []map[string]any{}
+	for _, cat := range cats {
+		edges = append(edges, map[string]any{"cursor": encodeCatCursor(cat.ID), "node": cat})
+	}
+	pageInfo := map[string]any{"hasNextPage": hasNext}
+	if len(edges) > 0 {
+		pageInfo["endCursor"] = edges[len(edges)-1]["cursor"]
+	}
+	return map[string]any{"totalCount": total, "edges": edges, "pageInfo": pageInfo}, nil
+}
+
+func resolveOwner(params graphql.ResolveParams) (any, error) {
+	db := requestDB(params.Context)
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	if owner, found := db.Owners[params.Args["id"].(string)]; found {
+		return owner, nil
+	}
+	return nil, nil
+}
+
+func resolveCatOwner(params graphql.ResolveParams) (any, error) {
+	db := requestDB(params.Context)
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	if owner, found := db.Owners[params.Source.(Cat).OwnerID]; found {
+		return owner, nil
+	}
+	return nil, nil
+}
+
+func resolveOwnerCats(params graphql.ResolveParams) (any, error) {
+	db := requestDB(params.Context)
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	cats := []Cat{}
+	for _, catID := range db.listOwnerCatIDs(params.Source.(Owner).ID) {
+		cat := db.Cats[catID]
+		cat.ID = catID
+		cats = append(cats, cat)
+	}
+	slices.SortFunc(cats, func(a, b Cat) int { return strings.Compare(a.ID, b.ID) })
+	return cats, nil
+}
+
+func resolveCatRecords(params graphql.ResolveParams) (any, error) {
+	db := requestDB(params.Context)
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+	return db.findCatRecords(params.Source.(Cat).ID).Records, nil
+}
+
+func resolveNextVaccinationDue(params graphql.ResolveParams) (any, error) {
+	db := requestDB(params.Context)
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	if due := db.findCatRecords(params.Source.(Cat).ID).NextVaccinationDue; due != "" {
+		return due, nil
+	}
+	return nil, nil
+}
+
+func resolveCreateCat(params graphql.ResolveParams) (any, error) {
+	input := params.Args["input"].(map[string]any)
+	cat := Cat{Name: input["name"].(string)}
+	cat.BirthDate, _ = input["birthDate"].(string)
+	cat.Color, _ = input["color"].(string)
+	cat.OwnerID, _ = input["ownerId"].(string)
+	Logger.Info("Creating the cat through GraphQL: ", cat)
+
+	cat, err := requestDB(params.Context).insertCat(params.Context, cat)
+	if err != nil {
+		return nil, err
+	}
+	return cat, nil
+}
+
+func resolveDeleteCat(params graphql.ResolveParams) (any, error) {
+	catID := params.Args["id"].(string)
+	Logger.Infof("Deleting the cat through GraphQL: %s", catID)
+	db := requestDB(params.Context)
+
+	dbLock.Lock()
+	defer dbLock.Unlock()
+
+	trashed, err := db.trashCat(params.Context, catID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !trashed {
+		return nil, errors.New("Cat not found")
+	}
+	return true, nil
+}
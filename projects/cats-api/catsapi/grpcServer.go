@@ -0,0 +1,428 @@
+package catsapi
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	catsv1 "backend/proto/cats/v1"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Implements cats.v1.CatService on the same databases as the REST handlers, the tenant of the calls
+// being told by their x-tenant-id metadata or bearer token, whose subject is the actor of their audit entries.
+type catService struct {
+	catsv1.UnimplementedCatServiceServer
+}
+
+func catToProto(cat Cat) *catsv1.Cat {
+	return &catsv1.Cat{
+		Id:        cat.ID,
+		Name:      cat.Name,
+		BirthDate: cat.BirthDate,
+		Color:     cat.Color,
+		OwnerId:   cat.OwnerID,
+	}
+}
+
+var catEventTypesToProto = map[string]catsv1.CatEvent_Type{
+	"created": catsv1.CatEvent_TYPE_CREATED,
+	"updated": catsv1.CatEvent_TYPE_UPDATED,
+	"deleted": catsv1.CatEvent_TYPE_DELETED,
+}
+
+func catEventToProto(event CatEvent) *catsv1.CatEvent {
+	result := &catsv1.CatEvent{
+		Id:    event.ID,
+		Type:  catEventTypesToProto[event.Type],
+		Time:  timestamppb.New(event.Time),
+		CatId: event.CatID,
+	}
+	if event.Cat != nil {
+		result.Cat = catToProto(*event.Cat)
+		result.Cat.Id = event.CatID
+	}
+	return result
+}
+
+func (service *catService) CreateCat(ctx context.Context, req *catsv1.CreateCatRequest) (*catsv1.CreateCatResponse, error) {
+	if req.GetCat() == nil {
+		return nil, status.Error(codes.InvalidArgument, "Missing the cat")
+	}
+	cat := Cat{
+		Name:      req.Cat.Name,
+		BirthDate: req.Cat.BirthDate,
+		Color:     req.Cat.Color,
+		OwnerID:   req.Cat.OwnerId,
+	}
+	Logger.Info("Creating the cat through gRPC: ", cat)
+
+	cat, err := requestDB(ctx).insertCat(ctx, cat)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &catsv1.CreateCatResponse{Cat: catToProto(cat)}, nil
+}
+
+func (service *catService) GetCat(ctx context.Context, req *catsv1.GetCatRequest) (*catsv1.GetCatResponse, error) {
+	dbLock.RLock()
+	cat, found := requestDB(ctx).findCat(req.Id)
+	dbLock.RUnlock()
+
+	if !found {
+		return nil, status.Error(codes.NotFound, "Cat not found")
+	}
+	cat.ID = req.Id
+	return &catsv1.GetCatResponse{Cat: catToProto(cat)}, nil
+}
+
+func (service *catService) ListCats(ctx context.Context, req *catsv1.ListCatsRequest) (*catsv1.ListCatsResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize < 0 || pageSize > maxCatsPageSize {
+		return nil, status.Error(codes.InvalidArgument, "Invalid page size, expecting 0 to 100")
+	}
+	if pageSize == 0 {
+		pageSize = defaultCatsPageSize
+	}
+	afterID, valid := decodeCatCursor(req.PageToken)
+	if !valid {
+		return nil, status.Error(codes.InvalidArgument, "Invalid page token")
+	}
+
+	cats, total, hasNext := requestDB(ctx).pageCats(func(Cat) bool { return true }, afterID, pageSize)
+
+	res := &catsv1.ListCatsResponse{Cats: []*catsv1.Cat{}, TotalSize: int32(total)}
+	for _, cat := range cats {
+		res.Cats = append(res.Cats, catToProto(cat))
+	}
+	if hasNext {
+		res.NextPageToken = encodeCatCursor(cats[len(cats)-1].ID)
+	}
+	return res, nil
+}
+
+func (service *catService) DeleteCat(ctx context.Context, req *catsv1.DeleteCatRequest) (*catsv1.DeleteCatResponse, error) {
+	Logger.Infof("Deleting the cat through gRPC: %s", req.Id)
+	db := requestDB(ctx)
+
+	dbLock.Lock()
+	defer dbLock.Unlock()
+
+	trashed, err := db.trashCat(ctx, req.Id, time.Now())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if !trashed {
+		return nil, status.Error(codes.NotFound, "Cat not found")
+	}
+	return &catsv1.DeleteCatResponse{}, nil
+}
+
+func (service *catService) WatchCats(req *catsv1.WatchCatsRequest, stream grpc.ServerStreamingServer[catsv1.WatchCatsResponse]) error {
+	events := requestDB(stream.Context()).events
+	sub, missed := events.Subscribe(req.AfterEventId)
+	defer events.Unsubscribe(sub)
+	Logger.Infof("New gRPC cat events subscriber, resuming after %d with %d missed events", req.AfterEventId, len(missed))
+
+	for _, event := range missed {
+		if event.Type == catEventReset {
+			return status.Errorf(codes.OutOfRange, "The events after %d are lost, list the cats again then watch the new events", req.AfterEventId)
+		}
+		if err := stream.Send(&catsv1.WatchCatsResponse{Event: catEventToProto(event)}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, open := <-sub.events:
+			if !open {
+				// The client resumes from its last event when reconnecting
+				return status.Error(codes.ResourceExhausted, "Too slow to consume the events")
+			}
+			if err := stream.Send(&catsv1.WatchCatsResponse{Event: catEventToProto(event)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Status of an API error, for the gRPC callers
+func grpcError(err error) error {
+	code, message := errorResponse(err)
+	grpcCode, found := map[int]codes.Code{
+		http.StatusBadRequest:         codes.InvalidArgument,
+		http.StatusUnauthorized:       codes.Unauthenticated,
+		http.StatusForbidden:          codes.PermissionDenied,
+		http.StatusNotFound:           codes.NotFound,
+		http.StatusServiceUnavailable: codes.Unavailable,
+	}[code]
+	if !found {
+		grpcCode = codes.Internal
+	}
+	text, _ := message.(string)
+	return status.Error(grpcCode, text)
+}
+
+// Binds the call to the databases of its tenant, like withTenant for the HTTP requests
+func grpcTenantContext(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	firstValue := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	tenant, subject, err := tenants.resolve("", firstValue(tenantHeader), firstValue("authorization"), time.Now())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	// Like withRequestID, the audit entries tell the ID set by the caller if any
+	requestID := firstValue("x-request-id")
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	ctx = context.WithValue(ctx, requestIDKey, requestID)
+	return context.WithValue(ctx, tenantContextKey{}, requestTenant{db: openTenantDB(tenant), subject: subject}), nil
+}
+
+// Methods changing the databases, run by the leader when replicating
+var grpcWriteMethods = []string{catsv1.CatService_CreateCat_FullMethodName, catsv1.CatService_DeleteCat_FullMethodName}
+
+// The health and reflection services do not depend on the tenant
+func isCatServiceMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+catsv1.CatService_ServiceDesc.ServiceName+"/")
+}
+
+func grpcUnaryTenant(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !isCatServiceMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	ctx, err := grpcTenantContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if replication == nil {
+		return handler(ctx, req)
+	}
+
+	// Like the HTTP requests, the writes are run by the leader and the reads wait for the committed writes
+	var res any
+	call := func() { res, err = handler(ctx, req) }
+	var replicationErr error
+	if slices.Contains(grpcWriteMethods, info.FullMethod) {
+		if _, isLeader := replication.currentLeader(); !isLeader {
+			return forwardGrpcCall(ctx, info.FullMethod, req)
+		}
+		replicationErr = replication.write(ctx, func() []string {
+			if call(); err != nil {
+				return nil
+			}
+			return []string{requestDB(ctx).tenant}
+		})
+	} else {
+		replicationErr = replication.read(ctx, call)
+	}
+	if replicationErr != nil {
+		return nil, grpcError(replicationErr)
+	}
+	return res, err
+}
+
+func grpcStreamTenant(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !isCatServiceMethod(info.FullMethod) {
+		return handler(srv, stream)
+	}
+	ctx, err := grpcTenantContext(stream.Context())
+	if err != nil {
+		return err
+	}
+	// The events are published by the replica which runs the writes
+	if replication != nil {
+		if _, isLeader := replication.currentLeader(); !isLeader {
+			return forwardGrpcStream(stream, info)
+		}
+	}
+	return handler(srv, tenantStream{stream, ctx})
+}
+
+// Connection of a follower to the gRPC API of the leader, replaced when another replica leads
+var leaderGrpc struct {
+	lock    sync.Mutex
+	address string
+	conn    *grpc.ClientConn
+}
+
+// Connects to the gRPC API of the leader, on the gRPC port of this replica, with its metadata being forwarded
+func leaderGrpcConn(ctx context.Context) (context.Context, *grpc.ClientConn, error) {
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	if len(incoming.Get(replicationForwardedHeader)) > 0 {
+		// The leader changed while the call was forwarded
+		return nil, nil, grpcError(replication.notLeaderError())
+	}
+	leader, _ := replication.currentLeader()
+	host, _, err := net.SplitHostPort(leader)
+	if err != nil {
+		return nil, nil, grpcError(replication.notLeaderError())
+	}
+	address := net.JoinHostPort(host, replication.grpcPort)
+
+	leaderGrpc.lock.Lock()
+	defer leaderGrpc.lock.Unlock()
+	if leaderGrpc.address != address {
+		if leaderGrpc.conn != nil {
+			leaderGrpc.conn.Close()
+		}
+		creds := insecure.NewCredentials()
+		if transport, isHTTP := peerClient.Transport.(*http.Transport); isHTTP && peerScheme == "https" {
+			creds = credentials.NewTLS(transport.TLSClientConfig)
+		}
+		conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, nil, status.Errorf(codes.Unavailable, "The leader replica is unreachable, retry later")
+		}
+		leaderGrpc.address, leaderGrpc.conn = address, conn
+	}
+
+	Logger.Debugf("Forwarding a gRPC call to the leader %s", address)
+	outgoing := incoming.Copy()
+	outgoing.Set(replicationForwardedHeader, replication.self)
+	return metadata.NewOutgoingContext(ctx, outgoing), leaderGrpc.conn, nil
+}
+
+// Creates a message of the input or output type of a method, named like /cats.v1.CatService/CreateCat
+func newGrpcMessage(fullMethod string, output bool) (proto.Message, error) {
+	name := protoreflect.FullName(strings.ReplaceAll(strings.TrimPrefix(fullMethod, "/"), "/", "."))
+	descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	method, isMethod := descriptor.(protoreflect.MethodDescriptor)
+	if err != nil || !isMethod {
+		return nil, status.Errorf(codes.Unimplemented, "Unknown method %s", fullMethod)
+	}
+	messageDescriptor := method.Input()
+	if output {
+		messageDescriptor = method.Output()
+	}
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(messageDescriptor.FullName())
+	if err != nil {
+		return nil, status.Errorf(codes.Unimplemented, "Unknown message %s", messageDescriptor.FullName())
+	}
+	return messageType.New().Interface(), nil
+}
+
+// Runs a unary call of a follower on the leader
+func forwardGrpcCall(ctx context.Context, fullMethod string, req any) (any, error) {
+	res, err := newGrpcMessage(fullMethod, true)
+	if err != nil {
+		return nil, err
+	}
+	ctx, conn, err := leaderGrpcConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Invoke(ctx, fullMethod, req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Relays a server stream of a follower from the leader, like the events of WatchCats
+func forwardGrpcStream(stream grpc.ServerStream, info *grpc.StreamServerInfo) error {
+	if info.IsClientStream {
+		return grpcError(replication.notLeaderError())
+	}
+	req, err := newGrpcMessage(info.FullMethod, false)
+	if err != nil {
+		return err
+	}
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	ctx, conn, err := leaderGrpcConn(stream.Context())
+	if err != nil {
+		return err
+	}
+	leaderStream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, info.FullMethod)
+	if err != nil {
+		return err
+	}
+	if err := leaderStream.SendMsg(req); err != nil {
+		return err
+	}
+	if err := leaderStream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		res, _ := newGrpcMessage(info.FullMethod, true)
+		if err := leaderStream.RecvMsg(res); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(res); err != nil {
+			return err
+		}
+	}
+}
+
+// Server stream carrying the tenant in its context
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream tenantStream) Context() context.Context {
+	return stream.ctx
+}
+
+// Creates the gRPC server, with the standard health and reflection services
+func newGrpcServer(options ...grpc.ServerOption) *grpc.Server {
+	options = append(options, grpc.UnaryInterceptor(grpcUnaryTenant), grpc.StreamInterceptor(grpcStreamTenant))
+	server := grpc.NewServer(options...)
+	catsv1.RegisterCatServiceServer(server, &catService{})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(catsv1.CatService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+	return server
+}
+
+// Serves the gRPC API on its own port, until the listener fails, with TLS when there is a configuration
+func serveGrpc(port string, tlsConfig *tls.Config) {
+	options := []grpc.ServerOption{}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		Logger.Error("Unable to listen for gRPC: ", err)
+		return
+	}
+	Logger.Infof("gRPC server listening on %v", listener.Addr())
+	if err := newGrpcServer(options...).Serve(listener); err != nil {
+		Logger.Error("gRPC server stopped: ", err)
+	}
+}
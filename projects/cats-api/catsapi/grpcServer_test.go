@@ -0,0 +1,246 @@
+package catsapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	catsv1 "backend/proto/cats/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Serves the gRPC API in memory
+func setupGrpcTest(t *testing.T) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	server := newGrpcServer()
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect to the gRPC server: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+	return conn
+}
+
+// Test the cats lifecycle through gRPC
+func TestGrpcCatService(t *testing.T) {
+	setupOwnerTest(t)
+	client := catsv1.NewCatServiceClient(setupGrpcTest(t))
+	ctx := context.Background()
+
+	created, err := client.CreateCat(ctx, &catsv1.CreateCatRequest{Cat: &catsv1.Cat{Name: "Felix", Color: "Black"}})
+	if err != nil {
+		t.Fatalf("Failed to create the cat: %v", err)
+	}
+	catID := created.Cat.Id
+
+	got, err := client.GetCat(ctx, &catsv1.GetCatRequest{Id: catID})
+	if err != nil || got.Cat.Name != "Felix" || got.Cat.Color != "Black" {
+		t.Errorf("Unexpected cat: %v, %v", got, err)
+	}
+
+	// Shares the validation of the REST API
+	_, err = client.CreateCat(ctx, &catsv1.CreateCatRequest{Cat: &catsv1.Cat{Name: "Tom", OwnerId: "unknown"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected %v, got %v", codes.InvalidArgument, err)
+	}
+
+	if _, err := client.DeleteCat(ctx, &catsv1.DeleteCatRequest{Id: catID}); err != nil {
+		t.Errorf("Failed to delete the cat: %v", err)
+	}
+	if _, err := client.GetCat(ctx, &catsv1.GetCatRequest{Id: catID}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected %v, got %v", codes.NotFound, err)
+	}
+	if _, err := client.DeleteCat(ctx, &catsv1.DeleteCatRequest{Id: catID}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected %v, got %v", codes.NotFound, err)
+	}
+}
+
+// Test that the gRPC changes are audited as made by the subject of the token, with the request ID of the call
+func TestGrpcAudit(t *testing.T) {
+	secret := "test-secret"
+	setupTenantsTest(t, tenantSettings{maxCats: map[string]int{DefaultTenant: 0}, tokenSecret: []byte(secret)})
+	originalAudit := testDB().audit
+	testDB().audit = newMemoryAuditStore(0)
+	t.Cleanup(func() { testDB().audit = originalAudit })
+	client := catsv1.NewCatServiceClient(setupGrpcTest(t))
+
+	token := signTestToken(secret, fmt.Sprintf(`{"tenant": "default", "sub": "alice", "exp": %d}`, time.Now().Add(time.Hour).Unix()))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token, "x-request-id", "req-7")
+	created, err := client.CreateCat(ctx, &catsv1.CreateCatRequest{Cat: &catsv1.Cat{Name: "Felix"}})
+	if err != nil {
+		t.Fatalf("Failed to create the cat: %v", err)
+	}
+	if _, err := client.DeleteCat(ctx, &catsv1.DeleteCatRequest{Id: created.Cat.Id}); err != nil {
+		t.Fatalf("Failed to delete the cat: %v", err)
+	}
+
+	entries := testDB().audit.List(AuditFilter{CatID: created.Cat.Id})
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %+v", entries)
+	}
+	for _, entry := range entries {
+		if entry.Actor != "alice" || entry.RequestID != "req-7" {
+			t.Errorf("Expected the change of alice in req-7, got %+v", entry)
+		}
+	}
+}
+
+// Test the pagination of the cats list
+func TestGrpcListCats(t *testing.T) {
+	setupOwnerTest(t)
+	client := catsv1.NewCatServiceClient(setupGrpcTest(t))
+	ctx := context.Background()
+	for _, name := range []string{"A", "B", "C"} {
+		client.CreateCat(ctx, &catsv1.CreateCatRequest{Cat: &catsv1.Cat{Name: name}})
+	}
+
+	first, err := client.ListCats(ctx, &catsv1.ListCatsRequest{PageSize: 2})
+	if err != nil || len(first.Cats) != 2 || first.NextPageToken == "" || first.TotalSize != 3 {
+		t.Fatalf("Unexpected first page: %v, %v", first, err)
+	}
+	last, err := client.ListCats(ctx, &catsv1.ListCatsRequest{PageSize: 2, PageToken: first.NextPageToken})
+	if err != nil || len(last.Cats) != 1 || last.NextPageToken != "" {
+		t.Errorf("Unexpected last page: %v, %v", last, err)
+	}
+
+	if _, err := client.ListCats(ctx, &catsv1.ListCatsRequest{PageSize: 1000}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected %v, got %v", codes.InvalidArgument, err)
+	}
+}
+
+// Test the streaming of the cat changes, whatever the API used to make them
+func TestGrpcWatchCats(t *testing.T) {
+	app := setupOwnerTest(t)
+	conn := setupGrpcTest(t)
+	originalEvents := testDB().events
+	testDB().events = newEventBroker(10, 10)
+	defer func() { testDB().events = originalEvents }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var catID string
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Toto"}`, &catID)
+
+	stream, err := catsv1.NewCatServiceClient(conn).WatchCats(ctx, &catsv1.WatchCatsRequest{AfterEventId: 0})
+	if err != nil {
+		t.Fatalf("Failed to watch the cats: %v", err)
+	}
+	// Waits for the subscription, the stream has no header to wait for
+	time.Sleep(50 * time.Millisecond)
+	doJSON(t, app, "DELETE", "/api/cats/"+catID, "", nil)
+
+	res, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive the event: %v", err)
+	}
+	createdID := testDB().events.replay[0].ID
+	if res.Event.Type != catsv1.CatEvent_TYPE_DELETED || res.Event.CatId != catID || res.Event.Id <= createdID {
+		t.Errorf("Unexpected event: %v", res.Event)
+	}
+
+	resumed, _ := catsv1.NewCatServiceClient(conn).WatchCats(ctx, &catsv1.WatchCatsRequest{AfterEventId: createdID})
+	if resumed, err := resumed.Recv(); err != nil || resumed.Event.Id != res.Event.Id {
+		t.Errorf("Expected to resume with the deleted event, got %v, %v", resumed, err)
+	}
+
+	// The events following an unknown one are lost
+	lost, _ := catsv1.NewCatServiceClient(conn).WatchCats(ctx, &catsv1.WatchCatsRequest{AfterEventId: createdID - 1})
+	if _, err := lost.Recv(); status.Code(err) != codes.OutOfRange {
+		t.Errorf("Expected %v, got %v", codes.OutOfRange, err)
+	}
+}
+
+// Leader of the forwarding tests, answering the calls itself
+type leaderCatService struct {
+	catsv1.UnimplementedCatServiceServer
+	forwardedBy chan string
+}
+
+func (service leaderCatService) CreateCat(ctx context.Context, req *catsv1.CreateCatRequest) (*catsv1.CreateCatResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	service.forwardedBy <- strings.Join(md.Get(replicationForwardedHeader), ",")
+	return &catsv1.CreateCatResponse{Cat: &catsv1.Cat{Id: "leader-cat", Name: req.Cat.Name}}, nil
+}
+
+func (service leaderCatService) WatchCats(req *catsv1.WatchCatsRequest, stream catsv1.CatService_WatchCatsServer) error {
+	return stream.Send(&catsv1.WatchCatsResponse{Event: &catsv1.CatEvent{Id: req.AfterEventId + 1, CatId: "leader-cat"}})
+}
+
+// Test the forwarding of the writes and streams of a follower to the gRPC API of the leader
+func TestGrpcForwarding(t *testing.T) {
+	setupOwnerTest(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaderServer := grpc.NewServer()
+	service := leaderCatService{forwardedBy: make(chan string, 1)}
+	catsv1.RegisterCatServiceServer(leaderServer, service)
+	go leaderServer.Serve(listener)
+	defer leaderServer.Stop()
+
+	node := newReplicaNode("127.0.0.1:8081", []string{"127.0.0.1:8080"}, 2, time.Minute, memoryTransport{&memoryNetwork{}, "127.0.0.1:8081"}, tenantsStore{})
+	node.leader = "127.0.0.1:8080"
+	_, node.grpcPort, _ = net.SplitHostPort(listener.Addr().String())
+	setupReplicationTest(t, node)
+	client := catsv1.NewCatServiceClient(setupGrpcTest(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	created, err := client.CreateCat(ctx, &catsv1.CreateCatRequest{Cat: &catsv1.Cat{Name: "Felix"}})
+	if err != nil || created.Cat.Id != "leader-cat" || created.Cat.Name != "Felix" {
+		t.Fatalf("Expected the cat created by the leader, got %v, %v", created, err)
+	}
+	if forwardedBy := <-service.forwardedBy; forwardedBy != node.self {
+		t.Errorf("Expected the forwarding metadata, got %q", forwardedBy)
+	}
+	if len(testDB().Cats) != 0 {
+		t.Error("Expected the follower not to run the write")
+	}
+
+	stream, err := client.WatchCats(ctx, &catsv1.WatchCatsRequest{AfterEventId: 41})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err := stream.Recv(); err != nil || res.Event.Id != 42 {
+		t.Errorf("Expected the event of the leader, got %v, %v", res, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected the end of the stream, got %v", err)
+	}
+
+	// A call forwarded to a replica which no longer leads is not forwarded again
+	forwarded := metadata.AppendToOutgoingContext(ctx, replicationForwardedHeader, "127.0.0.1:8082")
+	if _, err := client.CreateCat(forwarded, &catsv1.CreateCatRequest{Cat: &catsv1.Cat{Name: "Felix"}}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected %v, got %v", codes.Unavailable, err)
+	}
+}
+
+// Test the standard health service
+func TestGrpcHealth(t *testing.T) {
+	client := healthpb.NewHealthClient(setupGrpcTest(t))
+
+	res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "cats.v1.CatService"})
+	if err != nil || res.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected the service to be serving, got %v, %v", res, err)
+	}
+}
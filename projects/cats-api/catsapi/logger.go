@@ -0,0 +1,117 @@
+package catsapi
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync/atomic"
+
+	"gitlab.com/ggpack/logchain-go"
+)
+
+// Names of the log levels, their index being the logchain verbosity
+var logLevels = []string{"error", "warning", "info", "debug"}
+
+// Current verbosity, the index of the level in logLevels
+var logVerbosity atomic.Int32
+
+func init() {
+	logVerbosity.Store(3)
+}
+
+// Logger checking the current level before formatting the lines, the logchain one writing all of them:
+// the level changes without replacing it
+type levelLogger struct {
+	chain logchain.Logger
+}
+
+// Creates the logger writing to the stream, the standard output for the server
+func initLogging(stream io.Writer) levelLogger {
+	params := logchain.Params{
+		// The file and line are part of the message, logchain would find the ones of levelLogger
+		"template":  "{{.timestamp}} " + version + " {{.levelLetter}} {{.msg}}",
+		"verbosity": len(logLevels) - 1,
+		"stream":    stream,
+	}
+	chainer := logchain.NewLogChainer(params)
+	return levelLogger{chain: chainer.InitLogging()}
+}
+
+var Logger = initLogging(os.Stdout)
+
+func logEnabled(level string) bool {
+	return slices.Index(logLevels, level) <= int(logVerbosity.Load())
+}
+
+// Prefixes the message with the file and line of the code calling the logger
+func withCallerLine(msg string) string {
+	_, file, line, _ := runtime.Caller(2)
+	return filepath.Base(file) + ":" + strconv.Itoa(line) + " " + msg
+}
+
+func (logger levelLogger) Debug(args ...any) {
+	if logEnabled("debug") {
+		logger.chain.Debug(withCallerLine(fmt.Sprint(args...)))
+	}
+}
+
+func (logger levelLogger) Debugf(format string, args ...any) {
+	if logEnabled("debug") {
+		logger.chain.Debug(withCallerLine(fmt.Sprintf(format, args...)))
+	}
+}
+
+func (logger levelLogger) Info(args ...any) {
+	if logEnabled("info") {
+		logger.chain.Info(withCallerLine(fmt.Sprint(args...)))
+	}
+}
+
+func (logger levelLogger) Infof(format string, args ...any) {
+	if logEnabled("info") {
+		logger.chain.Info(withCallerLine(fmt.Sprintf(format, args...)))
+	}
+}
+
+func (logger levelLogger) Warn(args ...any) {
+	if logEnabled("warning") {
+		logger.chain.Warn(withCallerLine(fmt.Sprint(args...)))
+	}
+}
+
+func (logger levelLogger) Warnf(format string, args ...any) {
+	if logEnabled("warning") {
+		logger.chain.Warn(withCallerLine(fmt.Sprintf(format, args...)))
+	}
+}
+
+func (logger levelLogger) Error(args ...any) {
+	if logEnabled("error") {
+		logger.chain.Error(withCallerLine(fmt.Sprint(args...)))
+	}
+}
+
+func (logger levelLogger) Errorf(format string, args ...any) {
+	if logEnabled("error") {
+		logger.chain.Error(withCallerLine(fmt.Sprintf(format, args...)))
+	}
+}
+
+// Name of the current log level
+func logLevel() string {
+	return logLevels[logVerbosity.Load()]
+}
+
+// Changes the log level of the server, the loggers and their streams being kept
+func setLogLevel(level string) error {
+	verbosity := slices.Index(logLevels, level)
+	if verbosity < 0 {
+		return fmt.Errorf("unknown log level '%s', expected one of %v", level, logLevels)
+	}
+	logVerbosity.Store(int32(verbosity))
+	return nil
+}
@@ -1,10 +1,9 @@
-package main
+package catsapi
 
 import (
 	"bytes"
 	"encoding/json"
 	"net/http"
-	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -18,16 +17,16 @@ import (
 func TestActualCreateCat(t *testing.T) {
 	// Save original database state
 	originalDB := make(map[string]Cat)
-	for k, v := range catsDatabase {
+	for k, v := range testDB().Cats {
 		originalDB[k] = v
 	}
 	defer func() {
 		// Restore original state
-		catsDatabase = originalDB
+		testDB().Cats = originalDB
 	}()
 
 	// Clear database for test
-	catsDatabase = make(map[string]Cat)
+	testDB().Cats = make(map[string]Cat)
 
 	// Create test cat
 	testCat := Cat{
@@ -42,11 +41,11 @@ func TestActualCreateCat(t *testing.T) {
 	}
 
 	// Create request
-	req := httptest.NewRequest("POST", "/api/cats", bytes.NewBuffer(jsonData))
+	req := newTenantRequest("POST", "/api/cats", bytes.NewBuffer(jsonData))
 	req.Header.Set("Content-Type", "application/json")
 
 	// Call actual function
-	statusCode, response := createCat(req)
+	statusCode, response := typedService(http.StatusCreated, createCat)(req)
 
 	// Assertions
 	if statusCode != http.StatusCreated {
@@ -65,12 +64,12 @@ func TestActualCreateCat(t *testing.T) {
 	}
 
 	// Check cat was saved to database
-	if len(catsDatabase) != 1 {
-		t.Errorf("Expected 1 cat in database, got %d", len(catsDatabase))
+	if len(testDB().Cats) != 1 {
+		t.Errorf("Expected 1 cat in database, got %d", len(testDB().Cats))
 	}
 
 	// Verify the cat in database
-	savedCat, exists := catsDatabase[responseStr]
+	savedCat, exists := testDB().Cats[responseStr]
 	if !exists {
 		t.Error("Created cat not found in database")
 		return
@@ -88,19 +87,19 @@ func TestActualCreateCat(t *testing.T) {
 // Test actual createCat function with invalid JSON
 func TestActualCreateCatInvalidJSON(t *testing.T) {
 	// Create request with invalid JSON
-	req := httptest.NewRequest("POST", "/api/cats", strings.NewReader("{ invalid json }"))
+	req := newTenantRequest("POST", "/api/cats", strings.NewReader("{ invalid json }"))
 	req.Header.Set("Content-Type", "application/json")
 
 	// Call actual function
-	statusCode, response := createCat(req)
+	statusCode, response := typedService(http.StatusCreated, createCat)(req)
 
 	// Assertions
 	if statusCode != http.StatusBadRequest {
 		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, statusCode)
 	}
 
-	if response != "Invalid JSON input" {
-		t.Errorf("Expected 'Invalid JSON input', got %v", response)
+	if response != "Invalid JSON input: invalid character 'i' looking for beginning of object key string at offset 3" {
+		t.Errorf("Expected 'Invalid JSON input' at offset 3, got %v", response)
 	}
 }
 
@@ -108,12 +107,12 @@ func TestActualCreateCatInvalidJSON(t *testing.T) {
 func TestActualDeleteCatExists(t *testing.T) {
 	// Save original database state
 	originalDB := make(map[string]Cat)
-	for k, v := range catsDatabase {
+	for k, v := range testDB().Cats {
 		originalDB[k] = v
 	}
 	defer func() {
 		// Restore original state
-		catsDatabase = originalDB
+		testDB().Cats = originalDB
 	}()
 
 	// Set up test cat in database
@@ -122,16 +121,16 @@ func TestActualDeleteCatExists(t *testing.T) {
 		Name: "TestCat",
 		ID:   testCatID,
 	}
-	catsDatabase = map[string]Cat{
+	testDB().Cats = map[string]Cat{
 		testCatID: testCat,
 	}
 
 	// Create request with path parameter
-	req := httptest.NewRequest("DELETE", "/api/cats/"+testCatID, nil)
+	req := newTenantRequest("DELETE", "/api/cats/"+testCatID, nil)
 	req.SetPathValue("catId", testCatID)
 
 	// Call actual function
-	statusCode, response := deleteCat(req)
+	statusCode, response := typedService(http.StatusNoContent, deleteCat)(req)
 
 	// Assertions
 	if statusCode != http.StatusNoContent {
@@ -142,13 +141,13 @@ func TestActualDeleteCatExists(t *testing.T) {
 		t.Errorf("Expected nil response, got %v", response)
 	}
 
-	// Check cat was deleted from database
-	if _, exists := catsDatabase[testCatID]; exists {
-		t.Error("Cat should have been deleted from database")
+	// Check cat was moved to the trash
+	if deletedCat, exists := testDB().Cats[testCatID]; !exists || deletedCat.DeletedAt == nil {
+		t.Error("Cat should have been moved to the trash")
 	}
 
-	if len(catsDatabase) != 0 {
-		t.Errorf("Expected empty database, got %d items", len(catsDatabase))
+	if len(listMapKeys(testDB().Cats)) != 0 {
+		t.Errorf("Expected no cat listed, got %d items", len(listMapKeys(testDB().Cats)))
 	}
 }
 
@@ -156,25 +155,25 @@ func TestActualDeleteCatExists(t *testing.T) {
 func TestActualDeleteCatNotExists(t *testing.T) {
 	// Save original database state
 	originalDB := make(map[string]Cat)
-	for k, v := range catsDatabase {
+	for k, v := range testDB().Cats {
 		originalDB[k] = v
 	}
 	defer func() {
 		// Restore original state
-		catsDatabase = originalDB
+		testDB().Cats = originalDB
 	}()
 
 	// Clear database
-	catsDatabase = make(map[string]Cat)
+	testDB().Cats = make(map[string]Cat)
 
 	nonExistentID := "non-existent-cat-id"
 
 	// Create request
-	req := httptest.NewRequest("DELETE", "/api/cats/"+nonExistentID, nil)
+	req := newTenantRequest("DELETE", "/api/cats/"+nonExistentID, nil)
 	req.SetPathValue("catId", nonExistentID)
 
 	// Call actual function
-	statusCode, response := deleteCat(req)
+	statusCode, response := typedService(http.StatusNoContent, deleteCat)(req)
 
 	// Assertions
 	if statusCode != http.StatusNotFound {
@@ -190,16 +189,16 @@ func TestActualDeleteCatNotExists(t *testing.T) {
 func TestActualCRUDOperations(t *testing.T) {
 	// Save original database state
 	originalDB := make(map[string]Cat)
-	for k, v := range catsDatabase {
+	for k, v := range testDB().Cats {
 		originalDB[k] = v
 	}
 	defer func() {
 		// Restore original state
-		catsDatabase = originalDB
+		testDB().Cats = originalDB
 	}()
 
 	// Clear database
-	catsDatabase = make(map[string]Cat)
+	testDB().Cats = make(map[string]Cat)
 
 	// Create cat
 	testCat := Cat{
@@ -209,10 +208,10 @@ func TestActualCRUDOperations(t *testing.T) {
 	}
 
 	jsonData, _ := json.Marshal(testCat)
-	createReq := httptest.NewRequest("POST", "/api/cats", bytes.NewBuffer(jsonData))
+	createReq := newTenantRequest("POST", "/api/cats", bytes.NewBuffer(jsonData))
 	createReq.Header.Set("Content-Type", "application/json")
 
-	statusCode, response := createCat(createReq)
+	statusCode, response := typedService(http.StatusCreated, createCat)(createReq)
 	if statusCode != http.StatusCreated {
 		t.Fatalf("Failed to create cat: status %d", statusCode)
 	}
@@ -220,28 +219,28 @@ func TestActualCRUDOperations(t *testing.T) {
 	catID := response.(string)
 
 	// Verify cat exists with getCat
-	getReq := httptest.NewRequest("GET", "/api/cats/"+catID, nil)
+	getReq := newTenantRequest("GET", "/api/cats/"+catID, nil)
 	getReq.SetPathValue("catId", catID)
 
-	statusCode, _ = getCat(getReq)
+	statusCode, _ = typedService(http.StatusOK, getCat)(getReq)
 	if statusCode != http.StatusOK {
 		t.Errorf("Failed to get cat: status %d", statusCode)
 	}
 
 	// Delete cat
-	deleteReq := httptest.NewRequest("DELETE", "/api/cats/"+catID, nil)
+	deleteReq := newTenantRequest("DELETE", "/api/cats/"+catID, nil)
 	deleteReq.SetPathValue("catId", catID)
 
-	statusCode, _ = deleteCat(deleteReq)
+	statusCode, _ = typedService(http.StatusNoContent, deleteCat)(deleteReq)
 	if statusCode != http.StatusNoContent {
 		t.Errorf("Failed to delete cat: status %d", statusCode)
 	}
 
 	// Verify cat is gone
-	getReq2 := httptest.NewRequest("GET", "/api/cats/"+catID, nil)
+	getReq2 := newTenantRequest("GET", "/api/cats/"+catID, nil)
 	getReq2.SetPathValue("catId", catID)
 
-	statusCode, _ = getCat(getReq2)
+	statusCode, _ = typedService(http.StatusOK, getCat)(getReq2)
 	if statusCode != http.StatusNotFound {
 		t.Errorf("Expected cat to be deleted, got status %d", statusCode)
 	}
@@ -413,16 +412,16 @@ func TestMainComponents(t *testing.T) {
 	t.Log("Logger is initialized as a global variable")
 
 	// Test app creation
-	app := newApp()
+	app := NewApp()
 	if app == nil {
-		t.Error("newApp() should return a non-nil handler")
+		t.Error("NewApp() should return a non-nil handler")
 	}
 }
 
 // Test server startup simulation (without actually starting)
 func TestMainServerSetup(t *testing.T) {
 	// Simulate the server setup from main()
-	app := newApp()
+	app := NewApp()
 
 	// This mimics the server creation in main()
 	testServer := func(addr string, handler interface{}) bool {
@@ -450,7 +449,7 @@ func TestMainWorkflow(t *testing.T) {
 	t.Log("Logger is available as global variable")
 
 	// Step 2: App creation
-	app := newApp()
+	app := NewApp()
 	if app == nil {
 		t.Error("App creation failed")
 	}
@@ -0,0 +1,530 @@
+package catsapi
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Encodes the responses and decodes the request bodies in one media type.
+// The values are first converted to their JSON form, so that every format shows the same field names.
+type mediaCodec struct {
+	name        string
+	contentType string
+	mediaTypes  []string
+	encode      func(w io.Writer, tree any) error
+	decode      func(r io.Reader) (any, error)
+
+	// Formats without types, like XML and CSV, only carry strings
+	untyped bool
+}
+
+// Supported codecs, by order of preference when the client accepts several ones equally
+var mediaCodecs = []*mediaCodec{
+	{name: "JSON", contentType: "application/json", mediaTypes: []string{"application/json"}, encode: encodeJSONTree, decode: decodeJSONTree},
+	{name: "XML", contentType: "application/xml; charset=utf-8", mediaTypes: []string{"application/xml", "text/xml"}, encode: encodeXMLTree, decode: decodeXMLTree, untyped: true},
+	{name: "YAML", contentType: "application/yaml", mediaTypes: []string{"application/yaml", "application/x-yaml", "text/yaml"}, encode: encodeYAMLTree, decode: decodeYAMLTree},
+	{name: "CSV", contentType: "text/csv; charset=utf-8", mediaTypes: []string{"text/csv"}, encode: encodeCSVTree, decode: decodeCSVTree, untyped: true},
+	{name: "MessagePack", contentType: "application/msgpack", mediaTypes: []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}, encode: encodeMsgpackTree, decode: decodeMsgpackTree},
+}
+
+var jsonCodec = mediaCodecs[0]
+
+// JSON object keeping the order of its fields, like the struct it comes from
+type orderedObject []orderedField
+
+type orderedField struct {
+	Key   string
+	Value any
+}
+
+// Converts a value to its JSON form: orderedObject, []any, json.Number, string, bool or nil
+func toJSONTree(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return readJSONTree(decoder)
+}
+
+func readJSONTree(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := orderedObject{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readJSONTree(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, orderedField{key.(string), value})
+		}
+		_, err = decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := []any{}
+		for decoder.More() {
+			value, err := readJSONTree(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	}
+	return token, nil
+}
+
+// Text of a scalar value, empty for null
+func scalarText(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// Compact JSON text of a tree, for the nested values of the flat formats
+func treeText(tree any) string {
+	switch tree.(type) {
+	case orderedObject, []any:
+		var buffer bytes.Buffer
+		encodeJSONTree(&buffer, tree)
+		return strings.TrimSuffix(buffer.String(), "\n")
+	}
+	return scalarText(tree)
+}
+
+// JSON
+
+func (object orderedObject) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, field := range object {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.Key)
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+func encodeJSONTree(w io.Writer, tree any) error {
+	return json.NewEncoder(w).Encode(tree)
+}
+
+func decodeJSONTree(r io.Reader) (any, error) {
+	var tree any
+	err := json.NewDecoder(r).Decode(&tree)
+	return tree, err
+}
+
+// XML: the objects fields become elements, the lists items become <item> elements
+
+const xmlRootName = "response"
+const xmlItemName = "item"
+
+var xmlNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+func encodeXMLTree(w io.Writer, tree any) error {
+	io.WriteString(w, xml.Header)
+	encoder := xml.NewEncoder(w)
+	if err := writeXMLElement(encoder, xmlRootName, tree); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func writeXMLElement(encoder *xml.Encoder, name string, tree any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	// The keys of the maps are not always valid element names
+	if !xmlNamePattern.MatchString(name) || strings.HasPrefix(strings.ToLower(name), "xml") {
+		start = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}}}
+	}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch tree := tree.(type) {
+	case orderedObject:
+		for _, field := range tree {
+			if err := writeXMLElement(encoder, field.Key, field.Value); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range tree {
+			if err := writeXMLElement(encoder, xmlItemName, item); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := encoder.EncodeToken(xml.CharData(scalarText(tree))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+func decodeXMLTree(r io.Reader) (any, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return readXMLElement(decoder, start)
+		}
+	}
+}
+
+// Reads an element as a string when it has no child element, as a list when its children are items,
+// or else as a map, the repeated children making lists
+func readXMLElement(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	var text strings.Builder
+	names := []string{}
+	children := map[string][]any{}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.CharData:
+			text.Write(token)
+		case xml.StartElement:
+			child, err := readXMLElement(decoder, token)
+			if err != nil {
+				return nil, err
+			}
+			name := token.Name.Local
+			for _, attr := range token.Attr {
+				if name == "entry" && attr.Name.Local == "key" {
+					name = attr.Value
+				}
+			}
+			if _, found := children[name]; !found {
+				names = append(names, name)
+			}
+			children[name] = append(children[name], child)
+		case xml.EndElement:
+			switch {
+			case len(names) == 0:
+				return strings.TrimSpace(text.String()), nil
+			case len(names) == 1 && names[0] == xmlItemName:
+				return children[xmlItemName], nil
+			}
+			object := map[string]any{}
+			for _, name := range names {
+				if values := children[name]; len(values) == 1 {
+					object[name] = values[0]
+				} else {
+					object[name] = values
+				}
+			}
+			return object, nil
+		}
+	}
+}
+
+// YAML
+
+func encodeYAMLTree(w io.Writer, tree any) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(yamlNode(tree)); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Builds the YAML node of a tree, to keep the fields order and the strings looking like numbers quoted
+func yamlNode(tree any) *yaml.Node {
+	switch tree := tree.(type) {
+	case orderedObject:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, field := range tree {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field.Key}, yamlNode(field.Value))
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range tree {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: scalarText(tree)}
+	case json.Number:
+		if _, err := tree.Int64(); err == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: tree.String()}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: tree.String()}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: scalarText(tree)}
+}
+
+func decodeYAMLTree(r io.Reader) (any, error) {
+	var tree any
+	err := yaml.NewDecoder(r).Decode(&tree)
+	return yamlTimesAsText(tree), err
+}
+
+// The unquoted dates and times are decoded as time.Time, which would be encoded back as RFC 3339
+// times: they are turned back into text, the dates without a time keeping the YYYY-MM-DD layout.
+func yamlTimesAsText(tree any) any {
+	switch value := tree.(type) {
+	case time.Time:
+		if value.Equal(value.Truncate(24*time.Hour)) && value.Location() == time.UTC {
+			return value.Format(dateLayout)
+		}
+		return value.Format(time.RFC3339Nano)
+	case map[string]any:
+		for key, item := range value {
+			value[key] = yamlTimesAsText(item)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = yamlTimesAsText(item)
+		}
+	}
+	return tree
+}
+
+// CSV: one row per item of a list, with a header made of all their fields.
+// The nested values are written as JSON, a single object is a one row table and a scalar a one cell table.
+
+func encodeCSVTree(w io.Writer, tree any) error {
+	items, isList := tree.([]any)
+	if !isList {
+		items = []any{tree}
+	}
+
+	columns := []string{}
+	for _, item := range items {
+		object, ok := item.(orderedObject)
+		if !ok {
+			object = orderedObject{{Key: "value", Value: item}}
+		}
+		for _, field := range object {
+			if !slices.Contains(columns, field.Key) {
+				columns = append(columns, field.Key)
+			}
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write(columns)
+	for _, item := range items {
+		object, ok := item.(orderedObject)
+		if !ok {
+			object = orderedObject{{Key: "value", Value: item}}
+		}
+		row := make([]string, len(columns))
+		for _, field := range object {
+			row[slices.Index(columns, field.Key)] = treeText(field.Value)
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Reads the rows as objects keyed by the header, a single row being a single object
+func decodeCSVTree(r io.Reader) (any, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, errors.New("expecting a header and at least one row")
+	}
+
+	items := []any{}
+	for _, row := range rows[1:] {
+		object := map[string]any{}
+		for i, column := range rows[0] {
+			if row[i] != "" {
+				object[column] = row[i]
+			}
+		}
+		items = append(items, object)
+	}
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return items, nil
+}
+
+// MessagePack
+
+func encodeMsgpackTree(w io.Writer, tree any) error {
+	return writeMsgpackTree(msgpack.NewEncoder(w), tree)
+}
+
+func writeMsgpackTree(encoder *msgpack.Encoder, tree any) error {
+	switch tree := tree.(type) {
+	case orderedObject:
+		if err := encoder.EncodeMapLen(len(tree)); err != nil {
+			return err
+		}
+		for _, field := range tree {
+			if err := encoder.EncodeString(field.Key); err != nil {
+				return err
+			}
+			if err := writeMsgpackTree(encoder, field.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		if err := encoder.EncodeArrayLen(len(tree)); err != nil {
+			return err
+		}
+		for _, item := range tree {
+			if err := writeMsgpackTree(encoder, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case json.Number:
+		if number, err := tree.Int64(); err == nil {
+			return encoder.EncodeInt(number)
+		}
+		number, _ := tree.Float64()
+		return encoder.EncodeFloat64(number)
+	}
+	return encoder.Encode(tree)
+}
+
+func decodeMsgpackTree(r io.Reader) (any, error) {
+	return msgpack.NewDecoder(r).DecodeInterface()
+}
+
+// Converts the strings of the untyped formats to the numbers, booleans, lists or objects
+// expected by the target type, before the JSON decoding
+func coerceTree(tree any, target reflect.Type) any {
+	for target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	text, isText := tree.(string)
+
+	// The types decoding themselves, like time.Time, expect their usual string
+	if reflect.PointerTo(target).Implements(reflect.TypeFor[json.Unmarshaler]()) ||
+		reflect.PointerTo(target).Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) {
+		return tree
+	}
+
+	switch target.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if isText {
+			return json.Number(strings.TrimSpace(text))
+		}
+	case reflect.Bool:
+		if value, err := strconv.ParseBool(strings.TrimSpace(text)); isText && err == nil {
+			return value
+		}
+	case reflect.Slice, reflect.Array:
+		if isText {
+			// Nested JSON, like in the CSV cells, or an empty XML element
+			var decoded []any
+			if json.Unmarshal([]byte(text), &decoded) == nil {
+				tree = decoded
+			} else if strings.TrimSpace(text) == "" {
+				tree = []any{}
+			}
+		}
+		items, isList := tree.([]any)
+		if !isList {
+			// A single XML child is not seen as a list
+			items = []any{tree}
+		}
+		for i, item := range items {
+			items[i] = coerceTree(item, target.Elem())
+		}
+		return items
+	case reflect.Map, reflect.Struct:
+		if isText {
+			var decoded map[string]any
+			if json.Unmarshal([]byte(text), &decoded) == nil {
+				tree = decoded
+			}
+		}
+		object, isObject := tree.(map[string]any)
+		if !isObject {
+			return tree
+		}
+		for key, value := range object {
+			if fieldType, found := jsonFieldType(target, key); found {
+				object[key] = coerceTree(value, fieldType)
+			}
+		}
+		return object
+	}
+	return tree
+}
+
+// Finds the type of the field encoded with the given JSON name, or of the map values
+func jsonFieldType(target reflect.Type, name string) (reflect.Type, bool) {
+	if target.Kind() == reflect.Map {
+		return target.Elem(), true
+	}
+	for _, field := range reflect.VisibleFields(target) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		tagName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tagName == "-" {
+			continue
+		}
+		if tagName == name || (tagName == "" && strings.EqualFold(field.Name, name)) {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,77 @@
+package catsapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// Cat representation with its owner embedded, for ?expand=owner
+type CatWithOwner struct {
+	CatV1
+	Owner *Owner `json:"owner,omitempty"`
+}
+
+func getCat(ctx context.Context, req *http.Request, _ struct{}) (CatWithOwner, error) {
+	catID := req.PathValue("catId")
+	Logger.Info("Getting the cat: ", catID)
+
+	expand := req.URL.Query().Get("expand")
+	if expand != "" && expand != "owner" {
+		return CatWithOwner{}, badRequest("Invalid expand, only 'owner' is supported")
+	}
+
+	db := requestDB(ctx)
+	cat, found, err := db.readCat(catID)
+	if err != nil {
+		return CatWithOwner{}, err
+	}
+	dbLock.RLock()
+	owner, hasOwner := db.Owners[cat.OwnerID]
+	dbLock.RUnlock()
+
+	if !found {
+		Logger.Info("Cat not found")
+		return CatWithOwner{}, notFound("Cat not found")
+	}
+	Logger.Info("Cat found")
+	result := CatWithOwner{CatV1: cat.toV1()}
+	if expand == "owner" && hasOwner {
+		result.Owner = &owner
+	}
+	return result, nil
+}
+
+// New owner of a cat
+type CatTransfer struct {
+	OwnerID string `json:"ownerId"`
+}
+
+// Transfers the cat to another owner, an empty ownerId leaves the cat without owner
+func transferCat(ctx context.Context, req *http.Request, transfer CatTransfer) (CatV1, error) {
+	catID := req.PathValue("catId")
+	Logger.Infof("Transferring the cat '%s' to the owner '%s'", catID, transfer.OwnerID)
+	db := requestDB(ctx)
+
+	dbLock.Lock()
+	defer dbLock.Unlock()
+
+	cat, found := db.findCat(catID)
+	if !found {
+		Logger.Info("Cat not found")
+		return CatV1{}, notFound("Cat not found")
+	}
+	if _, found := db.Owners[transfer.OwnerID]; transfer.OwnerID != "" && !found {
+		Logger.Info("Owner not found")
+		return CatV1{}, badRequest("Owner not found")
+	}
+
+	before := cat
+	cat.OwnerID = transfer.OwnerID
+	cat, err := db.saveCat(ctx, "update", catID, &before, cat)
+	if err != nil {
+		return CatV1{}, err
+	}
+
+	Logger.Infof("Cat '%s' transferred", catID)
+	return cat.toV1(), nil
+}
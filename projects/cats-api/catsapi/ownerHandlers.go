@@ -0,0 +1,144 @@
+package catsapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Owner struct {
+	Name  string `json:"name"`
+	ID    string `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// The name is the only required field
+func (owner Owner) Validate() error {
+	if owner.Name == "" {
+		return badRequest("Invalid input, the owner name is required")
+	}
+	return nil
+}
+
+func listOwners(ctx context.Context, req *http.Request, _ struct{}) ([]string, error) {
+	Logger.Info("Listing the owners")
+
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	results := []string{}
+	for ownerID := range requestDB(ctx).Owners {
+		results = append(results, ownerID)
+	}
+	return results, nil
+}
+
+func createOwner(ctx context.Context, req *http.Request, owner Owner) (string, error) {
+	Logger.Info("Creating the owner: ", owner)
+	owner.ID = uuid.New().String()
+
+	dbLock.Lock()
+	requestDB(ctx).Owners[owner.ID] = owner
+	dbLock.Unlock()
+
+	Logger.Infof("Owner '%s' saved into the DB", owner.ID)
+	return owner.ID, nil
+}
+
+func getOwner(ctx context.Context, req *http.Request, _ struct{}) (Owner, error) {
+	ownerID := req.PathValue("ownerId")
+	Logger.Info("Getting the owner: ", ownerID)
+
+	dbLock.RLock()
+	owner, found := requestDB(ctx).Owners[ownerID]
+	dbLock.RUnlock()
+
+	if !found {
+		Logger.Info("Owner not found")
+		return Owner{}, notFound("Owner not found")
+	}
+	return owner, nil
+}
+
+func updateOwner(ctx context.Context, req *http.Request, owner Owner) (Owner, error) {
+	ownerID := req.PathValue("ownerId")
+	Logger.Info("Updating the owner: ", ownerID)
+	owner.ID = ownerID
+	db := requestDB(ctx)
+
+	dbLock.Lock()
+	defer dbLock.Unlock()
+
+	if _, found := db.Owners[ownerID]; !found {
+		Logger.Info("Owner not found")
+		return Owner{}, notFound("Owner not found")
+	}
+	db.Owners[ownerID] = owner
+
+	Logger.Infof("Owner '%s' updated", ownerID)
+	return owner, nil
+}
+
+// Deleting an owner who still has cats is refused, unless cascade=true moves the cats to the trash too
+func deleteOwner(ctx context.Context, req *http.Request, _ struct{}) (struct{}, error) {
+	ownerID := req.PathValue("ownerId")
+	cascade := req.URL.Query().Get("cascade") == "true"
+	Logger.Infof("Deleting the owner: %s (cascade: %v)", ownerID, cascade)
+	db := requestDB(ctx)
+
+	dbLock.Lock()
+	if _, found := db.Owners[ownerID]; !found {
+		dbLock.Unlock()
+		Logger.Info("Owner not found")
+		return struct{}{}, notFound("Owner not found")
+	}
+
+	catIDs := db.listOwnerCatIDs(ownerID)
+	if len(catIDs) > 0 && !cascade {
+		dbLock.Unlock()
+		Logger.Infof("Owner '%s' still has %d cats", ownerID, len(catIDs))
+		return struct{}{}, &APIError{http.StatusConflict, "Owner still has cats, transfer them or use cascade=true"}
+	}
+
+	now := time.Now()
+	for _, catID := range catIDs {
+		if _, err := db.trashCat(ctx, catID, now); err != nil {
+			dbLock.Unlock()
+			return struct{}{}, err
+		}
+	}
+	delete(db.Owners, ownerID)
+	dbLock.Unlock()
+
+	Logger.Infof("Owner '%s' deleted from the DB, %d cats moved to the trash", ownerID, len(catIDs))
+	return struct{}{}, nil
+}
+
+func listOwnerCats(ctx context.Context, req *http.Request, _ struct{}) ([]string, error) {
+	ownerID := req.PathValue("ownerId")
+	Logger.Info("Listing the cats of the owner: ", ownerID)
+	db := requestDB(ctx)
+
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	if _, found := db.Owners[ownerID]; !found {
+		Logger.Info("Owner not found")
+		return nil, notFound("Owner not found")
+	}
+	return db.listOwnerCatIDs(ownerID), nil
+}
+
+// Lists the IDs of the cats belonging to the owner, the caller must hold the DB lock
+func (db *tenantDB) listOwnerCatIDs(ownerID string) []string {
+	results := []string{}
+	for catID, cat := range db.Cats {
+		if cat.OwnerID == ownerID && cat.DeletedAt == nil {
+			results = append(results, catID)
+		}
+	}
+	return results
+}
@@ -0,0 +1,149 @@
+package catsapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Databases of the default tenant, the one of the test requests which do not tell their tenant
+func testDB() *tenantDB {
+	return openTenantDB(DefaultTenant)
+}
+
+// Request bound to the default tenant, for the tests calling the handlers without the middlewares
+func newTenantRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	return req.WithContext(context.WithValue(req.Context(), tenantContextKey{}, requestTenant{db: testDB()}))
+}
+
+// Swaps the cats and owners databases for empty ones
+func setupOwnerTest(t *testing.T) http.Handler {
+	originalCats, originalOwners := testDB().Cats, testDB().Owners
+	t.Cleanup(func() {
+		testDB().Cats, testDB().Owners = originalCats, originalOwners
+	})
+
+	testDB().Cats = map[string]Cat{}
+	testDB().Owners = map[string]Owner{}
+	return NewApp()
+}
+
+// Sends a JSON request to the app and decodes the JSON response
+func doJSON(t *testing.T, app http.Handler, method, path, body string, result any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+
+	if result != nil {
+		if err := json.Unmarshal(rr.Body.Bytes(), result); err != nil {
+			t.Fatalf("%s %s: failed to decode the response %q: %v", method, path, rr.Body.String(), err)
+		}
+	}
+	return rr.Code
+}
+
+// Test the owners CRUD operations
+func TestOwnersCRUD(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	var ownerID string
+	if code := doJSON(t, app, "POST", "/api/owners", `{"name": "Alice"}`, &ownerID); code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, code)
+	}
+	if code := doJSON(t, app, "POST", "/api/owners", `{"email": "nobody@example.com"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a nameless owner, got %d", http.StatusBadRequest, code)
+	}
+
+	var owners []string
+	doJSON(t, app, "GET", "/api/owners", "", &owners)
+	if len(owners) != 1 || owners[0] != ownerID {
+		t.Errorf("Expected [%s], got %v", ownerID, owners)
+	}
+
+	var owner Owner
+	if code := doJSON(t, app, "PUT", "/api/owners/"+ownerID, `{"name": "Alice", "email": "alice@example.com"}`, &owner); code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+	doJSON(t, app, "GET", "/api/owners/"+ownerID, "", &owner)
+	if owner.Email != "alice@example.com" || owner.ID != ownerID {
+		t.Errorf("Unexpected owner after update: %+v", owner)
+	}
+
+	if code := doJSON(t, app, "DELETE", "/api/owners/"+ownerID, "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, code)
+	}
+	if code := doJSON(t, app, "GET", "/api/owners/"+ownerID, "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, code)
+	}
+}
+
+// Test the cats ownership, transfer and expansion
+func TestCatOwnership(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	var aliceID, bobID, catID string
+	doJSON(t, app, "POST", "/api/owners", `{"name": "Alice"}`, &aliceID)
+	doJSON(t, app, "POST", "/api/owners", `{"name": "Bob"}`, &bobID)
+
+	if code := doJSON(t, app, "POST", "/api/cats", `{"name": "Toto", "ownerId": "unknown"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an unknown owner, got %d", http.StatusBadRequest, code)
+	}
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Toto", "ownerId": "`+aliceID+`"}`, &catID)
+
+	var catIDs []string
+	doJSON(t, app, "GET", "/api/owners/"+aliceID+"/cats", "", &catIDs)
+	if !slices.Equal(catIDs, []string{catID}) {
+		t.Errorf("Expected Alice to own [%s], got %v", catID, catIDs)
+	}
+
+	var cat Cat
+	if code := doJSON(t, app, "PUT", "/api/cats/"+catID+"/owner", `{"ownerId": "`+bobID+`"}`, &cat); code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+	if cat.OwnerID != bobID {
+		t.Errorf("Expected the cat to belong to %s, got %s", bobID, cat.OwnerID)
+	}
+	doJSON(t, app, "GET", "/api/owners/"+aliceID+"/cats", "", &catIDs)
+	if len(catIDs) != 0 {
+		t.Errorf("Expected Alice to own no cat, got %v", catIDs)
+	}
+
+	var expanded CatWithOwner
+	doJSON(t, app, "GET", "/api/cats/"+catID+"?expand=owner", "", &expanded)
+	if expanded.Owner == nil || expanded.Owner.Name != "Bob" {
+		t.Errorf("Expected Bob to be embedded, got %+v", expanded.Owner)
+	}
+	if code := doJSON(t, app, "GET", "/api/cats/"+catID+"?expand=photos", "", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an unknown expansion, got %d", http.StatusBadRequest, code)
+	}
+}
+
+// Test the referential integrity when deleting an owner
+func TestDeleteOwnerWithCats(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	var ownerID, catID string
+	doJSON(t, app, "POST", "/api/owners", `{"name": "Alice"}`, &ownerID)
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Toto", "ownerId": "`+ownerID+`"}`, &catID)
+
+	if code := doJSON(t, app, "DELETE", "/api/owners/"+ownerID, "", nil); code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, code)
+	}
+	if _, found := testDB().Owners[ownerID]; !found {
+		t.Error("The owner should not have been deleted")
+	}
+
+	if code := doJSON(t, app, "DELETE", "/api/owners/"+ownerID+"?cascade=true", "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, code)
+	}
+	if cat := testDB().Cats[catID]; cat.DeletedAt == nil {
+		t.Error("The cat should have been moved to the trash with its owner")
+	}
+}
@@ -0,0 +1,281 @@
+package catsapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Photo struct {
+	ID          string    `json:"id"`
+	CatID       string    `json:"catId"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	UploadedAt  time.Time `json:"uploadedAt"`
+}
+
+var photoBlobs BlobStore = newDiskBlobStore(getEnv("PHOTOS_DIR", filepath.Join(os.TempDir(), "cats-api", "photos")))
+
+// Maximum size of an uploaded photo, in bytes
+var maxPhotoSize = getEnvInt64("PHOTO_MAX_SIZE", 10<<20)
+
+// Maximum width x height of an uploaded photo, a small file may declare a huge image which would not fit in memory once decoded
+var maxPhotoPixels = getEnvInt64("PHOTO_MAX_PIXELS", 50_000_000)
+
+// Key of a photo file, under the directory of the tenant except for the default one, which was the only one before
+func (db *tenantDB) photoBlobKey(photo Photo, size string) string {
+	key := "cats/" + photo.CatID + "/" + photo.ID
+	if db.tenant != DefaultTenant {
+		key = "tenants/" + db.tenant + "/" + key
+	}
+	if size == "thumb" {
+		key += "-thumb"
+	}
+	return key
+}
+
+func uploadCatPhoto(ctx context.Context, req *http.Request, _ struct{}) (Photo, error) {
+	catID := req.PathValue("catId")
+	Logger.Info("Uploading a photo for the cat: ", catID)
+	db := requestDB(ctx)
+
+	dbLock.RLock()
+	_, catExists := db.findCat(catID)
+	dbLock.RUnlock()
+	if !catExists {
+		Logger.Infof("Cat '%s' not found in the DB", catID)
+		return Photo{}, notFound("Cat not found")
+	}
+
+	data, code, msg := readPhotoPart(req)
+	if code != http.StatusOK {
+		Logger.Info("Rejecting the photo upload: ", msg)
+		return Photo{}, &APIError{code, msg}
+	}
+
+	// Trust the bytes, not the client provided content type
+	contentType := http.DetectContentType(data)
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		Logger.Infof("Unsupported photo content type '%s'", contentType)
+		return Photo{}, &APIError{http.StatusUnsupportedMediaType, "Only JPEG and PNG photos are supported"}
+	}
+
+	// The orientation is lost with the EXIF metadata, so it is applied to the pixels
+	orientation := 1
+	if contentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	data, err := stripImageMetadata(contentType, data)
+	if err != nil {
+		Logger.Info("Unable to strip the photo metadata: ", err)
+		return Photo{}, badRequest("Invalid image")
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		Logger.Info("Unable to decode the photo: ", err)
+		return Photo{}, badRequest("Invalid image")
+	}
+	if int64(config.Width)*int64(config.Height) > maxPhotoPixels {
+		Logger.Infof("Rejecting a photo of %dx%d pixels", config.Width, config.Height)
+		return Photo{}, &APIError{http.StatusRequestEntityTooLarge, "Photo dimensions too large"}
+	}
+	if orientation != 1 {
+		if data, err = orientJPEG(data, orientation); err != nil {
+			Logger.Info("Unable to orient the photo: ", err)
+			return Photo{}, badRequest("Invalid image")
+		}
+		if orientation >= 5 {
+			config.Width, config.Height = config.Height, config.Width
+		}
+	}
+	thumbnail, err := makeThumbnail(contentType, bytes.NewReader(data))
+	if err != nil {
+		Logger.Info("Unable to make the photo thumbnail: ", err)
+		return Photo{}, badRequest("Invalid image")
+	}
+
+	// Not storing anything for a client which gave up
+	if err := ctx.Err(); err != nil {
+		return Photo{}, err
+	}
+
+	photo := Photo{
+		ID:          uuid.New().String(),
+		CatID:       catID,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Width:       config.Width,
+		Height:      config.Height,
+		UploadedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+
+	if err := photoBlobs.Put(db.photoBlobKey(photo, "original"), bytes.NewReader(data)); err != nil {
+		Logger.Error("Unable to store the photo: ", err)
+		return Photo{}, &APIError{http.StatusInternalServerError, "Unable to store the photo"}
+	}
+	if err := photoBlobs.Put(db.photoBlobKey(photo, "thumb"), bytes.NewReader(thumbnail)); err != nil {
+		Logger.Error("Unable to store the photo thumbnail: ", err)
+		db.deletePhotoBlobs([]Photo{photo})
+		return Photo{}, &APIError{http.StatusInternalServerError, "Unable to store the photo"}
+	}
+	// The blobs are needed by the photo once committed, they are only deleted if it never is
+	afterRollBack(func() { db.deletePhotoBlobs([]Photo{photo}) })
+
+	dbLock.Lock()
+	_, catExists = db.findCat(catID)
+	if catExists {
+		db.Photos[photo.ID] = photo
+	}
+	dbLock.Unlock()
+
+	// The cat may have been deleted during the upload
+	if !catExists {
+		db.deletePhotoBlobs([]Photo{photo})
+		return Photo{}, notFound("Cat not found")
+	}
+
+	Logger.Infof("Photo '%s' saved for the cat '%s'", photo.ID, catID)
+	return photo, nil
+}
+
+// Reads the "photo" part of the multipart body, enforcing the size limit
+func readPhotoPart(req *http.Request) ([]byte, int, string) {
+	// Also bound the whole body, leaving room for the other form fields
+	req.Body = http.MaxBytesReader(nil, req.Body, maxPhotoSize+(1<<20))
+
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, http.StatusBadRequest, "Expecting a multipart/form-data body"
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, http.StatusBadRequest, "Missing the 'photo' file field"
+		}
+		if err != nil {
+			return nil, multipartErrorCode(err), "Invalid multipart body"
+		}
+		if part.FormName() != "photo" {
+			part.Close()
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, maxPhotoSize+1))
+		part.Close()
+		if err != nil {
+			return nil, multipartErrorCode(err), "Invalid multipart body"
+		}
+		if int64(len(data)) > maxPhotoSize {
+			return nil, http.StatusRequestEntityTooLarge, "Photo too large"
+		}
+		return data, http.StatusOK, ""
+	}
+}
+
+func multipartErrorCode(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func listCatPhotos(ctx context.Context, req *http.Request, _ struct{}) ([]string, error) {
+	catID := req.PathValue("catId")
+	Logger.Info("Listing the photos of the cat: ", catID)
+	db := requestDB(ctx)
+
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	if _, catExists := db.findCat(catID); !catExists {
+		Logger.Infof("Cat '%s' not found in the DB", catID)
+		return nil, notFound("Cat not found")
+	}
+
+	results := []string{}
+	for _, photo := range db.Photos {
+		if photo.CatID == catID {
+			results = append(results, photo.ID)
+		}
+	}
+	return results, nil
+}
+
+// Serves the photo bytes, so it cannot go through makeHandlerFunc
+func getCatPhoto(res http.ResponseWriter, req *http.Request) {
+	catID := req.PathValue("catId")
+	photoID := req.PathValue("photoId")
+	Logger.Infof("Getting the photo '%s' of the cat '%s'", photoID, catID)
+
+	size := req.URL.Query().Get("size")
+	if size == "" {
+		size = "original"
+	}
+	if size != "original" && size != "thumb" {
+		writeJSON(res, http.StatusBadRequest, "Invalid size, expecting 'original' or 'thumb'")
+		return
+	}
+
+	db := requestDB(req.Context())
+	dbLock.RLock()
+	photo, found := db.Photos[photoID]
+	_, catExists := db.findCat(catID)
+	dbLock.RUnlock()
+	if !found || photo.CatID != catID || !catExists {
+		Logger.Info("Photo not found")
+		writeJSON(res, http.StatusNotFound, "Photo not found")
+		return
+	}
+
+	blob, err := photoBlobs.Open(db.photoBlobKey(photo, size))
+	if err != nil {
+		Logger.Error("Unable to read the photo: ", err)
+		if errors.Is(err, errBlobNotFound) {
+			writeJSON(res, http.StatusNotFound, "Photo not found")
+		} else {
+			writeJSON(res, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+		return
+	}
+	defer blob.Close()
+
+	// Photos never change once uploaded, so clients may keep them forever
+	res.Header().Set("Content-Type", photo.ContentType)
+	res.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	res.Header().Set("ETag", `"`+photo.ID+"-"+size+`"`)
+	http.ServeContent(res, req, "", photo.UploadedAt, blob)
+}
+
+// Removes the photos of a cat from the metadata, the caller must hold the DB write lock
+func (db *tenantDB) detachCatPhotos(catID string) []Photo {
+	photos := []Photo{}
+	for photoID, photo := range db.Photos {
+		if photo.CatID == catID {
+			photos = append(photos, photo)
+			delete(db.Photos, photoID)
+		}
+	}
+	return photos
+}
+
+func (db *tenantDB) deletePhotoBlobs(photos []Photo) {
+	for _, photo := range photos {
+		for _, size := range []string{"original", "thumb"} {
+			if err := photoBlobs.Delete(db.photoBlobKey(photo, size)); err != nil {
+				Logger.Errorf("Unable to delete the photo '%s': %v", photo.ID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package catsapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Swaps the photo storage and cats database for isolated ones
+func setupPhotoTest(t *testing.T) http.Handler {
+	originalDB, originalPhotos, originalBlobs := testDB().Cats, testDB().Photos, photoBlobs
+	t.Cleanup(func() {
+		testDB().Cats, testDB().Photos, photoBlobs = originalDB, originalPhotos, originalBlobs
+	})
+
+	testDB().Cats = map[string]Cat{"cat-1": {ID: "cat-1", Name: "Toto"}}
+	testDB().Photos = map[string]Photo{}
+	photoBlobs = newDiskBlobStore(t.TempDir())
+	return NewApp()
+}
+
+func makeTestPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x%height, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode the test PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// JPEG with a red left half and a blue right half, and an EXIF segment telling its orientation
+func makeOrientedJPEG(t *testing.T, width, height int, orientation uint16) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("Failed to encode the test JPEG: %v", err)
+	}
+
+	// Big endian TIFF with a single directory entry, the orientation short
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(tiff[18:20], orientation)
+	exif := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xFF, 0xE1, 0, 0}, exif...)
+	binary.BigEndian.PutUint16(segment[2:4], uint16(len(exif)+2))
+	return append(append(append([]byte{}, buf.Bytes()[:2]...), segment...), buf.Bytes()[2:]...)
+}
+
+// PNG of a few bytes declaring a huge image, its IHDR chunk being rewritten with a valid checksum
+func makeForgedPNG(t *testing.T, width, height uint32) []byte {
+	data := makeTestPNG(t, 10, 10)
+	ihdr := data[len(pngSignature)+4:]
+	binary.BigEndian.PutUint32(ihdr[4:8], width)
+	binary.BigEndian.PutUint32(ihdr[8:12], height)
+	binary.BigEndian.PutUint32(ihdr[17:21], crc32.ChecksumIEEE(ihdr[:17]))
+	return data
+}
+
+func makePhotoUpload(t *testing.T, catID string, data []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("photo", "cat.png")
+	if err != nil {
+		t.Fatalf("Failed to create the form file: %v", err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/api/cats/"+catID+"/photos", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// Test the complete upload, download and deletion of a photo
+func TestPhotoUploadAndServe(t *testing.T) {
+	app := setupPhotoTest(t)
+
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, makePhotoUpload(t, "cat-1", makeTestPNG(t, 600, 300)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	var photo Photo
+	if err := json.Unmarshal(rr.Body.Bytes(), &photo); err != nil {
+		t.Fatalf("Failed to decode the photo: %v", err)
+	}
+	if photo.ContentType != "image/png" || photo.Width != 600 || photo.Height != 300 {
+		t.Errorf("Unexpected photo metadata: %+v", photo)
+	}
+
+	// The thumbnail keeps the aspect ratio
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest("GET", "/api/cats/cat-1/photos/"+photo.ID+"?size=thumb", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if rr.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("Expected content type image/png, got %s", rr.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rr.Header().Get("Cache-Control"), "immutable") {
+		t.Errorf("Expected an immutable cache control, got %s", rr.Header().Get("Cache-Control"))
+	}
+	thumb, err := png.Decode(rr.Body)
+	if err != nil {
+		t.Fatalf("Failed to decode the thumbnail: %v", err)
+	}
+	if thumb.Bounds().Dx() != thumbnailSize || thumb.Bounds().Dy() != thumbnailSize/2 {
+		t.Errorf("Unexpected thumbnail size %v", thumb.Bounds())
+	}
+
+	// Revalidation with the ETag
+	req := httptest.NewRequest("GET", "/api/cats/cat-1/photos/"+photo.ID, nil)
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("The thumbnail ETag should not match the original, got %d", rr.Code)
+	}
+	req = httptest.NewRequest("GET", "/api/cats/cat-1/photos/"+photo.ID, nil)
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotModified, rr.Code)
+	}
+
+	// Deleting the cat hides its photos, purging it removes them
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/cats/cat-1", nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected status code %d, got %d", http.StatusNoContent, rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest("GET", "/api/cats/cat-1/photos/"+photo.ID, nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d for a trashed cat, got %d", http.StatusNotFound, rr.Code)
+	}
+	purgeTrash(time.Now().Add(trashRetention))
+	if len(testDB().Photos) != 0 {
+		t.Errorf("Expected no photo left, got %d", len(testDB().Photos))
+	}
+	if _, err := photoBlobs.Open(testDB().photoBlobKey(photo, "original")); err != errBlobNotFound {
+		t.Errorf("Expected the photo file to be deleted, got %v", err)
+	}
+}
+
+// Test the rejected uploads
+func TestPhotoUploadErrors(t *testing.T) {
+	app := setupPhotoTest(t)
+
+	originalMax := maxPhotoSize
+	maxPhotoSize = 1024
+	defer func() { maxPhotoSize = originalMax }()
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		expected int
+	}{
+		{"unknown cat", makePhotoUpload(t, "unknown", makeTestPNG(t, 10, 10)), http.StatusNotFound},
+		{"not an image", makePhotoUpload(t, "cat-1", []byte("just some text")), http.StatusUnsupportedMediaType},
+		{"too large", makePhotoUpload(t, "cat-1", bytes.Repeat([]byte{0}, 2048)), http.StatusRequestEntityTooLarge},
+		{"too many pixels", makePhotoUpload(t, "cat-1", makeForgedPNG(t, 50000, 50000)), http.StatusRequestEntityTooLarge},
+		{"truncated image", makePhotoUpload(t, "cat-1", makeTestPNG(t, 10, 10)[:40]), http.StatusBadRequest},
+		{"not multipart", httptest.NewRequest("POST", "/api/cats/cat-1/photos", strings.NewReader("{}")), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			app.ServeHTTP(rr, tt.req)
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d: %s", tt.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	if len(testDB().Photos) != 0 {
+		t.Errorf("Expected no photo stored, got %d", len(testDB().Photos))
+	}
+}
+
+// Test that the EXIF orientation is applied to the pixels before the metadata is removed
+func TestPhotoUploadOrientation(t *testing.T) {
+	app := setupPhotoTest(t)
+
+	// Rotated by 90 degrees clockwise when displayed: the left half is at the top
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, makePhotoUpload(t, "cat-1", makeOrientedJPEG(t, 40, 20, 6)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var photo Photo
+	json.Unmarshal(rr.Body.Bytes(), &photo)
+	if photo.Width != 20 || photo.Height != 40 {
+		t.Errorf("Expected the dimensions of the upright photo, got %dx%d", photo.Width, photo.Height)
+	}
+
+	for _, size := range []string{"original", "thumb"} {
+		rr = httptest.NewRecorder()
+		app.ServeHTTP(rr, httptest.NewRequest("GET", "/api/cats/cat-1/photos/"+photo.ID+"?size="+size, nil))
+		if bytes.Contains(rr.Body.Bytes(), []byte("Exif")) {
+			t.Errorf("Expected the %s photo without EXIF metadata", size)
+		}
+		img, err := jpeg.Decode(rr.Body)
+		if err != nil {
+			t.Fatalf("Failed to decode the %s photo: %v", size, err)
+		}
+		bounds := img.Bounds()
+		if bounds.Dx() != 20 || bounds.Dy() != 40 {
+			t.Fatalf("Expected the %s photo upright, got %v", size, bounds)
+		}
+		top, _, _, _ := img.At(10, 5).RGBA()
+		bottom, _, _, _ := img.At(10, 35).RGBA()
+		if top < 0x8000 || bottom > 0x8000 {
+			t.Errorf("Expected the red half at the top of the %s photo", size)
+		}
+	}
+
+	// The upright photos are kept as they are
+	if orientation := jpegOrientation(makeOrientedJPEG(t, 4, 4, 1)); orientation != 1 {
+		t.Errorf("Expected the orientation 1, got %d", orientation)
+	}
+	for orientation := range 8 {
+		img := orientImage(image.NewRGBA(image.Rect(0, 0, 4, 2)), orientation+1)
+		if wide := img.Bounds().Dx() == 4; wide != (orientation+1 < 5) {
+			t.Errorf("Unexpected bounds %v for the orientation %d", img.Bounds(), orientation+1)
+		}
+	}
+}
+
+// Test the metadata removal from the uploaded images
+func TestStripImageMetadata(t *testing.T) {
+	// JPEG with an EXIF segment followed by a comment and the scan
+	jpegData := []byte{0xFF, 0xD8,
+		0xFF, 0xE1, 0x00, 0x08, 'E', 'x', 'i', 'f', 0x00, 0x00,
+		0xFF, 0xFE, 0x00, 0x04, 'h', 'i',
+		0xFF, 0xDB, 0x00, 0x03, 0x01,
+		0xFF, 0xDA, 0x00, 0x02, 0x42, 0xFF, 0xD9}
+	stripped, err := stripImageMetadata("image/jpeg", jpegData)
+	if err != nil {
+		t.Fatalf("Failed to strip the JPEG: %v", err)
+	}
+	expected := []byte{0xFF, 0xD8, 0xFF, 0xDB, 0x00, 0x03, 0x01, 0xFF, 0xDA, 0x00, 0x02, 0x42, 0xFF, 0xD9}
+	if !bytes.Equal(stripped, expected) {
+		t.Errorf("Expected %x, got %x", expected, stripped)
+	}
+
+	// PNG with a text chunk
+	pngData := makeTestPNG(t, 4, 4)
+	textChunk := []byte{0, 0, 0, 4, 't', 'E', 'X', 't', 'a', '=', 'b', 'c', 0, 0, 0, 0}
+	withText := append(append(append([]byte{}, pngData[:33]...), textChunk...), pngData[33:]...)
+	stripped, err = stripImageMetadata("image/png", withText)
+	if err != nil {
+		t.Fatalf("Failed to strip the PNG: %v", err)
+	}
+	if !bytes.Equal(stripped, pngData) {
+		t.Error("Expected the text chunk to be removed")
+	}
+}
@@ -0,0 +1,232 @@
+package catsapi
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const dateLayout = "2006-01-02"
+
+// Validity of a vaccination when the record does not tell
+const defaultVaccineValidMonths = 12
+
+var recordTypes = []string{"vaccination", "checkup", "treatment"}
+
+// Medical entry of a cat
+type Record struct {
+	ID      string `json:"id,omitempty"`
+	CatID   string `json:"catId,omitempty"`
+	Type    string `json:"type"`
+	Date    string `json:"date"`
+	VetName string `json:"vetName,omitempty"`
+	Notes   string `json:"notes,omitempty"`
+
+	// Vaccinations only
+	Vaccine     string `json:"vaccine,omitempty"`
+	ValidMonths int    `json:"validMonths,omitempty"`
+	DueDate     string `json:"dueDate,omitempty"`
+}
+
+// Medical history of a cat
+type CatRecords struct {
+	Records            []Record `json:"records"`
+	NextVaccinationDue string   `json:"nextVaccinationDue,omitempty"`
+}
+
+// Vaccine booster to plan, in the cross-cats due query
+type DueVaccination struct {
+	CatID    string `json:"catId"`
+	RecordID string `json:"recordId"`
+	Vaccine  string `json:"vaccine"`
+	DueDate  string `json:"dueDate"`
+}
+
+// Checks the record and fills its computed fields
+func (record *Record) Validate() error {
+	if !slices.Contains(recordTypes, record.Type) {
+		return badRequest("Invalid record type, expecting vaccination, checkup or treatment")
+	}
+	date, err := time.Parse(dateLayout, record.Date)
+	if err != nil {
+		return badRequest("Invalid record date, expecting YYYY-MM-DD")
+	}
+
+	if record.Type != "vaccination" {
+		record.Vaccine, record.ValidMonths, record.DueDate = "", 0, ""
+		return nil
+	}
+	if record.Vaccine == "" {
+		return badRequest("Missing the vaccine name")
+	}
+	if record.ValidMonths < 0 {
+		return badRequest("Invalid vaccine validity")
+	}
+	if record.ValidMonths == 0 {
+		record.ValidMonths = defaultVaccineValidMonths
+	}
+	record.DueDate = date.AddDate(0, record.ValidMonths, 0).Format(dateLayout)
+	return nil
+}
+
+// Lists the latest vaccination of each cat and vaccine, for one cat or all of them when catID is empty.
+// The caller must hold the DB lock.
+func (db *tenantDB) latestVaccinations(catID string) map[[2]string]Record {
+	latest := map[[2]string]Record{}
+	for _, record := range db.Records {
+		if (catID != "" && record.CatID != catID) || record.Type != "vaccination" {
+			continue
+		}
+		// The dates layout sorts lexicographically
+		key := [2]string{record.CatID, record.Vaccine}
+		if current, found := latest[key]; !found || record.Date > current.Date {
+			latest[key] = record
+		}
+	}
+	return latest
+}
+
+func createCatRecord(ctx context.Context, req *http.Request, record Record) (string, error) {
+	catID := req.PathValue("catId")
+	Logger.Infof("Creating a %s record for the cat '%s'", record.Type, catID)
+
+	record.ID = uuid.New().String()
+	record.CatID = catID
+	db := requestDB(ctx)
+
+	dbLock.Lock()
+	defer dbLock.Unlock()
+
+	if _, catExists := db.findCat(catID); !catExists {
+		Logger.Infof("Cat '%s' not found in the DB", catID)
+		return "", notFound("Cat not found")
+	}
+	db.Records[record.ID] = record
+
+	Logger.Infof("Record '%s' saved into the DB", record.ID)
+	return record.ID, nil
+}
+
+func listCatRecords(ctx context.Context, req *http.Request, _ struct{}) (CatRecords, error) {
+	catID := req.PathValue("catId")
+	Logger.Info("Listing the records of the cat: ", catID)
+	db := requestDB(ctx)
+
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	if _, catExists := db.findCat(catID); !catExists {
+		Logger.Infof("Cat '%s' not found in the DB", catID)
+		return CatRecords{}, notFound("Cat not found")
+	}
+
+	return db.findCatRecords(catID), nil
+}
+
+// Gets the records of a cat sorted by date, with its next vaccination due. The caller must hold the DB lock.
+func (db *tenantDB) findCatRecords(catID string) CatRecords {
+	result := CatRecords{Records: []Record{}}
+	for _, record := range db.Records {
+		if record.CatID == catID {
+			result.Records = append(result.Records, record)
+		}
+	}
+	slices.SortFunc(result.Records, func(a, b Record) int {
+		if a.Date != b.Date {
+			return cmp.Compare(a.Date, b.Date)
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	for _, vaccination := range db.latestVaccinations(catID) {
+		if result.NextVaccinationDue == "" || vaccination.DueDate < result.NextVaccinationDue {
+			result.NextVaccinationDue = vaccination.DueDate
+		}
+	}
+	return result
+}
+
+func getCatRecord(ctx context.Context, req *http.Request, _ struct{}) (Record, error) {
+	catID := req.PathValue("catId")
+	recordID := req.PathValue("recordId")
+	Logger.Infof("Getting the record '%s' of the cat '%s'", recordID, catID)
+	db := requestDB(ctx)
+
+	dbLock.RLock()
+	record, found := db.Records[recordID]
+	_, catExists := db.findCat(catID)
+	dbLock.RUnlock()
+
+	if !found || record.CatID != catID || !catExists {
+		Logger.Info("Record not found")
+		return Record{}, notFound("Record not found")
+	}
+	return record, nil
+}
+
+func deleteCatRecord(ctx context.Context, req *http.Request, _ struct{}) (struct{}, error) {
+	catID := req.PathValue("catId")
+	recordID := req.PathValue("recordId")
+	Logger.Infof("Deleting the record '%s' of the cat '%s'", recordID, catID)
+	db := requestDB(ctx)
+
+	dbLock.Lock()
+	defer dbLock.Unlock()
+
+	_, catExists := db.findCat(catID)
+	if record, found := db.Records[recordID]; !found || record.CatID != catID || !catExists {
+		Logger.Info("Record not found")
+		return struct{}{}, notFound("Record not found")
+	}
+	delete(db.Records, recordID)
+
+	Logger.Infof("Record '%s' deleted from the DB", recordID)
+	return struct{}{}, nil
+}
+
+// Lists the vaccine boosters due before the given date (defaults to today), across all the cats of the tenant
+func listDueVaccinations(ctx context.Context, req *http.Request, _ struct{}) ([]DueVaccination, error) {
+	before := req.URL.Query().Get("before")
+	if before == "" {
+		before = time.Now().Format(dateLayout)
+	} else if _, err := time.Parse(dateLayout, before); err != nil {
+		return nil, badRequest("Invalid before date, expecting YYYY-MM-DD")
+	}
+	Logger.Info("Listing the vaccinations due before: ", before)
+	db := requestDB(ctx)
+
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	results := []DueVaccination{}
+	for _, vaccination := range db.latestVaccinations("") {
+		if _, catExists := db.findCat(vaccination.CatID); catExists && vaccination.DueDate < before {
+			results = append(results, DueVaccination{
+				CatID:    vaccination.CatID,
+				RecordID: vaccination.ID,
+				Vaccine:  vaccination.Vaccine,
+				DueDate:  vaccination.DueDate,
+			})
+		}
+	}
+	slices.SortFunc(results, func(a, b DueVaccination) int {
+		if a.DueDate != b.DueDate {
+			return cmp.Compare(a.DueDate, b.DueDate)
+		}
+		return cmp.Compare(a.RecordID, b.RecordID)
+	})
+	return results, nil
+}
+
+// Removes the records of a cat, the caller must hold the DB write lock
+func (db *tenantDB) deleteCatRecords(catID string) {
+	for recordID, record := range db.Records {
+		if record.CatID == catID {
+			delete(db.Records, recordID)
+		}
+	}
+}
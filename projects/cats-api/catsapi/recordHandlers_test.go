@@ -0,0 +1,111 @@
+package catsapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Swaps the cats and records databases for isolated ones
+func setupRecordTest(t *testing.T) http.Handler {
+	originalCats, originalRecords := testDB().Cats, testDB().Records
+	t.Cleanup(func() {
+		testDB().Cats, testDB().Records = originalCats, originalRecords
+	})
+
+	testDB().Cats = map[string]Cat{
+		"cat-1": {ID: "cat-1", Name: "Toto"},
+		"cat-2": {ID: "cat-2", Name: "Felix"},
+	}
+	testDB().Records = map[string]Record{}
+	return NewApp()
+}
+
+// Test the medical records of a cat and the computed due dates
+func TestCatRecords(t *testing.T) {
+	app := setupRecordTest(t)
+
+	var rabiesID, checkupID string
+	doJSON(t, app, "POST", "/api/cats/cat-1/records", `{"type": "vaccination", "date": "2024-01-10", "vaccine": "rabies", "vetName": "Dr Who"}`, nil)
+	doJSON(t, app, "POST", "/api/cats/cat-1/records", `{"type": "vaccination", "date": "2025-01-10", "vaccine": "rabies"}`, &rabiesID)
+	doJSON(t, app, "POST", "/api/cats/cat-1/records", `{"type": "vaccination", "date": "2024-06-01", "vaccine": "typhus", "validMonths": 36}`, nil)
+	if code := doJSON(t, app, "POST", "/api/cats/cat-1/records", `{"type": "checkup", "date": "2024-03-01", "notes": "All good"}`, &checkupID); code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, code)
+	}
+
+	var records CatRecords
+	if code := doJSON(t, app, "GET", "/api/cats/cat-1/records", "", &records); code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+	if len(records.Records) != 4 {
+		t.Fatalf("Expected 4 records, got %d", len(records.Records))
+	}
+	if records.Records[0].Date != "2024-01-10" || records.Records[3].Date != "2025-01-10" {
+		t.Errorf("Expected the records sorted by date, got %+v", records.Records)
+	}
+	// Only the latest rabies shot counts
+	if records.NextVaccinationDue != "2026-01-10" {
+		t.Errorf("Expected the next vaccination due on 2026-01-10, got %s", records.NextVaccinationDue)
+	}
+
+	var record Record
+	doJSON(t, app, "GET", "/api/cats/cat-1/records/"+checkupID, "", &record)
+	if record.Type != "checkup" || record.Notes != "All good" || record.DueDate != "" {
+		t.Errorf("Unexpected checkup record: %+v", record)
+	}
+	if code := doJSON(t, app, "GET", "/api/cats/cat-2/records/"+checkupID, "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status code %d for another cat's record, got %d", http.StatusNotFound, code)
+	}
+
+	var due []DueVaccination
+	doJSON(t, app, "GET", "/api/records/due?before=2027-07-01", "", &due)
+	if len(due) != 2 || due[0].Vaccine != "rabies" || due[0].RecordID != rabiesID || due[1].DueDate != "2027-06-01" {
+		t.Errorf("Unexpected due vaccinations: %+v", due)
+	}
+	doJSON(t, app, "GET", "/api/records/due?before=2026-01-10", "", &due)
+	if len(due) != 0 {
+		t.Errorf("Expected no vaccination due, got %+v", due)
+	}
+
+	if code := doJSON(t, app, "DELETE", "/api/cats/cat-1/records/"+checkupID, "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, code)
+	}
+
+	// The records go away when their cat is purged
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/api/cats/cat-1", nil))
+	purgeTrash(time.Now().Add(trashRetention))
+	if len(testDB().Records) != 0 {
+		t.Errorf("Expected no record left, got %d", len(testDB().Records))
+	}
+}
+
+// Test the rejected records
+func TestCatRecordsErrors(t *testing.T) {
+	app := setupRecordTest(t)
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		expected int
+	}{
+		{"unknown cat", "/api/cats/unknown/records", `{"type": "checkup", "date": "2024-01-01"}`, http.StatusNotFound},
+		{"unknown type", "/api/cats/cat-1/records", `{"type": "grooming", "date": "2024-01-01"}`, http.StatusBadRequest},
+		{"invalid date", "/api/cats/cat-1/records", `{"type": "checkup", "date": "01/02/2024"}`, http.StatusBadRequest},
+		{"missing vaccine", "/api/cats/cat-1/records", `{"type": "vaccination", "date": "2024-01-01"}`, http.StatusBadRequest},
+		{"invalid JSON", "/api/cats/cat-1/records", `{"type":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := doJSON(t, app, "POST", tt.path, tt.body, nil); code != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+
+	if code := doJSON(t, app, "GET", "/api/records/due?before=tomorrow", "", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid date, got %d", http.StatusBadRequest, code)
+	}
+}
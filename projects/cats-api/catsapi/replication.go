@@ -0,0 +1,942 @@
+package catsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Role of a replica in the Raft consensus
+type replicaRole int
+
+const (
+	roleFollower replicaRole = iota
+	roleCandidate
+	roleLeader
+)
+
+func (role replicaRole) String() string {
+	return [...]string{"follower", "candidate", "leader"}[role]
+}
+
+// Entry of the replicated log, carrying the databases of the tenants changed by a write of the leader.
+// The first entry of each leader carries all the tenants, for the replicas to converge on its state.
+type logEntry struct {
+	Term    uint64                `json:"term"`
+	Index   uint64                `json:"index"`
+	Tenants map[string]TenantData `json:"tenants"`
+}
+
+type voteRequest struct {
+	Term      uint64 `json:"term"`
+	Candidate string `json:"candidate"`
+	LastIndex uint64 `json:"lastIndex"`
+	LastTerm  uint64 `json:"lastTerm"`
+}
+
+type voteResponse struct {
+	Term    uint64 `json:"term"`
+	Granted bool   `json:"granted"`
+}
+
+// Entries sent by the leader, empty for the heartbeats. The snapshot replaces the log of the followers
+// lagging behind the compacted entries.
+type appendRequest struct {
+	Term      uint64     `json:"term"`
+	Leader    string     `json:"leader"`
+	PrevIndex uint64     `json:"prevIndex"`
+	PrevTerm  uint64     `json:"prevTerm"`
+	Entries   []logEntry `json:"entries,omitempty"`
+	Commit    uint64     `json:"commit"`
+	Snapshot  *logEntry  `json:"snapshot,omitempty"`
+}
+
+// LastIndex is the last entry matching the log of the leader on success, else a hint of where to resume
+type appendResponse struct {
+	Term      uint64 `json:"term"`
+	Success   bool   `json:"success"`
+	LastIndex uint64 `json:"lastIndex"`
+}
+
+// Sends the messages of the consensus to the other replicas
+type replicaTransport interface {
+	requestVote(ctx context.Context, peer string, req voteRequest) (voteResponse, error)
+	appendEntries(ctx context.Context, peer string, req appendRequest) (appendResponse, error)
+	readIndex(ctx context.Context, peer string) (uint64, error)
+}
+
+// Databases kept in sync by the log, copied in and out of the entries
+type replicatedStore interface {
+	// Copies the databases of the tenants, all of them when nil
+	capture(tenants []string) map[string]TenantData
+	// Replaces the databases of the given tenants
+	apply(tenants map[string]TenantData)
+	// Replaces the databases of all the tenants, emptying the missing ones
+	restore(tenants map[string]TenantData)
+}
+
+// Default number of entries above which the committed ones are folded into the snapshot of the log
+const defaultMaxLogEntries = 256
+
+// Maximum number of entries sent at once to a follower
+const maxAppendEntries = 64
+
+// Replica of the databases, agreeing with the other ones on a log of writes through the Raft consensus.
+// The writes are run by the leader, which replicates the resulting databases to the followers: an entry
+// is committed, and the write answered, once a majority of the replicas has it.
+type replicaNode struct {
+	self            string
+	size            int
+	electionTimeout time.Duration
+	maxLogEntries   int
+	followerReads   bool
+	secret          string
+	// Port of the gRPC API, the same on all the replicas, where the followers forward the gRPC writes
+	grpcPort  string
+	transport replicaTransport
+	store     replicatedStore
+	// Goroutines of the elections and of the replication to the peers, waited for by Run when stopping
+	workers sync.WaitGroup
+	// File keeping the term, the vote and the log across the restarts, none in the tests
+	stateFile string
+	saved     replicaStateMark
+
+	// Serializes the writes of the leader, the reads waiting for the running one to be committed
+	writeLock sync.RWMutex
+	// Effects of the running write, queued until its entry is settled
+	runningLock sync.Mutex
+	running     *writeEffects
+
+	lock        sync.Mutex
+	peers       []string
+	role        replicaRole
+	term        uint64
+	votedFor    string
+	leader      string
+	snapshot    logEntry
+	entries     []logEntry
+	commitIndex uint64
+	lastApplied uint64
+	termStart   uint64
+	nextIndex   map[string]uint64
+	matchIndex  map[string]uint64
+	ackedAt     map[string]time.Time
+	wakeups     map[string]chan struct{}
+	effects     map[uint64]*writeEffects
+	deadline    time.Time
+	changed     chan struct{}
+	stop        <-chan struct{}
+}
+
+// Term, vote and log which must survive the restarts of the replica: forgetting them, it could vote twice in
+// a term, or elect a leader missing the entries it acknowledged
+type replicaState struct {
+	Term     uint64     `json:"term"`
+	VotedFor string     `json:"votedFor"`
+	Snapshot logEntry   `json:"snapshot"`
+	Entries  []logEntry `json:"entries"`
+}
+
+// What the saved state was made of, the logs ending with the same entry being the same
+type replicaStateMark struct {
+	term          uint64
+	votedFor      string
+	snapshotIndex uint64
+	lastIndex     uint64
+	lastTerm      uint64
+}
+
+// Replica in the given cluster of peers, starting as a follower with the current content of the store
+func newReplicaNode(self string, peers []string, size int, electionTimeout time.Duration, transport replicaTransport, store replicatedStore) *replicaNode {
+	node := &replicaNode{
+		self:            self,
+		size:            size,
+		electionTimeout: electionTimeout,
+		maxLogEntries:   defaultMaxLogEntries,
+		transport:       transport,
+		store:           store,
+		snapshot:        logEntry{Tenants: store.capture(nil)},
+		effects:         map[uint64]*writeEffects{},
+		changed:         make(chan struct{}),
+	}
+	node.setPeers(peers)
+	node.resetDeadline()
+	return node
+}
+
+// Takes the state saved before a restart, if any, the databases going back to its snapshot
+func (node *replicaNode) loadState() error {
+	state, found, err := readReplicaState(node.stateFile)
+	if err != nil || !found {
+		return err
+	}
+	node.lock.Lock()
+	defer node.lock.Unlock()
+
+	node.term, node.votedFor = state.Term, state.VotedFor
+	node.snapshot, node.entries = state.Snapshot, state.Entries
+	node.commitIndex, node.lastApplied = state.Snapshot.Index, state.Snapshot.Index
+	node.store.restore(state.Snapshot.Tenants)
+	node.saved = node.stateMark()
+	Logger.Infof("Replica %s resuming the term %d with %d entries", node.self, node.term, node.lastIndex())
+	return nil
+}
+
+func (node *replicaNode) stateMark() replicaStateMark {
+	return replicaStateMark{node.term, node.votedFor, node.snapshot.Index, node.lastIndex(), node.termAt(node.lastIndex())}
+}
+
+// Saves the state when it changed, the lock being held: it must be saved before the replica answers or sends
+// a message depending on it
+func (node *replicaNode) saveState() error {
+	mark := node.stateMark()
+	if node.stateFile == "" || mark == node.saved {
+		return nil
+	}
+	state := replicaState{Term: node.term, VotedFor: node.votedFor, Snapshot: node.snapshot, Entries: node.entries}
+	if err := writeReplicaState(node.stateFile, state); err != nil {
+		Logger.Error("Unable to save the state of the replica: ", err)
+		return err
+	}
+	node.saved = mark
+	return nil
+}
+
+// Reads the state file, found is false when it does not exist yet
+func readReplicaState(path string) (state replicaState, found bool, err error) {
+	if path == "" {
+		return state, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, false, nil
+	}
+	if err != nil {
+		return state, false, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, true, fmt.Errorf("invalid replica state file %s: %w", path, err)
+	}
+	return state, true, nil
+}
+
+// Writes the state file atomically, synced to the disk before the replica relies on it
+func writeReplicaState(path string, state replicaState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if err := json.NewEncoder(temp).Encode(state); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Updates the other replicas, for the peers discovered through the DNS
+func (node *replicaNode) setPeers(peers []string) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+
+	node.peers = slices.DeleteFunc(slices.Clone(peers), func(peer string) bool { return peer == node.self })
+	if node.role == roleLeader {
+		for _, peer := range node.peers {
+			node.startReplication(peer)
+		}
+	}
+}
+
+// Runs the elections until the stop channel is closed, returning once the replica stopped sending messages
+func (node *replicaNode) Run(stop <-chan struct{}) {
+	node.lock.Lock()
+	node.stop = stop
+	node.lock.Unlock()
+
+	ticker := time.NewTicker(node.electionTimeout / 10)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			node.workers.Wait()
+			return
+		case now := <-ticker.C:
+			node.lock.Lock()
+			if node.role != roleLeader && now.After(node.deadline) {
+				node.startElection()
+			}
+			node.lock.Unlock()
+		}
+	}
+}
+
+// Majority of the replicas
+func (node *replicaNode) quorum() int {
+	return node.size/2 + 1
+}
+
+func (node *replicaNode) heartbeatInterval() time.Duration {
+	return node.electionTimeout / 5
+}
+
+// Randomizes the next election, for the replicas not to compete forever
+func (node *replicaNode) resetDeadline() {
+	node.deadline = time.Now().Add(node.electionTimeout + rand.N(node.electionTimeout))
+}
+
+// Wakes up the callers waiting for a change, the lock being held
+func (node *replicaNode) notify() {
+	close(node.changed)
+	node.changed = make(chan struct{})
+}
+
+// Waits for the condition, checked with the lock held on each change
+func (node *replicaNode) waitFor(ctx context.Context, condition func() bool) error {
+	for {
+		node.lock.Lock()
+		if condition() {
+			node.lock.Unlock()
+			return nil
+		}
+		changed := node.changed
+		node.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (node *replicaNode) lastIndex() uint64 {
+	return node.snapshot.Index + uint64(len(node.entries))
+}
+
+// Term of the entry, 0 when it is compacted
+func (node *replicaNode) termAt(index uint64) uint64 {
+	if index < node.snapshot.Index {
+		return 0
+	}
+	if index == node.snapshot.Index {
+		return node.snapshot.Term
+	}
+	return node.entries[index-node.snapshot.Index-1].Term
+}
+
+func (node *replicaNode) entryAt(index uint64) logEntry {
+	return node.entries[index-node.snapshot.Index-1]
+}
+
+// Steps down on a newer term, the databases going back to the committed entries if the replica was leading
+func (node *replicaNode) becomeFollower(term uint64) {
+	if node.role == roleLeader {
+		Logger.Infof("Replica %s is no longer the leader of the term %d", node.self, node.term)
+		if node.lastApplied > node.commitIndex {
+			node.rollBack()
+		}
+	}
+	node.role = roleFollower
+	if term > node.term {
+		node.term, node.votedFor, node.leader = term, "", ""
+	}
+	node.notify()
+}
+
+// Restores the databases to the committed entries, dropping the writes of the leader which were not replicated
+func (node *replicaNode) rollBack() {
+	tenants := maps.Clone(node.snapshot.Tenants)
+	for index := node.snapshot.Index + 1; index <= node.commitIndex; index++ {
+		maps.Copy(tenants, node.entryAt(index).Tenants)
+	}
+	node.store.restore(tenants)
+	node.lastApplied = node.commitIndex
+}
+
+func (node *replicaNode) startElection() {
+	node.role = roleCandidate
+	node.term++
+	node.votedFor, node.leader = node.self, ""
+	node.resetDeadline()
+	Logger.Infof("Replica %s starting the election of the term %d", node.self, node.term)
+	// Retried on the next timeout
+	if node.saveState() != nil {
+		return
+	}
+
+	votes := 1
+	if votes >= node.quorum() {
+		node.becomeLeader()
+		return
+	}
+	req := voteRequest{Term: node.term, Candidate: node.self, LastIndex: node.lastIndex(), LastTerm: node.termAt(node.lastIndex())}
+	for _, peer := range node.peers {
+		node.workers.Add(1)
+		go func() {
+			defer node.workers.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), node.electionTimeout)
+			defer cancel()
+			res, err := node.transport.requestVote(ctx, peer, req)
+			if err != nil {
+				return
+			}
+
+			node.lock.Lock()
+			defer node.lock.Unlock()
+			if res.Term > node.term {
+				node.becomeFollower(res.Term)
+				return
+			}
+			if res.Granted && node.role == roleCandidate && node.term == req.Term {
+				votes++
+				if votes >= node.quorum() {
+					node.becomeLeader()
+				}
+			}
+		}()
+	}
+}
+
+// Takes the lead, appending the databases of all the tenants for the followers to converge on them
+func (node *replicaNode) becomeLeader() {
+	Logger.Infof("Replica %s elected leader of the term %d", node.self, node.term)
+	node.role, node.leader = roleLeader, node.self
+
+	// The entries of the previous terms are committed along with the first one of this term
+	for index := node.lastApplied + 1; index <= node.lastIndex(); index++ {
+		node.store.apply(node.entryAt(index).Tenants)
+	}
+	node.termStart = node.appendEntry(node.store.capture(nil))
+	if node.saveState() != nil {
+		node.becomeFollower(node.term)
+		return
+	}
+
+	node.nextIndex, node.matchIndex = map[string]uint64{}, map[string]uint64{}
+	node.ackedAt, node.wakeups = map[string]time.Time{}, map[string]chan struct{}{}
+	for _, peer := range node.peers {
+		node.startReplication(peer)
+	}
+	node.advanceCommit()
+	node.notify()
+}
+
+// Appends an entry of the leader, whose databases already hold its content
+func (node *replicaNode) appendEntry(tenants map[string]TenantData) uint64 {
+	index := node.lastIndex() + 1
+	node.entries = append(node.entries, logEntry{Term: node.term, Index: index, Tenants: tenants})
+	node.lastApplied = index
+	for _, wakeup := range node.wakeups {
+		select {
+		case wakeup <- struct{}{}:
+		default:
+		}
+	}
+	return index
+}
+
+// Sends the entries to the peer in the background, as long as the replica leads the current term
+func (node *replicaNode) startReplication(peer string) {
+	if _, started := node.wakeups[peer]; started {
+		return
+	}
+	wakeup := make(chan struct{}, 1)
+	node.wakeups[peer] = wakeup
+	node.nextIndex[peer] = node.lastIndex()
+	term, stop := node.term, node.stop
+	node.workers.Add(1)
+	go func() {
+		defer node.workers.Done()
+		node.replicateTo(peer, term, wakeup, stop)
+	}()
+}
+
+func (node *replicaNode) replicateTo(peer string, term uint64, wakeup chan struct{}, stop <-chan struct{}) {
+	ticker := time.NewTicker(node.heartbeatInterval())
+	defer ticker.Stop()
+
+	for {
+		node.lock.Lock()
+		if node.role != roleLeader || node.term != term {
+			node.lock.Unlock()
+			return
+		}
+		req := node.appendRequestFor(peer)
+		node.lock.Unlock()
+
+		sentAt := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), node.electionTimeout)
+		res, err := node.transport.appendEntries(ctx, peer, req)
+		cancel()
+
+		retry := false
+		if err == nil {
+			node.lock.Lock()
+			retry = node.handleAppendResponse(peer, req, res, sentAt)
+			node.lock.Unlock()
+		}
+		if retry {
+			continue
+		}
+		select {
+		case <-stop:
+			return
+		case <-wakeup:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Next entries of the peer, with the snapshot when it lags behind the compacted ones
+func (node *replicaNode) appendRequestFor(peer string) appendRequest {
+	req := appendRequest{Term: node.term, Leader: node.self, Commit: node.commitIndex}
+	prevIndex := node.nextIndex[peer] - 1
+	if node.nextIndex[peer] <= node.snapshot.Index {
+		snapshot := node.snapshot
+		req.Snapshot = &snapshot
+		prevIndex = node.snapshot.Index
+	}
+	req.PrevIndex, req.PrevTerm = prevIndex, node.termAt(prevIndex)
+	start := prevIndex - node.snapshot.Index
+	// Copied, the log of a former leader being truncated while the request is sent
+	req.Entries = slices.Clone(node.entries[start:min(len(node.entries), int(start)+maxAppendEntries)])
+	return req
+}
+
+// Records the progress of the peer, returns true when the next entries must be sent right away
+func (node *replicaNode) handleAppendResponse(peer string, req appendRequest, res appendResponse, sentAt time.Time) bool {
+	if res.Term > node.term {
+		node.becomeFollower(res.Term)
+		return false
+	}
+	if node.role != roleLeader || node.term != req.Term {
+		return false
+	}
+	if !res.Success {
+		node.nextIndex[peer] = max(1, min(node.nextIndex[peer]-1, res.LastIndex+1))
+		return true
+	}
+	node.matchIndex[peer] = max(node.matchIndex[peer], res.LastIndex)
+	node.nextIndex[peer] = node.matchIndex[peer] + 1
+	node.ackedAt[peer] = sentAt
+	node.advanceCommit()
+	node.notify()
+	return node.nextIndex[peer] <= node.lastIndex()
+}
+
+// Commits the last entry of the term held by a majority
+func (node *replicaNode) advanceCommit() {
+	for index := node.lastIndex(); index > node.commitIndex && node.termAt(index) == node.term; index-- {
+		count := 1
+		for _, peer := range node.peers {
+			if node.matchIndex[peer] >= index {
+				count++
+			}
+		}
+		if count >= node.quorum() {
+			node.commitIndex = index
+			node.settleEffects()
+			node.compact()
+			return
+		}
+	}
+}
+
+// Folds the applied entries into the snapshot when the log grows too long
+func (node *replicaNode) compact() {
+	upTo := min(node.commitIndex, node.lastApplied)
+	if len(node.entries) <= node.maxLogEntries || upTo <= node.snapshot.Index {
+		return
+	}
+	// The entries being sent keep the previous snapshot
+	tenants := maps.Clone(node.snapshot.Tenants)
+	for index := node.snapshot.Index + 1; index <= upTo; index++ {
+		maps.Copy(tenants, node.entryAt(index).Tenants)
+	}
+	snapshot := logEntry{Term: node.termAt(upTo), Index: upTo, Tenants: tenants}
+	node.entries = slices.Clone(node.entries[upTo-node.snapshot.Index:])
+	node.snapshot = snapshot
+}
+
+// Applies the committed entries to the databases of a follower
+func (node *replicaNode) applyCommitted() {
+	for node.lastApplied < node.commitIndex {
+		node.lastApplied++
+		node.store.apply(node.entryAt(node.lastApplied).Tenants)
+	}
+	node.settleEffects()
+	node.compact()
+	node.notify()
+}
+
+// Grants the vote to the first candidate of the term whose log is at least as recent
+func (node *replicaNode) handleVote(req voteRequest) voteResponse {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+
+	if req.Term > node.term {
+		node.becomeFollower(req.Term)
+	}
+	lastTerm := node.termAt(node.lastIndex())
+	upToDate := req.LastTerm > lastTerm || (req.LastTerm == lastTerm && req.LastIndex >= node.lastIndex())
+	granted := req.Term == node.term && (node.votedFor == "" || node.votedFor == req.Candidate) && upToDate
+	if granted {
+		node.votedFor = req.Candidate
+		node.resetDeadline()
+	}
+	// The vote stays given when it cannot be saved, the candidate getting it once it is
+	if node.saveState() != nil {
+		granted = false
+	}
+	return voteResponse{Term: node.term, Granted: granted}
+}
+
+// Appends the entries of the leader after the matching ones, replacing the conflicting ones
+func (node *replicaNode) handleAppend(req appendRequest) (res appendResponse) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+
+	if req.Term < node.term {
+		return appendResponse{Term: node.term}
+	}
+	// The entries held in memory only are not acknowledged, the leader sending them again
+	defer func() {
+		if node.saveState() != nil {
+			res = appendResponse{Term: node.term, LastIndex: node.snapshot.Index}
+		}
+	}()
+	if req.Term > node.term || node.role != roleFollower {
+		node.becomeFollower(req.Term)
+	}
+	if node.leader != req.Leader {
+		node.leader = req.Leader
+		node.notify()
+	}
+	node.resetDeadline()
+
+	if req.Snapshot != nil && req.Snapshot.Index > node.commitIndex {
+		node.snapshot, node.entries = *req.Snapshot, nil
+		node.commitIndex, node.lastApplied = req.Snapshot.Index, req.Snapshot.Index
+		node.store.restore(req.Snapshot.Tenants)
+		node.settleEffects()
+		node.notify()
+	}
+	if req.PrevIndex > node.lastIndex() {
+		return appendResponse{Term: node.term, LastIndex: node.lastIndex()}
+	}
+	if req.PrevIndex >= node.snapshot.Index && node.termAt(req.PrevIndex) != req.PrevTerm {
+		return appendResponse{Term: node.term, LastIndex: max(node.snapshot.Index, req.PrevIndex-1)}
+	}
+
+	for _, entry := range req.Entries {
+		if entry.Index <= node.snapshot.Index {
+			continue
+		}
+		if entry.Index <= node.lastIndex() {
+			if node.termAt(entry.Index) == entry.Term {
+				continue
+			}
+			node.entries = node.entries[:entry.Index-node.snapshot.Index-1]
+		}
+		node.entries = append(node.entries, entry)
+	}
+	node.settleEffects()
+	lastNew := req.PrevIndex + uint64(len(req.Entries))
+	if req.Commit > node.commitIndex {
+		node.commitIndex = max(node.commitIndex, min(req.Commit, lastNew))
+		node.applyCommitted()
+	}
+	return appendResponse{Term: node.term, Success: true, LastIndex: lastNew}
+}
+
+// Error of the requests needing a leader, telling where it is when known
+func (node *replicaNode) notLeaderError() error {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if node.leader == "" {
+		return &APIError{http.StatusServiceUnavailable, "No leader replica elected yet, retry later"}
+	}
+	return &APIError{http.StatusServiceUnavailable, "This replica is not the leader, the leader is " + node.leader}
+}
+
+// Gets the leader, empty when unknown
+func (node *replicaNode) currentLeader() (string, bool) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return node.leader, node.role == roleLeader
+}
+
+// Runs a write on the leader, then replicates the databases of the tenants it returns.
+// The write is answered once committed, the databases going back to the committed entries when it cannot be.
+func (node *replicaNode) write(ctx context.Context, run func() []string) error {
+	node.writeLock.Lock()
+	defer node.writeLock.Unlock()
+
+	if _, isLeader := node.currentLeader(); !isLeader {
+		return node.notLeaderError()
+	}
+	effects := &writeEffects{}
+	node.runningLock.Lock()
+	node.running = effects
+	node.runningLock.Unlock()
+	changed := run()
+	node.runningLock.Lock()
+	node.running = nil
+	node.runningLock.Unlock()
+
+	if len(changed) == 0 {
+		effects.run(effects.committed)
+		return nil
+	}
+	tenants := node.store.capture(changed)
+
+	node.lock.Lock()
+	if node.role != roleLeader {
+		node.rollBack()
+		node.lock.Unlock()
+		effects.run(effects.rolledBack)
+		return node.notLeaderError()
+	}
+	term, index := node.term, node.appendEntry(tenants)
+	if node.saveState() != nil {
+		node.becomeFollower(term)
+		node.lock.Unlock()
+		effects.run(effects.rolledBack)
+		return &APIError{http.StatusServiceUnavailable, "The write could not be saved, retry later"}
+	}
+	effects.term = term
+	node.effects[index] = effects
+	node.advanceCommit()
+	node.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*node.electionTimeout)
+	defer cancel()
+	err := node.waitFor(ctx, func() bool { return node.commitIndex >= index || node.term != term })
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if err != nil || node.termAt(index) != term || node.commitIndex < index {
+		Logger.Warnf("Write %d of the term %d not committed: %v", index, term, err)
+		return &APIError{http.StatusServiceUnavailable, "The write could not be replicated to a majority of the replicas, retry later"}
+	}
+	return nil
+}
+
+// Effects of a write outside of the databases, like its audit entry or events: the committed ones run once
+// its entry is committed, the rolled back ones undo what could not wait, once its entry is dropped
+type writeEffects struct {
+	term       uint64
+	committed  []func()
+	rolledBack []func()
+}
+
+func (effects *writeEffects) run(queue []func()) {
+	for _, effect := range queue {
+		effect()
+	}
+}
+
+// Queues the effect of the running write until it is committed, running it right away without a running write
+func afterCommit(effect func()) {
+	if node := replication; node != nil {
+		node.runningLock.Lock()
+		defer node.runningLock.Unlock()
+		if node.running != nil {
+			node.running.committed = append(node.running.committed, effect)
+			return
+		}
+	}
+	effect()
+}
+
+// Queues the undoing of a change made by the running write, in case it is dropped
+func afterRollBack(undo func()) {
+	if node := replication; node != nil {
+		node.runningLock.Lock()
+		defer node.runningLock.Unlock()
+		if node.running != nil {
+			node.running.rolledBack = append(node.running.rolledBack, undo)
+		}
+	}
+}
+
+// Runs the effects of the writes whose entry is committed or dropped, the lock being held.
+// The entries replaced by a snapshot are neither: their effects are forgotten.
+// The effects must not wait for the replica nor the databases.
+func (node *replicaNode) settleEffects() {
+	for _, index := range slices.Sorted(maps.Keys(node.effects)) {
+		effects := node.effects[index]
+		switch {
+		case index > node.lastIndex() || (index > node.snapshot.Index && node.termAt(index) != effects.term):
+			effects.run(effects.rolledBack)
+		case index <= node.snapshot.Index:
+		case index <= node.commitIndex:
+			effects.run(effects.committed)
+		default:
+			continue
+		}
+		delete(node.effects, index)
+	}
+}
+
+// Waits until the databases hold all the writes committed before the call, unless the stale reads are allowed.
+// The caller holds the read lock of the writes, for the leader not to serve the uncommitted ones.
+func (node *replicaNode) readBarrier(ctx context.Context) error {
+	if node.followerReads {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*node.electionTimeout)
+	defer cancel()
+
+	leader, isLeader := node.currentLeader()
+	if isLeader {
+		_, err := node.leaderReadIndex(ctx)
+		return err
+	}
+	if leader == "" {
+		return node.notLeaderError()
+	}
+	index, err := node.transport.readIndex(ctx, leader)
+	if err != nil {
+		Logger.Warn("Unable to get the read index of the leader: ", err)
+		return node.notLeaderError()
+	}
+	if err := node.waitFor(ctx, func() bool { return node.lastApplied >= index }); err != nil {
+		return node.notLeaderError()
+	}
+	return nil
+}
+
+// Gets the commit index once a majority confirms the leadership, the reads up to it being linearizable
+func (node *replicaNode) leaderReadIndex(ctx context.Context) (uint64, error) {
+	node.lock.Lock()
+	term := node.term
+	node.lock.Unlock()
+
+	// The first entry of the term commits the ones of the previous leaders
+	if err := node.waitFor(ctx, func() bool { return node.term != term || node.commitIndex >= node.termStart }); err != nil {
+		return 0, node.notLeaderError()
+	}
+
+	node.lock.Lock()
+	index, askedAt := node.commitIndex, time.Now()
+	for _, wakeup := range node.wakeups {
+		select {
+		case wakeup <- struct{}{}:
+		default:
+		}
+	}
+	node.lock.Unlock()
+
+	err := node.waitFor(ctx, func() bool {
+		if node.role != roleLeader || node.term != term {
+			return true
+		}
+		count := 1
+		for _, ackedAt := range node.ackedAt {
+			if !ackedAt.Before(askedAt) {
+				count++
+			}
+		}
+		return count >= node.quorum()
+	})
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if err != nil || node.role != roleLeader || node.term != term {
+		return 0, &APIError{http.StatusServiceUnavailable, "The leadership could not be confirmed, retry later"}
+	}
+	return index, nil
+}
+
+// Serves a read once the barrier is passed, serve having to copy the data it reads: the writes wait for it
+func (node *replicaNode) read(ctx context.Context, serve func()) error {
+	node.writeLock.RLock()
+	defer node.writeLock.RUnlock()
+
+	if err := node.readBarrier(ctx); err != nil {
+		return err
+	}
+	serve()
+	return nil
+}
+
+// State of the replica, for the operators
+type ReplicaStatus struct {
+	Self        string   `json:"self"`
+	Role        string   `json:"role"`
+	Term        uint64   `json:"term"`
+	Leader      string   `json:"leader"`
+	Peers       []string `json:"peers"`
+	LastIndex   uint64   `json:"lastIndex"`
+	CommitIndex uint64   `json:"commitIndex"`
+	LastApplied uint64   `json:"lastApplied"`
+}
+
+func (node *replicaNode) status() ReplicaStatus {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	return ReplicaStatus{
+		Self:        node.self,
+		Role:        node.role.String(),
+		Term:        node.term,
+		Leader:      node.leader,
+		Peers:       slices.Clone(node.peers),
+		LastIndex:   node.lastIndex(),
+		CommitIndex: node.commitIndex,
+		LastApplied: node.lastApplied,
+	}
+}
+
+// Store of the databases of the tenants
+type tenantsStore struct{}
+
+func (tenantsStore) capture(tenants []string) map[string]TenantData {
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	captured := map[string]TenantData{}
+	for tenant, db := range tenantDBs {
+		if tenants == nil || slices.Contains(tenants, tenant) {
+			captured[tenant] = db.TenantData.clone()
+		}
+	}
+	return captured
+}
+
+func (tenantsStore) apply(tenants map[string]TenantData) {
+	changes := map[string]catChanges{}
+	dbLock.Lock()
+	for tenant, data := range tenants {
+		lockedTenantDB(tenant).replaceData(data.clone(), changes)
+	}
+	dbLock.Unlock()
+	storeCatChanges(changes)
+}
+
+func (tenantsStore) restore(tenants map[string]TenantData) {
+	snapshot := Snapshot{SchemaVersion: snapshotSchemaVersion, Tenants: map[string]TenantData{}}
+	for tenant, data := range tenants {
+		snapshot.Tenants[tenant] = data.clone()
+	}
+	RestoreSnapshot(snapshot)
+}
@@ -0,0 +1,487 @@
+package catsapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Routes of the messages between the replicas, authenticated by the shared secret
+const (
+	replicationPathPrefix   = "/internal/replication/"
+	replicationSecretHeader = "X-Replication-Secret"
+	// Set on the requests forwarded to the leader, which must not be forwarded again
+	replicationForwardedHeader = "X-Replication-Forwarded"
+)
+
+// Replica of this server, nil when the databases are not replicated
+var replication *replicaNode
+
+// Scheme and client of the calls to the other replicas, HTTPS ones when this server serves TLS
+var (
+	peerScheme = "http"
+	peerClient = &http.Client{}
+)
+
+// Settings of the replication, enabled by the list of the replicas
+type replicationSettings struct {
+	// Addresses of the HTTP servers of the replicas, the "dns:" ones being resolved to all their IPv4 addresses
+	peers []string
+	// Address of this replica among the peers, found from the local addresses when empty
+	self string
+	// Number of replicas, the majority of which commits the writes
+	size            int
+	secret          string
+	electionTimeout time.Duration
+	// Serve the reads from the local databases, which may lag behind the leader, instead of linearizable ones
+	followerReads bool
+	grpcPort      string
+	// File keeping the term, the vote and the log of the replica across its restarts, in the temporary directory by default
+	stateFile string
+}
+
+func loadReplicationSettings() replicationSettings {
+	settings := replicationSettings{
+		self:            getEnv("REPLICATION_SELF", ""),
+		size:            int(getEnvInt64("REPLICATION_SIZE", 0)),
+		secret:          getEnv("REPLICATION_SECRET", ""),
+		electionTimeout: getEnvDuration("REPLICATION_ELECTION_TIMEOUT", time.Second),
+		grpcPort:        getEnv("GRPC_PORT", "9090"),
+		stateFile:       getEnv("REPLICATION_STATE_FILE", ""),
+	}
+	for _, peer := range strings.Split(getEnv("REPLICATION_PEERS", ""), ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			settings.peers = append(settings.peers, peer)
+		}
+	}
+	switch consistency := getEnv("READ_CONSISTENCY", "linearizable"); consistency {
+	case "linearizable":
+	case "follower":
+		settings.followerReads = true
+	default:
+		Logger.Warnf("Invalid value '%s' for READ_CONSISTENCY, expecting linearizable or follower, using linearizable", consistency)
+	}
+	return settings
+}
+
+// Joins the replicas of the settings, if any, the HTTP server of this one listening on the port
+func startReplication(settings replicationSettings, port string, stop <-chan struct{}) error {
+	if len(settings.peers) == 0 {
+		return nil
+	}
+	if settings.secret == "" {
+		return errors.New("REPLICATION_SECRET is required to authenticate the replicas")
+	}
+
+	peers := resolvePeers(settings.peers)
+	self := settings.self
+	if self == "" {
+		if self = findSelf(peers, port); self == "" {
+			return fmt.Errorf("no local address with the port %s among the replicas %v, set REPLICATION_SELF", port, peers)
+		}
+	}
+	discovery := slices.ContainsFunc(settings.peers, isDiscoveredPeer)
+	size := settings.size
+	if size <= 0 {
+		if discovery {
+			return errors.New("REPLICATION_SIZE is required when discovering the replicas through the DNS")
+		}
+		size = len(peers)
+		if !slices.Contains(peers, self) {
+			size++
+		}
+	}
+
+	transport := httpReplicaTransport{client: peerClient, secret: settings.secret}
+	node := newReplicaNode(self, peers, size, settings.electionTimeout, transport, tenantsStore{})
+	node.followerReads, node.secret, node.grpcPort = settings.followerReads, settings.secret, settings.grpcPort
+	node.stateFile = settings.stateFile
+	if node.stateFile == "" {
+		// A file by replica, for the ones sharing a host
+		name := strings.NewReplacer(":", "-", "/", "-", "[", "", "]", "").Replace(self)
+		node.stateFile = filepath.Join(os.TempDir(), "cats-api", "replica-"+name+".json")
+	}
+	if err := node.loadState(); err != nil {
+		return err
+	}
+	replication = node
+
+	go node.Run(stop)
+	if discovery {
+		go runPeersDiscovery(node, settings.peers, stop)
+	}
+	Logger.Infof("Replicating the databases as %s, in a cluster of %d replicas", self, size)
+	return nil
+}
+
+func isDiscoveredPeer(peer string) bool {
+	return strings.HasPrefix(peer, "dns:")
+}
+
+// Lists the addresses of the peers, resolving the discovered ones
+func resolvePeers(peers []string) []string {
+	addresses := []string{}
+	for _, peer := range peers {
+		name, discovered := strings.CutPrefix(peer, "dns:")
+		if !discovered {
+			addresses = append(addresses, peer)
+			continue
+		}
+		host, port, err := net.SplitHostPort(name)
+		if err != nil {
+			Logger.Warnf("Invalid replica '%s', expecting dns:HOST:PORT", peer)
+			continue
+		}
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			Logger.Warnf("Unable to resolve the replicas %s: %v", host, err)
+			continue
+		}
+		for _, ip := range ips {
+			if ip.To4() != nil {
+				addresses = append(addresses, net.JoinHostPort(ip.String(), port))
+			}
+		}
+	}
+	slices.Sort(addresses)
+	return slices.Compact(addresses)
+}
+
+// Finds the address of this replica, listening on the port of a local address
+func findSelf(peers []string, port string) string {
+	localIPs := []net.IP{}
+	if addresses, err := net.InterfaceAddrs(); err == nil {
+		for _, address := range addresses {
+			if ipNet, isIP := address.(*net.IPNet); isIP {
+				localIPs = append(localIPs, ipNet.IP)
+			}
+		}
+	}
+
+	for _, peer := range peers {
+		host, peerPort, err := net.SplitHostPort(peer)
+		if err != nil || peerPort != port {
+			continue
+		}
+		ips, _ := net.LookupIP(host)
+		for _, ip := range ips {
+			if ip.IsLoopback() || slices.ContainsFunc(localIPs, ip.Equal) {
+				return peer
+			}
+		}
+	}
+	return ""
+}
+
+// Resolves the discovered peers periodically, for the replicas started or moved since
+func runPeersDiscovery(node *replicaNode, peers []string, stop <-chan struct{}) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			node.setPeers(resolvePeers(peers))
+		}
+	}
+}
+
+// Sends the messages to the replication routes of the peers
+type httpReplicaTransport struct {
+	client *http.Client
+	secret string
+}
+
+func (transport httpReplicaTransport) call(ctx context.Context, peer, name string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, peerScheme+"://"+peer+replicationPathPrefix+name, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(replicationSecretHeader, transport.secret)
+
+	res, err := transport.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("replica %s answered %s", peer, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+func (transport httpReplicaTransport) requestVote(ctx context.Context, peer string, req voteRequest) (voteResponse, error) {
+	var res voteResponse
+	err := transport.call(ctx, peer, "vote", req, &res)
+	return res, err
+}
+
+func (transport httpReplicaTransport) appendEntries(ctx context.Context, peer string, req appendRequest) (appendResponse, error) {
+	var res appendResponse
+	err := transport.call(ctx, peer, "append", req, &res)
+	return res, err
+}
+
+type readIndexResponse struct {
+	Index uint64 `json:"index"`
+}
+
+func (transport httpReplicaTransport) readIndex(ctx context.Context, peer string) (uint64, error) {
+	var res readIndexResponse
+	err := transport.call(ctx, peer, "read-index", struct{}{}, &res)
+	return res.Index, err
+}
+
+// Serves the messages of the other replicas, and the status of this one
+func (node *replicaNode) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(req.Header.Get(replicationSecretHeader)), []byte(node.secret)) != 1 {
+		writeJSON(res, http.StatusForbidden, "Invalid replication secret")
+		return
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, replicationPathPrefix)
+	if req.Method == http.MethodGet && name == "status" {
+		writeJSON(res, http.StatusOK, node.status())
+		return
+	}
+	if req.Method != http.MethodPost {
+		writeJSON(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	switch name {
+	case "vote":
+		var message voteRequest
+		if err := decoder.Decode(&message); err != nil {
+			writeJSON(res, http.StatusBadRequest, "Invalid vote request")
+			return
+		}
+		writeJSON(res, http.StatusOK, node.handleVote(message))
+	case "append":
+		var message appendRequest
+		if err := decoder.Decode(&message); err != nil {
+			writeJSON(res, http.StatusBadRequest, "Invalid append request")
+			return
+		}
+		writeJSON(res, http.StatusOK, node.handleAppend(message))
+	case "read-index":
+		ctx, cancel := context.WithTimeout(req.Context(), 2*node.electionTimeout)
+		defer cancel()
+		index, err := node.leaderReadIndex(ctx)
+		if err != nil {
+			writeReplicationError(res, err)
+			return
+		}
+		writeJSON(res, http.StatusOK, readIndexResponse{index})
+	default:
+		writeJSON(res, http.StatusNotFound, "Unknown replication message")
+	}
+}
+
+// Routes served by the leader only, their data staying on the replica which runs the writes
+func isLeaderOnlyRoute(path string) bool {
+	_, route, _ := cutTenantPrefix(path)
+	return route == "/api/cats/events" || route == "/api/audit" || strings.HasPrefix(route, "/api/webhooks") ||
+		(strings.HasPrefix(route, "/api/cats/") && strings.HasSuffix(route, "/history"))
+}
+
+// Runs the requests where the replication needs them: the writes and the leader only routes on the leader,
+// forwarded there by the followers, the reads, GraphQL queries included, once the barrier is passed.
+// Also serves the replication routes.
+func withReplication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		node := replication
+		if node == nil || req.Method == http.MethodOptions {
+			next.ServeHTTP(res, req)
+			return
+		}
+		if strings.HasPrefix(req.URL.Path, replicationPathPrefix) {
+			node.ServeHTTP(res, req)
+			return
+		}
+
+		isWrite := req.Method != http.MethodGet && req.Method != http.MethodHead && !isGraphqlQueryRequest(req)
+		if isWrite || isLeaderOnlyRoute(req.URL.Path) {
+			if _, isLeader := node.currentLeader(); !isLeader {
+				node.forward(res, req)
+			} else if isWrite {
+				node.serveWrite(res, req, next)
+			} else {
+				next.ServeHTTP(res, req)
+			}
+			return
+		}
+		// The response is sent once the writes are no longer held, a slow client not holding them
+		buffered := &bufferedResponse{header: http.Header{}}
+		if err := node.read(req.Context(), func() { next.ServeHTTP(buffered, req) }); err != nil {
+			writeReplicationError(res, err)
+			return
+		}
+		buffered.sendTo(res)
+	})
+}
+
+func writeReplicationError(res http.ResponseWriter, err error) {
+	code, message := errorResponse(err)
+	res.Header().Set("Retry-After", "1")
+	writeJSON(res, code, message)
+}
+
+// Proxies the request to the leader
+func (node *replicaNode) forward(res http.ResponseWriter, req *http.Request) {
+	leader, _ := node.currentLeader()
+	if leader == "" || req.Header.Get(replicationForwardedHeader) != "" {
+		// The leader changed while the request was forwarded
+		writeReplicationError(res, node.notLeaderError())
+		return
+	}
+
+	Logger.Debugf("Forwarding '%s %s' to the leader %s", req.Method, req.RequestURI, leader)
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(proxyReq *httputil.ProxyRequest) {
+			proxyReq.SetURL(&url.URL{Scheme: peerScheme, Host: leader})
+			proxyReq.Out.Host = proxyReq.In.Host
+			proxyReq.Out.Header["X-Forwarded-For"] = proxyReq.In.Header["X-Forwarded-For"]
+			proxyReq.SetXForwarded()
+			proxyReq.Out.Header.Set(replicationForwardedHeader, node.self)
+		},
+		Transport: peerClient.Transport,
+		// The event streams are sent as they come
+		FlushInterval: -1,
+		ErrorHandler: func(res http.ResponseWriter, req *http.Request, err error) {
+			Logger.Warnf("Unable to forward '%s %s' to the leader %s: %v", req.Method, req.RequestURI, leader, err)
+			writeReplicationError(res, &APIError{http.StatusServiceUnavailable, "The leader replica is unreachable, retry later"})
+		},
+	}
+	proxy.ServeHTTP(res, req)
+}
+
+type replicatedWriteKey struct{}
+
+// Tenant whose databases are changed by a write of the leader, noted by withTenant
+type replicatedWrite struct {
+	tenant string
+}
+
+func noteReplicatedTenant(ctx context.Context, tenant string) {
+	if write, found := ctx.Value(replicatedWriteKey{}).(*replicatedWrite); found {
+		write.tenant = tenant
+	}
+}
+
+// Runs the write on the leader, its response being sent once the write is committed
+func (node *replicaNode) serveWrite(res http.ResponseWriter, req *http.Request, next http.Handler) {
+	// The body is read before holding the writes, so a slow client only delays its own
+	if !readWriteBody(res, req) {
+		return
+	}
+	write := &replicatedWrite{}
+	req = req.WithContext(context.WithValue(req.Context(), replicatedWriteKey{}, write))
+	buffered := &bufferedResponse{header: http.Header{}}
+
+	err := node.write(req.Context(), func() []string {
+		next.ServeHTTP(buffered, req)
+		if buffered.code >= http.StatusBadRequest || write.tenant == "" {
+			return nil
+		}
+		return []string{write.tenant}
+	})
+	if err != nil {
+		writeReplicationError(res, err)
+		return
+	}
+	buffered.sendTo(res)
+}
+
+// Time given to the clients to send the body of a write
+var writeBodyTimeout = getEnvDuration("WRITE_BODY_TIMEOUT", 30*time.Second)
+
+// Largest body of the writes, the photos being the largest ones: the routes still apply their own limits
+func maxWriteBodySize() int64 {
+	return max(maxBodySize, graphqlMaxBodySize, maxPhotoSize+(1<<20))
+}
+
+// Replaces the body of the request by its content, answering the error when it cannot be read in time
+func readWriteBody(res http.ResponseWriter, req *http.Request) bool {
+	controller := http.NewResponseController(res)
+	controller.SetReadDeadline(time.Now().Add(writeBodyTimeout))
+	body, err := io.ReadAll(http.MaxBytesReader(res, req.Body, maxWriteBodySize()))
+	controller.SetReadDeadline(time.Time{})
+	if err != nil {
+		code, message := http.StatusBadRequest, "Unable to read the request body"
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			code, message = http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body too large, the limit is %d bytes", maxBytesErr.Limit)
+		}
+		Logger.Info("Rejecting the write: ", err)
+		writeJSON(res, code, message)
+		return false
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return true
+}
+
+// Response kept until the write is committed, or the read done
+type bufferedResponse struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (buffered *bufferedResponse) Header() http.Header {
+	return buffered.header
+}
+
+func (buffered *bufferedResponse) WriteHeader(code int) {
+	if buffered.code == 0 {
+		buffered.code = code
+	}
+}
+
+func (buffered *bufferedResponse) Write(data []byte) (int, error) {
+	buffered.WriteHeader(http.StatusOK)
+	return buffered.body.Write(data)
+}
+
+func (buffered *bufferedResponse) sendTo(res http.ResponseWriter) {
+	maps.Copy(res.Header(), buffered.header)
+	buffered.WriteHeader(http.StatusOK)
+	res.WriteHeader(buffered.code)
+	res.Write(buffered.body.Bytes())
+}
+
+// Runs a write of a background job, on the leader only when replicating
+func runReplicatedJob(run func() []string) {
+	if replication == nil {
+		run()
+		return
+	}
+	if _, isLeader := replication.currentLeader(); !isLeader {
+		return
+	}
+	if err := replication.write(context.Background(), run); err != nil {
+		Logger.Warn("Unable to replicate the job: ", err)
+	}
+}
@@ -0,0 +1,497 @@
+package catsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// Network of the replicas of a test, the messages going through JSON like with the HTTP transport
+type memoryNetwork struct {
+	lock  sync.Mutex
+	nodes map[string]*replicaNode
+	down  map[string]bool
+}
+
+// Transport of one replica of the network
+type memoryTransport struct {
+	network *memoryNetwork
+	from    string
+}
+
+func (transport memoryTransport) target(peer string) (*replicaNode, error) {
+	transport.network.lock.Lock()
+	defer transport.network.lock.Unlock()
+	if transport.network.down[transport.from] || transport.network.down[peer] {
+		return nil, errors.New("replica unreachable")
+	}
+	return transport.network.nodes[peer], nil
+}
+
+func viaJSON[T any](value T) T {
+	data, _ := json.Marshal(value)
+	var decoded T
+	json.Unmarshal(data, &decoded)
+	return decoded
+}
+
+func (transport memoryTransport) requestVote(ctx context.Context, peer string, req voteRequest) (voteResponse, error) {
+	node, err := transport.target(peer)
+	if err != nil {
+		return voteResponse{}, err
+	}
+	return viaJSON(node.handleVote(viaJSON(req))), nil
+}
+
+func (transport memoryTransport) appendEntries(ctx context.Context, peer string, req appendRequest) (appendResponse, error) {
+	node, err := transport.target(peer)
+	if err != nil {
+		return appendResponse{}, err
+	}
+	return viaJSON(node.handleAppend(viaJSON(req))), nil
+}
+
+func (transport memoryTransport) readIndex(ctx context.Context, peer string) (uint64, error) {
+	node, err := transport.target(peer)
+	if err != nil {
+		return 0, err
+	}
+	return node.leaderReadIndex(ctx)
+}
+
+// Databases of a replica of the tests
+type memoryStore struct {
+	lock    sync.Mutex
+	tenants map[string]TenantData
+}
+
+func (store *memoryStore) capture(tenants []string) map[string]TenantData {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	captured := map[string]TenantData{}
+	for tenant, data := range store.tenants {
+		if tenants == nil || slices.Contains(tenants, tenant) {
+			captured[tenant] = data.clone()
+		}
+	}
+	return captured
+}
+
+func (store *memoryStore) apply(tenants map[string]TenantData) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	for tenant, data := range tenants {
+		store.tenants[tenant] = data.clone()
+	}
+}
+
+func (store *memoryStore) restore(tenants map[string]TenantData) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	store.tenants = map[string]TenantData{}
+	for tenant, data := range tenants {
+		store.tenants[tenant] = data.clone()
+	}
+}
+
+func (store *memoryStore) addCat(catID string) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	data := store.tenants[DefaultTenant].orEmpty()
+	data.Cats[catID] = Cat{ID: catID, Name: "Cat " + catID}
+	store.tenants[DefaultTenant] = data
+}
+
+func (store *memoryStore) hasCat(catID string) bool {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	_, found := store.tenants[DefaultTenant].Cats[catID]
+	return found
+}
+
+type testReplica struct {
+	node  *replicaNode
+	store *memoryStore
+}
+
+// Starts a cluster of replicas, each one with its own databases, stopped at the end of the test
+func startTestCluster(t *testing.T, size, maxLogEntries int) (*memoryNetwork, map[string]testReplica) {
+	network := &memoryNetwork{nodes: map[string]*replicaNode{}, down: map[string]bool{}}
+	names := []string{}
+	for i := range size {
+		names = append(names, fmt.Sprintf("replica-%d", i+1))
+	}
+
+	replicas := map[string]testReplica{}
+	for _, name := range names {
+		store := &memoryStore{tenants: map[string]TenantData{}}
+		node := newReplicaNode(name, names, size, 50*time.Millisecond, memoryTransport{network, name}, store)
+		node.maxLogEntries = maxLogEntries
+		network.nodes[name] = node
+		replicas[name] = testReplica{node, store}
+	}
+
+	stop := make(chan struct{})
+	var running sync.WaitGroup
+	for _, replica := range replicas {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			replica.node.Run(stop)
+		}()
+	}
+	t.Cleanup(func() {
+		close(stop)
+		running.Wait()
+	})
+	return network, replicas
+}
+
+func (network *memoryNetwork) setDown(name string, down bool) {
+	network.lock.Lock()
+	defer network.lock.Unlock()
+	network.down[name] = down
+}
+
+// Waits for a leader known by all the reachable replicas
+func waitForLeader(t *testing.T, network *memoryNetwork, replicas map[string]testReplica) testReplica {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var leader testReplica
+		leaders := map[string]bool{}
+		for name, replica := range replicas {
+			network.lock.Lock()
+			down := network.down[name]
+			network.lock.Unlock()
+			if down {
+				continue
+			}
+			known, isLeader := replica.node.currentLeader()
+			if isLeader {
+				leader = replica
+			}
+			leaders[known] = true
+		}
+		if leader.node != nil && len(leaders) == 1 {
+			return leader
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("No leader elected")
+	return testReplica{}
+}
+
+func writeTestCat(replica testReplica, catID string) error {
+	return replica.node.write(context.Background(), func() []string {
+		replica.store.addCat(catID)
+		return []string{DefaultTenant}
+	})
+}
+
+// Test that the writes of the leader are read by all the replicas, and refused by the followers
+func TestReplicationWrites(t *testing.T) {
+	network, replicas := startTestCluster(t, 3, defaultMaxLogEntries)
+	leader := waitForLeader(t, network, replicas)
+
+	if err := writeTestCat(leader, "c1"); err != nil {
+		t.Fatal(err)
+	}
+	for name, replica := range replicas {
+		if err := replica.node.read(context.Background(), func() {}); err != nil || !replica.store.hasCat("c1") {
+			t.Errorf("Expected %s to read the committed cat, got %v", name, err)
+		}
+		if replica.node == leader.node {
+			continue
+		}
+		err := writeTestCat(replica, "c2")
+		if err == nil || !strings.Contains(err.Error(), "not the leader, the leader is "+leader.node.self) {
+			t.Errorf("Expected %s to refuse the write, got %v", name, err)
+		}
+	}
+}
+
+// Test the election of a new leader, and that the former one drops its writes which were not committed
+func TestReplicationFailover(t *testing.T) {
+	network, replicas := startTestCluster(t, 3, defaultMaxLogEntries)
+	former := waitForLeader(t, network, replicas)
+	writeTestCat(former, "c1")
+
+	network.setDown(former.node.self, true)
+	if err := writeTestCat(former, "lost"); err == nil {
+		t.Fatal("Expected the isolated leader to fail to commit")
+	}
+	leader := waitForLeader(t, network, replicas)
+	if err := writeTestCat(leader, "c2"); err != nil {
+		t.Fatal(err)
+	}
+
+	network.setDown(former.node.self, false)
+	deadline := time.Now().Add(5 * time.Second)
+	for !former.store.hasCat("c2") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !former.store.hasCat("c1") || !former.store.hasCat("c2") || former.store.hasCat("lost") {
+		t.Errorf("Expected the former leader to follow the new one, got %v", slices.Sorted(maps.Keys(former.store.capture(nil)[DefaultTenant].Cats)))
+	}
+	if status := former.node.status(); status.Role != "follower" || status.Leader != leader.node.self {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+// Test that the effects of the writes wait for their commit, and that the ones of the dropped writes are undone
+func TestReplicationEffects(t *testing.T) {
+	network, replicas := startTestCluster(t, 3, defaultMaxLogEntries)
+	former := waitForLeader(t, network, replicas)
+	replication = former.node
+	t.Cleanup(func() { replication = nil })
+
+	var effects sync.Map
+	writeWithEffects := func(replica testReplica, catID string) error {
+		return replica.node.write(context.Background(), func() []string {
+			replica.store.addCat(catID)
+			afterCommit(func() { effects.Store(catID+" committed", true) })
+			afterRollBack(func() { effects.Store(catID+" rolled back", true) })
+			return []string{DefaultTenant}
+		})
+	}
+	hasEffect := func(name string) bool {
+		_, found := effects.Load(name)
+		return found
+	}
+
+	if err := writeWithEffects(former, "c1"); err != nil || !hasEffect("c1 committed") {
+		t.Fatalf("Expected the effect of the committed write, got %v", err)
+	}
+	network.setDown(former.node.self, true)
+	if err := writeWithEffects(former, "lost"); err == nil || hasEffect("lost committed") || hasEffect("lost rolled back") {
+		t.Fatalf("Expected the effects to wait for the fate of the write, got %v", err)
+	}
+
+	leader := waitForLeader(t, network, replicas)
+	writeTestCat(leader, "c2")
+	network.setDown(former.node.self, false)
+	deadline := time.Now().Add(5 * time.Second)
+	for !hasEffect("lost rolled back") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !hasEffect("lost rolled back") || hasEffect("lost committed") || hasEffect("c1 rolled back") {
+		t.Error("Expected the dropped write to be undone")
+	}
+}
+
+// Test that a replica lagging behind the compacted entries catches up with the snapshot
+func TestReplicationSnapshot(t *testing.T) {
+	network, replicas := startTestCluster(t, 3, 4)
+	leader := waitForLeader(t, network, replicas)
+	var lagging testReplica
+	for _, replica := range replicas {
+		if replica.node != leader.node {
+			lagging = replica
+			break
+		}
+	}
+
+	network.setDown(lagging.node.self, true)
+	for i := range 10 {
+		if err := writeTestCat(leader, fmt.Sprintf("c%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	network.setDown(lagging.node.self, false)
+	// The lagging replica may have started elections while it was unreachable
+	leader = waitForLeader(t, network, replicas)
+
+	if err := lagging.node.read(context.Background(), func() {}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 10 {
+		if !lagging.store.hasCat(fmt.Sprintf("c%d", i)) {
+			t.Errorf("Expected the cat c%d on the lagging replica", i)
+		}
+	}
+	if status := lagging.node.status(); status.LastApplied != leader.node.status().CommitIndex {
+		t.Errorf("Expected the lagging replica to apply all the entries, got %+v", status)
+	}
+}
+
+// Sets the replica of the HTTP tests
+func setupReplicationTest(t *testing.T, node *replicaNode) http.Handler {
+	app := setupOwnerTest(t)
+	t.Cleanup(func() { replication = nil })
+	node.secret = "test-secret"
+	replication = node
+	return app
+}
+
+// Test the writes of a leader alone in its cluster, and the replication routes
+func TestReplicationHTTPLeader(t *testing.T) {
+	node := newReplicaNode("localhost:8080", nil, 1, 50*time.Millisecond, memoryTransport{&memoryNetwork{}, "localhost:8080"}, tenantsStore{})
+	app := setupReplicationTest(t, node)
+	stop := make(chan struct{})
+	defer close(stop)
+	go node.Run(stop)
+	for deadline := time.Now().Add(5 * time.Second); node.status().Role != "leader" && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var cat CatV2
+	if code := doJSON(t, app, "POST", "/api/v2/cats", `{"name": "Felix"}`, &cat); code != http.StatusCreated {
+		t.Fatalf("Expected the cat to be created, got %d", code)
+	}
+	if code := doJSON(t, app, "GET", "/api/v2/cats/"+cat.ID, "", &cat); code != http.StatusOK || cat.Name != "Felix" {
+		t.Errorf("Expected the created cat, got %d %+v", code, cat)
+	}
+	if status := node.status(); status.CommitIndex < 2 || status.LastApplied != status.CommitIndex {
+		t.Errorf("Expected the write to be committed, got %+v", status)
+	}
+
+	if code := doJSON(t, app, "GET", "/internal/replication/status", "", nil); code != http.StatusForbidden {
+		t.Errorf("Expected the replication routes to require the secret, got %d", code)
+	}
+	req := httptest.NewRequest("GET", "/internal/replication/status", nil)
+	req.Header.Set(replicationSecretHeader, "test-secret")
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"role":"leader"`) {
+		t.Errorf("Unexpected status %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+// Test that the body of a write is read before holding the writes, a slow or large upload not blocking them
+func TestReplicationHTTPWriteBody(t *testing.T) {
+	node := newReplicaNode("localhost:8080", nil, 1, 50*time.Millisecond, memoryTransport{&memoryNetwork{}, "localhost:8080"}, tenantsStore{})
+	app := setupReplicationTest(t, node)
+	stop := make(chan struct{})
+	defer close(stop)
+	go node.Run(stop)
+	for deadline := time.Now().Add(5 * time.Second); node.status().Role != "leader" && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	serve := func(body io.Reader) int {
+		done := make(chan int)
+		go func() {
+			rr := httptest.NewRecorder()
+			app.ServeHTTP(rr, httptest.NewRequest("POST", "/api/v2/cats", body))
+			done <- rr.Code
+		}()
+		select {
+		case code := <-done:
+			return code
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the write to be answered without holding the writes")
+			return 0
+		}
+	}
+
+	node.writeLock.Lock()
+	if code := serve(strings.NewReader(strings.Repeat("x", int(maxWriteBodySize())+1))); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code %d for a too large body, got %d", http.StatusRequestEntityTooLarge, code)
+	}
+	if code := serve(iotest.ErrReader(errors.New("connection reset"))); code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a failed body, got %d", http.StatusBadRequest, code)
+	}
+	node.writeLock.Unlock()
+
+	if code := serve(strings.NewReader(`{"name": "Felix"}`)); code != http.StatusCreated {
+		t.Errorf("Expected the cat to be created, got %d", code)
+	}
+}
+
+// Test that a restarted replica keeps its vote and its log, so it neither votes twice in a term nor elects a
+// candidate missing the entries it acknowledged
+func TestReplicaStateRestart(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "replica-state.json")
+	restart := func() *replicaNode {
+		store := &memoryStore{tenants: map[string]TenantData{}}
+		peers := []string{"replica-1", "replica-2", "replica-3"}
+		node := newReplicaNode("replica-1", peers, 3, time.Minute, memoryTransport{&memoryNetwork{}, "replica-1"}, store)
+		node.stateFile = stateFile
+		if err := node.loadState(); err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+
+	node := restart()
+	if res := node.handleVote(voteRequest{Term: 5, Candidate: "replica-2"}); !res.Granted {
+		t.Fatalf("Expected the vote to be granted, got %+v", res)
+	}
+	entry := logEntry{Term: 5, Index: 1, Tenants: map[string]TenantData{DefaultTenant: TenantData{}.orEmpty()}}
+	if res := node.handleAppend(appendRequest{Term: 5, Leader: "replica-2", Entries: []logEntry{entry}}); !res.Success {
+		t.Fatalf("Expected the entry to be appended, got %+v", res)
+	}
+
+	node = restart()
+	if status := node.status(); status.Term != 5 || status.LastIndex != 1 {
+		t.Errorf("Expected the term and the log to survive the restart, got %+v", status)
+	}
+	if res := node.handleVote(voteRequest{Term: 5, Candidate: "replica-3", LastIndex: 1, LastTerm: 5}); res.Granted {
+		t.Error("Expected the restarted replica not to vote twice in the term")
+	}
+	if res := node.handleVote(voteRequest{Term: 5, Candidate: "replica-2", LastIndex: 1, LastTerm: 5}); !res.Granted {
+		t.Error("Expected the restarted replica to vote again for its candidate")
+	}
+	if res := node.handleVote(voteRequest{Term: 6, Candidate: "replica-3"}); res.Granted {
+		t.Error("Expected the restarted replica not to elect a candidate missing its entries")
+	}
+}
+
+// Test the forwarding of the writes of a follower to the leader
+func TestReplicationHTTPForwarding(t *testing.T) {
+	var forwardedBy string
+	leaderServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		forwardedBy = req.Header.Get(replicationForwardedHeader)
+		writeJSON(res, http.StatusCreated, "forwarded "+req.URL.Path)
+	}))
+	defer leaderServer.Close()
+	leaderAddress := strings.TrimPrefix(leaderServer.URL, "http://")
+
+	node := newReplicaNode("follower:8080", []string{leaderAddress}, 2, time.Minute, memoryTransport{&memoryNetwork{}, "follower:8080"}, tenantsStore{})
+	node.leader = leaderAddress
+	app := setupReplicationTest(t, node)
+
+	var body string
+	if code := doJSON(t, app, "POST", "/tenants/default/api/v2/cats", `{"name": "Felix"}`, &body); code != http.StatusCreated || body != "forwarded /tenants/default/api/v2/cats" {
+		t.Errorf("Expected the write to be forwarded, got %d %q", code, body)
+	}
+	if forwardedBy != "follower:8080" {
+		t.Errorf("Expected the forwarding header, got %q", forwardedBy)
+	}
+	if len(testDB().Cats) != 0 {
+		t.Error("Expected the follower not to run the write")
+	}
+
+	// The GraphQL queries are read by the follower, the mutations forwarded
+	testDB().Cats["id1"] = Cat{Name: "Toto"}
+	node.followerReads = true
+	var result map[string]any
+	if code := doJSON(t, app, "POST", "/graphql", `{"query": "query Cat { cat(id: \"id1\") { name } }"}`, &result); code != http.StatusOK || !strings.Contains(fmt.Sprint(result), "Toto") {
+		t.Errorf("Expected the query to be read by the follower, got %d %v", code, result)
+	}
+	mutation := `{"query": "query Cat { cat(id: \"id1\") { name } } mutation Delete { deleteCat(id: \"id1\") }", "operationName": "Delete"}`
+	if code := doJSON(t, app, "POST", "/graphql", mutation, &body); code != http.StatusCreated || body != "forwarded /graphql" {
+		t.Errorf("Expected the mutation to be forwarded, got %d %q", code, body)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v2/cats", strings.NewReader(`{"name": "Felix"}`))
+	req.Header.Set(replicationForwardedHeader, "other:8080")
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected a forwarded request not to be forwarded again, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package catsapi
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Inline scripts, without a src attribute
+var inlineScriptPattern = regexp.MustCompile(`(?s)<script>(.*?)</script>`)
+
+// Hashes of the inline scripts of the embedded swagger-ui page, allowed by the CSP
+func swaggerScriptHashes() []string {
+	page, err := content.ReadFile("swagger-ui/index.html")
+	if err != nil {
+		Logger.Error("Unable to read the swagger-ui page: ", err)
+		return nil
+	}
+	hashes := []string{}
+	for _, match := range inlineScriptPattern.FindAllSubmatch(page, -1) {
+		sum := sha256.Sum256(match[1])
+		hashes = append(hashes, "'sha256-"+base64.StdEncoding.EncodeToString(sum[:])+"'")
+	}
+	return hashes
+}
+
+// Content Security Policy of the responses, only the API itself being reachable from its pages.
+// swagger-ui sets inline styles, and shows data: images.
+func defaultContentSecurityPolicy() string {
+	return strings.Join([]string{
+		"default-src 'self'",
+		"script-src 'self' " + strings.Join(swaggerScriptHashes(), " "),
+		"style-src 'self' 'unsafe-inline'",
+		"img-src 'self' data:",
+		"connect-src 'self'",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors 'none'",
+	}, "; ")
+}
+
+// Adds the security headers to every response
+func withSecurityHeaders(next http.Handler) http.Handler {
+	csp := getEnv("CONTENT_SECURITY_POLICY", defaultContentSecurityPolicy())
+	referrerPolicy := getEnv("REFERRER_POLICY", "no-referrer")
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		header := res.Header()
+		header.Set("Content-Security-Policy", csp)
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Referrer-Policy", referrerPolicy)
+		// For the browsers ignoring frame-ancestors
+		header.Set("X-Frame-Options", "DENY")
+		next.ServeHTTP(res, req)
+	})
+}
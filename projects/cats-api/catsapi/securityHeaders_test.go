@@ -0,0 +1,49 @@
+package catsapi
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test the security headers, and the CSP allowing the swagger-ui inline script
+func TestSecurityHeaders(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	for _, path := range []string{"/api/cats", "/swagger/", "/"} {
+		t.Run(path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			app.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+
+			csp := rr.Header().Get("Content-Security-Policy")
+			if !strings.Contains(csp, "frame-ancestors 'none'") || !strings.Contains(csp, "default-src 'self'") {
+				t.Errorf("Unexpected CSP: %q", csp)
+			}
+			if rr.Header().Get("X-Content-Type-Options") != "nosniff" || rr.Header().Get("Referrer-Policy") != "no-referrer" {
+				t.Errorf("Unexpected security headers: %v", rr.Header())
+			}
+		})
+	}
+
+	page, _ := content.ReadFile("swagger-ui/index.html")
+	script := inlineScriptPattern.FindSubmatch(page)[1]
+	sum := sha256.Sum256(script)
+	hash := "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+	if csp := defaultContentSecurityPolicy(); !strings.Contains(csp, "script-src 'self' "+hash) {
+		t.Errorf("Expected the CSP to allow the swagger-ui script %s, got %q", hash, csp)
+	}
+}
+
+// Test the CSP set from the environment
+func TestContentSecurityPolicyOverride(t *testing.T) {
+	t.Setenv("CONTENT_SECURITY_POLICY", "default-src 'none'")
+	app := setupOwnerTest(t)
+
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest("GET", "/api/cats", nil))
+	if csp := rr.Header().Get("Content-Security-Policy"); csp != "default-src 'none'" {
+		t.Errorf("Unexpected CSP: %q", csp)
+	}
+}
@@ -0,0 +1,181 @@
+package catsapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Version of the snapshots layout, bumped with a migration when the stored entities change
+const snapshotSchemaVersion = 3
+
+// Snapshot is the content of the databases of all the tenants, persisted as JSON in the data file.
+// The photo files stay in the blob store.
+type Snapshot struct {
+	SchemaVersion int                   `json:"schemaVersion"`
+	Tenants       map[string]TenantData `json:"tenants"`
+
+	// Databases of the schema versions 1 and 2, moved to the default tenant by the migration 3
+	Cats    map[string]Cat    `json:"cats,omitempty"`
+	Owners  map[string]Owner  `json:"owners,omitempty"`
+	Records map[string]Record `json:"records,omitempty"`
+	Photos  map[string]Photo  `json:"photos,omitempty"`
+}
+
+// Changes a snapshot of the previous schema version into the next one
+type snapshotMigration struct {
+	description string
+	migrate     func(snapshot *Snapshot)
+}
+
+// Migrations by the schema version they lead to, the snapshots without a version being at version 1
+var snapshotMigrations = map[int]snapshotMigration{
+	2: {"Add the creation and update times of the cats, for the v2 API", func(snapshot *Snapshot) {
+		now := time.Now().UTC()
+		for catID, cat := range snapshot.Cats {
+			if cat.CreatedAt == nil {
+				cat.CreatedAt = &now
+			}
+			if cat.UpdatedAt == nil {
+				cat.UpdatedAt = cat.CreatedAt
+			}
+			snapshot.Cats[catID] = cat
+		}
+	}},
+	3: {"Move the databases to the default tenant, for the multi-tenant deployments", func(snapshot *Snapshot) {
+		snapshot.Tenants = map[string]TenantData{
+			DefaultTenant: TenantData{Cats: snapshot.Cats, Owners: snapshot.Owners, Records: snapshot.Records, Photos: snapshot.Photos}.orEmpty(),
+		}
+		snapshot.Cats, snapshot.Owners, snapshot.Records, snapshot.Photos = nil, nil, nil, nil
+	}},
+}
+
+// Reads the snapshot of the data file, found is false when the file does not exist yet
+func readSnapshot(path string) (snapshot Snapshot, found bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Snapshot{}, false, nil
+	}
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, true, fmt.Errorf("invalid data file %s: %w", path, err)
+	}
+	if snapshot.SchemaVersion == 0 {
+		snapshot.SchemaVersion = 1
+	}
+	return snapshot, true, nil
+}
+
+// Writes the snapshot as indented JSON
+func encodeSnapshot(out io.Writer, snapshot Snapshot) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// Writes the snapshot to the data file, replacing it atomically
+func writeSnapshot(path string, snapshot Snapshot) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if err := encodeSnapshot(temp, snapshot); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Brings the snapshot to the current schema version, returning the applied migrations
+func migrateSnapshot(snapshot *Snapshot) ([]string, error) {
+	if snapshot.SchemaVersion > snapshotSchemaVersion {
+		return nil, fmt.Errorf("schema version %d is newer than the supported %d", snapshot.SchemaVersion, snapshotSchemaVersion)
+	}
+	if snapshot.Cats == nil {
+		snapshot.Cats = map[string]Cat{}
+	}
+	applied := []string{}
+	for version := snapshot.SchemaVersion + 1; version <= snapshotSchemaVersion; version++ {
+		migration := snapshotMigrations[version]
+		migration.migrate(snapshot)
+		snapshot.SchemaVersion = version
+		applied = append(applied, fmt.Sprintf("%d: %s", version, migration.description))
+	}
+	return applied, nil
+}
+
+// TakeSnapshot copies the databases of all the tenants
+func TakeSnapshot() Snapshot {
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	snapshot := Snapshot{SchemaVersion: snapshotSchemaVersion, Tenants: map[string]TenantData{}}
+	for tenant, db := range tenantDBs {
+		snapshot.Tenants[tenant] = db.TenantData.clone()
+	}
+	return snapshot
+}
+
+// RestoreSnapshot replaces the databases of all the tenants by the snapshot, which must be at the current
+// schema version. The tenants missing from the snapshot are emptied.
+func RestoreSnapshot(snapshot Snapshot) {
+	changes := map[string]catChanges{}
+	dbLock.Lock()
+	for tenant := range snapshot.Tenants {
+		lockedTenantDB(tenant)
+	}
+	for tenant, db := range tenantDBs {
+		db.replaceData(snapshot.Tenants[tenant].orEmpty(), changes)
+	}
+	dbLock.Unlock()
+	storeCatChanges(changes)
+}
+
+// ResetStore empties the databases of all the tenants along with their audit trails and events, and removes
+// the webhooks, for the servers of a test to start from a clean state. No request must be running.
+func ResetStore() {
+	changes := map[string]catChanges{}
+	dbLock.Lock()
+	for _, db := range tenantDBs {
+		db.replaceData(TenantData{}.orEmpty(), changes)
+		db.audit = newMemoryAuditStore(auditMaxEntries)
+		db.events = newEventBroker(eventsReplaySize, eventsBufferSize)
+	}
+	dbLock.Unlock()
+	storeCatChanges(changes)
+	webhooks.Reset()
+}
+
+func emptyIfNil[V any](aMap map[string]V) map[string]V {
+	if aMap == nil {
+		return map[string]V{}
+	}
+	return aMap
+}
+
+// Loads the data file into the databases, keeping the initial ones when it does not exist yet.
+// A data file at an older schema version must be migrated first.
+func loadDataFile(path string) error {
+	snapshot, found, err := readSnapshot(path)
+	if err != nil || !found {
+		return err
+	}
+	if snapshot.SchemaVersion != snapshotSchemaVersion {
+		return fmt.Errorf("the data file %s is at schema version %d, expecting %d: run the migrate command",
+			path, snapshot.SchemaVersion, snapshotSchemaVersion)
+	}
+	RestoreSnapshot(snapshot)
+	return nil
+}
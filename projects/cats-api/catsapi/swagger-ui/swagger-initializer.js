@@ -3,7 +3,11 @@ window.onload = function() {
 
   // the following lines will be replaced by docker/configurator, when it runs in a docker-container
   window.ui = SwaggerUIBundle({
-    url: "./openapi.json",
+    urls: [
+      { url: "./openapi.json", name: "v1" },
+      { url: "./openapi-v2.json", name: "v2" }
+    ],
+    "urls.primaryName": "v2",
     dom_id: '#swagger-ui',
     deepLinking: true,
     presets: [
@@ -0,0 +1,307 @@
+package catsapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTenant owns the data of the requests which do not tell their tenant, and of the single tenant deployments
+const DefaultTenant = "default"
+
+// Header telling the tenant of a request, the other ways being a token claim or a /tenants/{tenant} path prefix
+const tenantHeader = "X-Tenant-ID"
+
+const tenantPathPrefix = "/tenants/"
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// TenantData is the content of the databases of a tenant
+type TenantData struct {
+	Cats    map[string]Cat    `json:"cats"`
+	Owners  map[string]Owner  `json:"owners"`
+	Records map[string]Record `json:"records"`
+	Photos  map[string]Photo  `json:"photos"`
+}
+
+// Databases of a tenant, guarded by dbLock, with its own audit trail and events.
+// The handlers only reach the databases of the tenant of their request, so no tenant ever sees the data of another one.
+type tenantDB struct {
+	tenant string
+	TenantData
+	audit  AuditStore
+	events *eventBroker
+}
+
+// Databases by tenant, guarded by dbLock. They are never removed, the requests keeping the ones of their tenant.
+var tenantDBs = map[string]*tenantDB{}
+
+// Gets the databases of the tenant, creating them on its first use
+func openTenantDB(tenant string) *tenantDB {
+	dbLock.Lock()
+	defer dbLock.Unlock()
+	return lockedTenantDB(tenant)
+}
+
+// Same as openTenantDB, for the callers holding the DB write lock
+func lockedTenantDB(tenant string) *tenantDB {
+	db, found := tenantDBs[tenant]
+	if !found {
+		db = &tenantDB{
+			tenant:     tenant,
+			TenantData: TenantData{}.orEmpty(),
+			audit:      newMemoryAuditStore(auditMaxEntries),
+			events:     newEventBroker(eventsReplaySize, eventsBufferSize),
+		}
+		tenantDBs[tenant] = db
+	}
+	return db
+}
+
+func (data TenantData) orEmpty() TenantData {
+	return TenantData{
+		Cats:    emptyIfNil(data.Cats),
+		Owners:  emptyIfNil(data.Owners),
+		Records: emptyIfNil(data.Records),
+		Photos:  emptyIfNil(data.Photos),
+	}
+}
+
+// Copies the databases, the entities being replaced rather than changed in place
+func (data TenantData) clone() TenantData {
+	return TenantData{
+		Cats:    emptyIfNil(maps.Clone(data.Cats)),
+		Owners:  emptyIfNil(maps.Clone(data.Owners)),
+		Records: emptyIfNil(maps.Clone(data.Records)),
+		Photos:  emptyIfNil(maps.Clone(data.Photos)),
+	}
+}
+
+// Lists the tenants having databases
+func tenantNames() []string {
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+	return slices.Sorted(maps.Keys(tenantDBs))
+}
+
+// Tenants allowed to use the deployment, with their quotas
+type tenantSettings struct {
+	// Maximum number of cats of each known tenant, the cats in the trash included, 0 for no limit
+	maxCats map[string]int
+	// Secret of the HS256 bearer tokens carrying the tenant claim, the tokens being required when set
+	tokenSecret []byte
+}
+
+// Reads the settings from the environment: TENANTS lists the tenants, each one optionally followed by its quota
+// (e.g. "shelter-a:500,shelter-b"), the default tenant being the only one when it is not set
+func loadTenantSettings() tenantSettings {
+	defaultMaxCats := int(getEnvInt64("TENANT_MAX_CATS", 0))
+	settings := tenantSettings{maxCats: map[string]int{}, tokenSecret: []byte(getEnv("TENANT_TOKEN_SECRET", ""))}
+
+	for _, entry := range strings.Split(getEnv("TENANTS", DefaultTenant), ",") {
+		tenant, quota, hasQuota := strings.Cut(strings.TrimSpace(entry), ":")
+		maxCats := defaultMaxCats
+		if hasQuota {
+			var err error
+			if maxCats, err = strconv.Atoi(quota); err != nil || maxCats < 0 {
+				Logger.Warnf("Invalid quota '%s' for the tenant %s, using %d", quota, tenant, defaultMaxCats)
+				maxCats = defaultMaxCats
+			}
+		}
+		if !tenantIDPattern.MatchString(tenant) {
+			Logger.Warnf("Invalid tenant '%s' in TENANTS, expecting lowercase letters, digits and dashes", tenant)
+			continue
+		}
+		settings.maxCats[tenant] = maxCats
+	}
+	return settings
+}
+
+var tenants = loadTenantSettings()
+
+// Tenant bound to a request, with the path prefix naming it if any
+type requestTenant struct {
+	db       *tenantDB
+	basePath string
+	// Subject of the verified bearer token, empty without one
+	subject string
+}
+
+type tenantContextKey struct{}
+
+// Binds the request to the databases of its tenant, rejecting it when the tenant is unknown or ambiguous
+func withTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		// The responses depend on the tenant, the shared caches must not mix them
+		if len(tenants.maxCats) > 1 {
+			res.Header().Add("Vary", tenantHeader)
+		}
+		if len(tenants.tokenSecret) > 0 {
+			res.Header().Add("Vary", "Authorization")
+		}
+
+		pathTenant, path, hasPrefix := cutTenantPrefix(req.URL.Path)
+		tenant, subject, err := tenants.resolve(pathTenant, req.Header.Get(tenantHeader), req.Header.Get("Authorization"), time.Now())
+		if err != nil {
+			code, message := errorResponse(err)
+			Logger.Info("Rejecting the request: ", message)
+			writeJSON(res, code, message)
+			return
+		}
+
+		bound := requestTenant{db: openTenantDB(tenant), subject: subject}
+		if hasPrefix {
+			bound.basePath = tenantPathPrefix + url.PathEscape(tenant)
+		}
+		noteReplicatedTenant(req.Context(), tenant)
+		req = req.WithContext(context.WithValue(req.Context(), tenantContextKey{}, bound))
+		if hasPrefix {
+			// Like http.StripPrefix, the routes being the same for all the tenants
+			routeURL := *req.URL
+			routeURL.Path, routeURL.RawPath = path, ""
+			req.URL = &routeURL
+		}
+		next.ServeHTTP(res, req)
+	})
+}
+
+// Splits a /tenants/{tenant}/... path into the tenant and the path of the route
+func cutTenantPrefix(path string) (string, string, bool) {
+	rest, found := strings.CutPrefix(path, tenantPathPrefix)
+	if !found {
+		return "", path, false
+	}
+	tenant, route, _ := strings.Cut(rest, "/")
+	return tenant, "/" + route, true
+}
+
+// Finds the tenant of a request: all the given ones must match, the default one being used when none is given.
+// Also returns the subject of the bearer token, when the tokens are verified.
+func (settings tenantSettings) resolve(pathTenant, headerTenant, authorization string, now time.Time) (string, string, error) {
+	candidates, subject := []string{}, ""
+	if pathTenant != "" {
+		candidates = append(candidates, pathTenant)
+	}
+	if headerTenant != "" {
+		candidates = append(candidates, headerTenant)
+	}
+	if len(settings.tokenSecret) > 0 {
+		token, found := strings.CutPrefix(authorization, "Bearer ")
+		if !found {
+			return "", "", &APIError{http.StatusUnauthorized, "Missing the bearer token"}
+		}
+		claims, err := verifyTenantToken(token, settings.tokenSecret, now)
+		if err != nil {
+			Logger.Info("Invalid bearer token: ", err)
+			return "", "", &APIError{http.StatusUnauthorized, "Invalid bearer token"}
+		}
+		candidates = append(candidates, claims.Tenant)
+		subject = claims.Subject
+	}
+
+	tenant := DefaultTenant
+	if len(candidates) > 0 {
+		tenant = candidates[0]
+	}
+	for _, candidate := range candidates {
+		if candidate != tenant {
+			return "", "", &APIError{http.StatusForbidden, "The tenants of the request do not match"}
+		}
+	}
+	if _, known := settings.maxCats[tenant]; !known {
+		if len(candidates) == 0 {
+			return "", "", badRequest("Missing the tenant, expecting the " + tenantHeader + " header or a /tenants/{tenant} path prefix")
+		}
+		return "", "", notFound("Unknown tenant")
+	}
+	return tenant, subject, nil
+}
+
+// Claims of the bearer tokens
+type tenantClaims struct {
+	Tenant  string `json:"tenant"`
+	Subject string `json:"sub"`
+	Exp     *int64 `json:"exp"`
+}
+
+// Checks the HS256 signature and the expiration of a JWT, returning its claims
+func verifyTenantToken(token string, secret []byte, now time.Time) (tenantClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return tenantClaims{}, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeTokenPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return tenantClaims{}, errors.New("expecting a HS256 token")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return tenantClaims{}, errors.New("invalid signature")
+	}
+
+	var claims tenantClaims
+	if err := decodeTokenPart(parts[1], &claims); err != nil {
+		return tenantClaims{}, err
+	}
+	// A token without expiration would be valid forever, once leaked
+	if claims.Exp == nil {
+		return tenantClaims{}, errors.New("missing the exp claim")
+	}
+	if now.Unix() >= *claims.Exp {
+		return tenantClaims{}, errors.New("expired token")
+	}
+	if claims.Tenant == "" {
+		return tenantClaims{}, errors.New("missing the tenant claim")
+	}
+	return claims, nil
+}
+
+func decodeTokenPart(part string, target any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// Gets the databases of the tenant of the request, bound by withTenant
+func requestDB(ctx context.Context) *tenantDB {
+	bound, found := ctx.Value(tenantContextKey{}).(requestTenant)
+	if !found {
+		// Every entry point binds the tenant, falling back to another one could leak its data
+		panic("no tenant bound to the request")
+	}
+	return bound.db
+}
+
+// Path prefix of the UI links, for the requests made under /tenants/{tenant}
+func tenantBasePath(ctx context.Context) string {
+	bound, _ := ctx.Value(tenantContextKey{}).(requestTenant)
+	return bound.basePath
+}
+
+// Refuses a new cat beyond the quota of the tenant, the caller must hold the DB lock
+func (db *tenantDB) checkCatsQuota(added int) error {
+	maxCats := tenants.maxCats[db.tenant]
+	if maxCats > 0 && len(db.Cats)+added > maxCats {
+		Logger.Infof("Tenant '%s' reached its quota of %d cats", db.tenant, maxCats)
+		return &APIError{http.StatusForbidden, fmt.Sprintf("Quota of %d cats reached", maxCats)}
+	}
+	return nil
+}
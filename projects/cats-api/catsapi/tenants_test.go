@@ -0,0 +1,203 @@
+package catsapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Sets the tenants of the test, their databases being dropped at its end
+func setupTenantsTest(t *testing.T, settings tenantSettings) http.Handler {
+	app := setupOwnerTest(t)
+	original := tenants
+	t.Cleanup(func() {
+		tenants = original
+		dbLock.Lock()
+		for tenant := range settings.maxCats {
+			if tenant != DefaultTenant {
+				delete(tenantDBs, tenant)
+			}
+		}
+		dbLock.Unlock()
+	})
+	tenants = settings
+	return app
+}
+
+func doTenantRequest(app http.Handler, method, path string, headers map[string]string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	return rr
+}
+
+func signTestToken(secret, claims string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	unsigned := encode([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + encode([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + encode(mac.Sum(nil))
+}
+
+// Test that the tenants only see their own cats, whichever way they are told
+func TestTenantsIsolation(t *testing.T) {
+	app := setupTenantsTest(t, tenantSettings{maxCats: map[string]int{DefaultTenant: 0, "shelter-a": 0, "shelter-b": 0}})
+
+	rr := doTenantRequest(app, "POST", "/api/cats", map[string]string{tenantHeader: "shelter-a"}, `{"name": "Felix"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected the cat to be created, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !slices.Contains(rr.Header().Values("Vary"), tenantHeader) {
+		t.Errorf("Expected the responses to vary with the tenant, got %v", rr.Header().Values("Vary"))
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		visible bool
+	}{
+		{"header", "/api/v2/cats", map[string]string{tenantHeader: "shelter-a"}, true},
+		{"path prefix", "/tenants/shelter-a/api/v2/cats", nil, true},
+		{"other tenant", "/api/v2/cats", map[string]string{tenantHeader: "shelter-b"}, false},
+		{"other path prefix", "/tenants/shelter-b/api/v2/cats", nil, false},
+		{"default tenant", "/api/v2/cats", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doTenantRequest(app, "GET", tt.path, tt.headers, "")
+			if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "Felix") != tt.visible {
+				t.Errorf("Expected the cat to be visible: %v, got %d: %s", tt.visible, rr.Code, rr.Body.String())
+			}
+		})
+	}
+	if len(testDB().Cats) != 0 || len(openTenantDB("shelter-a").Cats) != 1 {
+		t.Errorf("Expected the cat in the databases of its tenant only, got %v", testDB().Cats)
+	}
+}
+
+// Test the rejection of the unknown, missing and conflicting tenants
+func TestTenantsResolution(t *testing.T) {
+	app := setupTenantsTest(t, tenantSettings{maxCats: map[string]int{"shelter-a": 0, "shelter-b": 0}})
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		code    int
+	}{
+		{"known tenant", "/api/cats", map[string]string{tenantHeader: "shelter-a"}, http.StatusOK},
+		{"unknown tenant", "/api/cats", map[string]string{tenantHeader: "shelter-z"}, http.StatusNotFound},
+		{"unknown path prefix", "/tenants/shelter-z/api/cats", nil, http.StatusNotFound},
+		{"missing tenant", "/api/cats", nil, http.StatusBadRequest},
+		{"conflicting tenants", "/tenants/shelter-a/api/cats", map[string]string{tenantHeader: "shelter-b"}, http.StatusForbidden},
+		{"matching tenants", "/tenants/shelter-a/api/cats", map[string]string{tenantHeader: "shelter-a"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := doTenantRequest(app, "GET", tt.path, tt.headers, ""); rr.Code != tt.code {
+				t.Errorf("Expected status code %d, got %d: %s", tt.code, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+// Test that the health check needs no tenant, and answers on a follower without leader
+func TestHealthCheck(t *testing.T) {
+	app := setupTenantsTest(t, tenantSettings{maxCats: map[string]int{"shelter-a": 0}, tokenSecret: []byte("test-secret")})
+	if rr := doTenantRequest(app, "GET", "/", nil, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the home page to require a token, got %d", rr.Code)
+	}
+	if rr := doTenantRequest(app, "GET", healthCheckPath, nil, ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected the health check to answer, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	node := newReplicaNode("follower:8080", []string{"other:8080"}, 2, time.Minute, memoryTransport{&memoryNetwork{}, "follower:8080"}, tenantsStore{})
+	replication = node
+	t.Cleanup(func() { replication = nil })
+	if rr := doTenantRequest(app, "HEAD", healthCheckPath, nil, ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected the follower without leader to be healthy, got %d", rr.Code)
+	}
+}
+
+// Test the tenant claim of the bearer tokens
+func TestTenantsToken(t *testing.T) {
+	secret := "test-secret"
+	app := setupTenantsTest(t, tenantSettings{maxCats: map[string]int{"shelter-a": 0, "shelter-b": 0}, tokenSecret: []byte(secret)})
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := fmt.Sprintf(`{"tenant": "shelter-a", "exp": %d}`, exp)
+	expired := fmt.Sprintf(`{"tenant": "shelter-a", "exp": %d}`, time.Now().Add(-time.Minute).Unix())
+
+	tests := []struct {
+		name    string
+		token   string
+		headers map[string]string
+		code    int
+	}{
+		{"valid token", signTestToken(secret, valid), nil, http.StatusOK},
+		{"matching header", signTestToken(secret, valid), map[string]string{tenantHeader: "shelter-a"}, http.StatusOK},
+		{"other tenant header", signTestToken(secret, valid), map[string]string{tenantHeader: "shelter-b"}, http.StatusForbidden},
+		{"missing token", "", map[string]string{tenantHeader: "shelter-a"}, http.StatusUnauthorized},
+		{"forged token", signTestToken("other-secret", valid), nil, http.StatusUnauthorized},
+		{"expired token", signTestToken(secret, expired), nil, http.StatusUnauthorized},
+		{"missing expiration", signTestToken(secret, `{"tenant": "shelter-a"}`), nil, http.StatusUnauthorized},
+		{"missing claim", signTestToken(secret, fmt.Sprintf(`{"sub": "alice", "exp": %d}`, exp)), nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			for name, value := range tt.headers {
+				headers[name] = value
+			}
+			if tt.token != "" {
+				headers["Authorization"] = "Bearer " + tt.token
+			}
+			if rr := doTenantRequest(app, "GET", "/api/cats", headers, ""); rr.Code != tt.code {
+				t.Errorf("Expected status code %d, got %d: %s", tt.code, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+// Test that a tenant cannot add cats beyond its quota, the other tenants being unaffected
+func TestTenantsQuota(t *testing.T) {
+	app := setupTenantsTest(t, tenantSettings{maxCats: map[string]int{"shelter-a": 1, "shelter-b": 0}})
+	shelterA := map[string]string{tenantHeader: "shelter-a"}
+
+	if rr := doTenantRequest(app, "POST", "/api/cats", shelterA, `{"name": "Felix"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("Expected the first cat to be created, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr := doTenantRequest(app, "POST", "/api/v2/cats", shelterA, `{"name": "Zaza"}`)
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "Quota of 1 cats reached") {
+		t.Errorf("Expected the quota to be reached, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := doTenantRequest(app, "POST", "/api/cats", map[string]string{tenantHeader: "shelter-b"}, `{"name": "Zaza"}`); rr.Code != http.StatusCreated {
+		t.Errorf("Expected the other tenant to have no quota, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+// Test that the links of the UI keep the path prefix of the tenant
+func TestTenantsUI(t *testing.T) {
+	app := setupTenantsTest(t, tenantSettings{maxCats: map[string]int{DefaultTenant: 0, "shelter-a": 0}})
+	browser := &uiBrowser{app: app, cookies: map[string]*http.Cookie{}}
+
+	expectPage(t, browser.get("/tenants/shelter-a/"), http.StatusOK, `href="/tenants/shelter-a/cats/new"`)
+	rr := browser.post("/tenants/shelter-a/cats", url.Values{"name": {"Felix"}})
+	if location := rr.Header().Get("Location"); rr.Code != http.StatusSeeOther || !strings.HasPrefix(location, "/tenants/shelter-a/cats/") {
+		t.Errorf("Expected a redirection under the prefix of the tenant, got %d %q", rr.Code, location)
+	}
+	if len(openTenantDB("shelter-a").Cats) != 1 || len(testDB().Cats) != 0 {
+		t.Error("Expected the cat to be added to the tenant of the path")
+	}
+}
@@ -0,0 +1,226 @@
+package catsapi
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Settings of the TLS serving, enabled by the certificate file
+type tlsSettings struct {
+	certFile string
+	keyFile  string
+	// CA of the client certificates, required from the clients when set
+	clientCAFile string
+	// CA of the certificates of the other replicas, the system ones when empty
+	caFile string
+	// Name checked in the certificates of the other replicas, for the peers addressed by IP
+	serverName     string
+	reloadInterval time.Duration
+}
+
+func loadTLSSettings() tlsSettings {
+	return tlsSettings{
+		certFile:       getEnv("TLS_CERT_FILE", ""),
+		keyFile:        getEnv("TLS_KEY_FILE", ""),
+		clientCAFile:   getEnv("TLS_CLIENT_CA_FILE", ""),
+		caFile:         getEnv("TLS_CA_FILE", ""),
+		serverName:     getEnv("TLS_SERVER_NAME", ""),
+		reloadInterval: getEnvDuration("TLS_RELOAD_INTERVAL", 10*time.Second),
+	}
+}
+
+func (settings tlsSettings) enabled() bool {
+	return settings.certFile != ""
+}
+
+// Certificate of the server, replaced when its files change
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	lock        sync.RWMutex
+	certificate *tls.Certificate
+	certPEM     []byte
+	keyPEM      []byte
+}
+
+// Loads the certificate, which must be valid at startup
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	if keyFile == "" {
+		return nil, errors.New("TLS_KEY_FILE is required with TLS_CERT_FILE")
+	}
+	reloader := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reads the files again, replacing the certificate when they changed and hold a valid one
+func (reloader *certificateReloader) reload() (bool, error) {
+	certPEM, err := os.ReadFile(reloader.certFile)
+	if err != nil {
+		return false, fmt.Errorf("unable to read the certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(reloader.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("unable to read the certificate key: %w", err)
+	}
+
+	reloader.lock.RLock()
+	unchanged := bytes.Equal(certPEM, reloader.certPEM) && bytes.Equal(keyPEM, reloader.keyPEM)
+	reloader.lock.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	// The files are not replaced together, the pair may not match for a while
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("invalid certificate %s: %w", reloader.certFile, err)
+	}
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+	reloader.certificate, reloader.certPEM, reloader.keyPEM = &certificate, certPEM, keyPEM
+	return true, nil
+}
+
+// Checks the files periodically, the current certificate being kept while the new one is invalid
+func (reloader *certificateReloader) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			reloaded, err := reloader.reload()
+			if err != nil {
+				Logger.Warn("Keeping the current certificate: ", err)
+			} else if reloaded {
+				Logger.Infof("Certificate reloaded from %s", reloader.certFile)
+			}
+		}
+	}
+}
+
+func (reloader *certificateReloader) current() *tls.Certificate {
+	reloader.lock.RLock()
+	defer reloader.lock.RUnlock()
+	return reloader.certificate
+}
+
+func (reloader *certificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return reloader.current(), nil
+}
+
+func (reloader *certificateReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return reloader.current(), nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate in the CA %s", file)
+	}
+	return pool, nil
+}
+
+// Makes the configuration of the servers, verifying the client certificates when there is a client CA
+func (settings tlsSettings) serverConfig(reloader *certificateReloader) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.getCertificate,
+	}
+	if settings.clientCAFile != "" {
+		pool, err := loadCertPool(settings.clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs, config.ClientAuth = pool, tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// Makes the configuration of the calls to the other replicas, which present the server certificate
+func (settings tlsSettings) peerConfig(reloader *certificateReloader) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		ServerName:           settings.serverName,
+		GetClientCertificate: reloader.getClientCertificate,
+	}
+	if settings.caFile != "" {
+		pool, err := loadCertPool(settings.caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// Loads the certificate of the settings, if any, and reloads it until stopped.
+// Returns the configuration of the servers, nil when serving plain HTTP.
+func startTLS(settings tlsSettings, stop <-chan struct{}) (*tls.Config, error) {
+	if !settings.enabled() {
+		return nil, nil
+	}
+	reloader, err := newCertificateReloader(settings.certFile, settings.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config, err := settings.serverConfig(reloader)
+	if err != nil {
+		return nil, err
+	}
+	peers, err := settings.peerConfig(reloader)
+	if err != nil {
+		return nil, err
+	}
+	usePeerTLS(peers)
+
+	go reloader.Run(settings.reloadInterval, stop)
+	Logger.Infof("Serving TLS with the certificate %s", settings.certFile)
+	return config, nil
+}
+
+// Calls the other replicas through HTTPS
+func usePeerTLS(config *tls.Config) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	peerScheme, peerClient = "https", &http.Client{Transport: transport}
+}
+
+// Serves HTTP/2 over TLS when the server has a TLS configuration, and over cleartext (h2c) otherwise,
+// next to HTTP/1.1
+func listenAndServe(server *http.Server) error {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	return serveHTTP(server, listener)
+}
+
+func serveHTTP(server *http.Server, listener net.Listener) error {
+	if server.TLSConfig != nil {
+		return server.ServeTLS(listener, "", "")
+	}
+	server.Handler = h2c.NewHandler(server.Handler, &http2.Server{})
+	return server.Serve(listener)
+}
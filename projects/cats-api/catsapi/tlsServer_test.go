@@ -0,0 +1,207 @@
+package catsapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Certificate of the tests, signed by its parent or by itself
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCertificate(t *testing.T, name string, parent *testCertificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (certificate *testCertificate) write(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, certificate.certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, certificate.keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (certificate *testCertificate) tlsCertificate(t *testing.T) tls.Certificate {
+	pair, err := tls.X509KeyPair(certificate.certPEM, certificate.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+// Serves the protocol of the requests, on a local port until the end of the test
+func startProtocolServer(t *testing.T, tlsConfig *tls.Config) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			io.WriteString(res, req.Proto)
+		}),
+		TLSConfig: tlsConfig,
+	}
+	go serveHTTP(server, listener)
+	t.Cleanup(func() { server.Close() })
+	return listener.Addr().String()
+}
+
+func getProtocol(client *http.Client, url string) (string, error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return string(body), err
+}
+
+// Test the HTTP/2 over TLS, with the client certificates required by the client CA
+func TestTLSServing(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil)
+	certFile, keyFile := newTestCertificate(t, "cats-api", ca).write(t, t.TempDir())
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	os.WriteFile(caFile, ca.certPEM, 0o600)
+
+	settings := tlsSettings{certFile: certFile, keyFile: keyFile, clientCAFile: caFile, caFile: caFile}
+	reloader, err := newCertificateReloader(settings.certFile, settings.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig, err := settings.serverConfig(reloader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := startProtocolServer(t, serverConfig)
+
+	// The replicas call each other with their own certificate
+	peerConfig, _ := settings.peerConfig(reloader)
+	peerTransport := http.DefaultTransport.(*http.Transport).Clone()
+	peerTransport.TLSClientConfig = peerConfig
+	if proto, err := getProtocol(&http.Client{Transport: peerTransport}, "https://"+address); err != nil || proto != "HTTP/2.0" {
+		t.Errorf("Expected an HTTP/2 answer to a replica, got %q: %v", proto, err)
+	}
+
+	proxy := newTestCertificate(t, "proxy", ca)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	proxyTransport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: []tls.Certificate{proxy.tlsCertificate(t)}}}
+	if proto, err := getProtocol(&http.Client{Transport: proxyTransport}, "https://"+address); err != nil || proto != "HTTP/1.1" {
+		t.Errorf("Expected an HTTP/1.1 answer to the proxy, got %q: %v", proto, err)
+	}
+
+	stranger := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	if _, err := getProtocol(&http.Client{Transport: stranger}, "https://"+address); err == nil {
+		t.Error("Expected a client without certificate to be refused")
+	}
+}
+
+// Test the HTTP/2 over cleartext next to the HTTP/1.1
+func TestTLSCleartext(t *testing.T) {
+	address := startProtocolServer(t, nil)
+
+	if proto, err := getProtocol(http.DefaultClient, "http://"+address); err != nil || proto != "HTTP/1.1" {
+		t.Errorf("Expected an HTTP/1.1 answer, got %q: %v", proto, err)
+	}
+	h2cTransport := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, config *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	if proto, err := getProtocol(&http.Client{Transport: h2cTransport}, "http://"+address); err != nil || proto != "HTTP/2.0" {
+		t.Errorf("Expected an HTTP/2 answer, got %q: %v", proto, err)
+	}
+}
+
+// Test the reloading of the certificate when its files change, an invalid one being ignored
+func TestTLSReload(t *testing.T) {
+	ca := newTestCertificate(t, "Test CA", nil)
+	dir := t.TempDir()
+	first := newTestCertificate(t, "cats-api", ca)
+	certFile, keyFile := first.write(t, dir)
+	reloader, err := newCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reloaded, err := reloader.reload(); reloaded || err != nil {
+		t.Errorf("Expected the unchanged files to be skipped, got %v %v", reloaded, err)
+	}
+
+	second := newTestCertificate(t, "cats-api", ca)
+	second.write(t, dir)
+	if reloaded, err := reloader.reload(); !reloaded || err != nil {
+		t.Fatalf("Expected the new certificate to be loaded, got %v %v", reloaded, err)
+	}
+	current, _ := reloader.getCertificate(nil)
+	if current.Leaf == nil || current.Leaf.SerialNumber.Cmp(second.cert.SerialNumber) != 0 {
+		t.Error("Expected the new certificate to be served")
+	}
+
+	// Only the certificate written yet, not matching the key
+	os.WriteFile(certFile, newTestCertificate(t, "cats-api", ca).certPEM, 0o600)
+	if _, err := reloader.reload(); err == nil {
+		t.Error("Expected the mismatched pair to be refused")
+	}
+	if kept, _ := reloader.getCertificate(nil); kept != current {
+		t.Error("Expected the current certificate to be kept")
+	}
+}
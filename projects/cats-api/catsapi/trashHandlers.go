@@ -0,0 +1,146 @@
+package catsapi
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// How long the deleted cats stay in the trash before being purged
+var trashRetention = getEnvDuration("TRASH_RETENTION", 30*24*time.Hour)
+
+// How often the trash is checked for expired cats
+var trashPurgeInterval = getEnvDuration("TRASH_PURGE_INTERVAL", time.Hour)
+
+// Moves a cat to the trash, false when not found. The caller must hold the DB write lock.
+func (db *tenantDB) trashCat(ctx context.Context, catID string, now time.Time) (bool, error) {
+	cat, found := db.findCat(catID)
+	if !found {
+		return false, nil
+	}
+	before := cat
+	deletedAt := now.UTC()
+	cat.DeletedAt = &deletedAt
+	_, err := db.saveCat(ctx, "delete", catID, &before, cat)
+	return err == nil, err
+}
+
+func listTrash(ctx context.Context, req *http.Request, _ struct{}) ([]CatV1, error) {
+	Logger.Info("Listing the cats in the trash")
+
+	dbLock.RLock()
+	defer dbLock.RUnlock()
+
+	results := []CatV1{}
+	for _, cat := range requestDB(ctx).Cats {
+		if cat.DeletedAt != nil {
+			results = append(results, cat.toV1())
+		}
+	}
+	slices.SortFunc(results, func(a, b CatV1) int {
+		return cmp.Or(b.DeletedAt.Compare(*a.DeletedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return results, nil
+}
+
+// Routes the custom methods on a cat, like "POST /api/cats/{catId}:restore"
+func catCustomMethod(ctx context.Context, req *http.Request, body struct{}) (CatV1, error) {
+	catID, found := strings.CutSuffix(req.PathValue("catAction"), ":restore")
+	if !found {
+		return CatV1{}, notFound(http.StatusText(http.StatusNotFound))
+	}
+	req.SetPathValue("catId", catID)
+	return restoreCat(ctx, req, body)
+}
+
+func restoreCat(ctx context.Context, req *http.Request, _ struct{}) (CatV1, error) {
+	catID := req.PathValue("catId")
+	Logger.Info("Restoring the cat: ", catID)
+	db := requestDB(ctx)
+
+	dbLock.Lock()
+	defer dbLock.Unlock()
+
+	cat, found := db.Cats[catID]
+	if !found || cat.DeletedAt == nil {
+		Logger.Infof("Cat '%s' not found in the trash", catID)
+		return CatV1{}, notFound("Cat not found in the trash")
+	}
+
+	before := cat
+	// The owner may have been deleted in the meantime
+	if _, found := db.Owners[cat.OwnerID]; !found {
+		cat.OwnerID = ""
+	}
+	cat.DeletedAt = nil
+	cat, err := db.saveCat(ctx, "restore", catID, &before, cat)
+	if err != nil {
+		return CatV1{}, err
+	}
+
+	Logger.Infof("Cat '%s' restored", catID)
+	return cat.toV1(), nil
+}
+
+// Permanently deletes the cats of all the tenants trashed for longer than the retention period, returns their count
+func purgeTrash(now time.Time) int {
+	dbLock.RLock()
+	dbs := slices.Collect(maps.Values(tenantDBs))
+	dbLock.RUnlock()
+
+	purged := 0
+	for _, db := range dbs {
+		purged += db.purgeTrash(now)
+	}
+	return purged
+}
+
+// Permanently deletes the expired cats of the tenant
+func (db *tenantDB) purgeTrash(now time.Time) int {
+	dbLock.Lock()
+	photos := []Photo{}
+	purged := 0
+	for catID, cat := range db.Cats {
+		if cat.DeletedAt != nil && now.Sub(*cat.DeletedAt) >= trashRetention {
+			// Kept for the next purge when it cannot be removed from the store
+			removed, err := db.removeCat(catID)
+			if err != nil {
+				continue
+			}
+			photos = append(photos, removed...)
+			db.catChanged(context.Background(), "purge", catID, &cat, nil)
+			purged++
+		}
+	}
+	dbLock.Unlock()
+
+	// The photos stay readable as long as the cats may come back
+	afterCommit(func() { db.deletePhotoBlobs(photos) })
+	return purged
+}
+
+// Purges the trash periodically, until the stop channel is closed
+func runTrashPurge(stop <-chan struct{}) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			runReplicatedJob(func() []string {
+				purged := purgeTrash(now)
+				if purged == 0 {
+					return nil
+				}
+				Logger.Infof("Purged %d cats from the trash", purged)
+				return tenantNames()
+			})
+		}
+	}
+}
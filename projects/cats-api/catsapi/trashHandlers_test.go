@@ -0,0 +1,79 @@
+package catsapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Test the trash listing and the restoration of a deleted cat
+func TestTrashAndRestore(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	var catID string
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Toto"}`, &catID)
+
+	if code := doJSON(t, app, "DELETE", "/api/cats/"+catID, "", nil); code != http.StatusNoContent {
+		t.Fatalf("Expected status code %d, got %d", http.StatusNoContent, code)
+	}
+	if code := doJSON(t, app, "DELETE", "/api/cats/"+catID, "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status code %d when deleting twice, got %d", http.StatusNotFound, code)
+	}
+
+	var catIDs []string
+	doJSON(t, app, "GET", "/api/cats", "", &catIDs)
+	if len(catIDs) != 0 {
+		t.Errorf("Expected the trashed cat to be hidden, got %v", catIDs)
+	}
+
+	var trash []Cat
+	doJSON(t, app, "GET", "/api/cats/trash", "", &trash)
+	if len(trash) != 1 || trash[0].ID != catID || trash[0].DeletedAt == nil {
+		t.Fatalf("Expected the cat in the trash, got %+v", trash)
+	}
+
+	var restored Cat
+	if code := doJSON(t, app, "POST", "/api/cats/"+catID+":restore", "", &restored); code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+	if restored.DeletedAt != nil || restored.Name != "Toto" {
+		t.Errorf("Unexpected restored cat: %+v", restored)
+	}
+	if code := doJSON(t, app, "GET", "/api/cats/"+catID, "", nil); code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+
+	if code := doJSON(t, app, "POST", "/api/cats/"+catID+":restore", "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status code %d when the cat is not in the trash, got %d", http.StatusNotFound, code)
+	}
+	if code := doJSON(t, app, "POST", "/api/cats/"+catID+":undelete", "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status code %d for an unknown method, got %d", http.StatusNotFound, code)
+	}
+}
+
+// Test the purge of the expired cats
+func TestPurgeTrash(t *testing.T) {
+	app := setupOwnerTest(t)
+
+	var oldCatID, recentCatID string
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Old"}`, &oldCatID)
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Recent"}`, &recentCatID)
+
+	now := time.Now()
+	db := testDB()
+	dbLock.Lock()
+	db.trashCat(context.Background(), oldCatID, now.Add(-trashRetention))
+	db.trashCat(context.Background(), recentCatID, now.Add(-time.Minute))
+	dbLock.Unlock()
+
+	if purged := purgeTrash(now); purged != 1 {
+		t.Errorf("Expected 1 cat purged, got %d", purged)
+	}
+	if _, found := testDB().Cats[oldCatID]; found {
+		t.Error("The expired cat should have been purged")
+	}
+	if _, found := testDB().Cats[recentCatID]; !found {
+		t.Error("The recently deleted cat should still be in the trash")
+	}
+}
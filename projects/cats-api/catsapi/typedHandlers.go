@@ -0,0 +1,95 @@
+package catsapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Maximum time given to a service to answer, its context being canceled after it
+var serviceTimeout = getEnvDuration("SERVICE_TIMEOUT", 30*time.Second)
+
+// Error answered to the client with its status code, the other errors being internal ones
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (err *APIError) Error() string {
+	return err.Message
+}
+
+func badRequest(message string) *APIError {
+	return &APIError{http.StatusBadRequest, message}
+}
+
+func notFound(message string) *APIError {
+	return &APIError{http.StatusNotFound, message}
+}
+
+// Request bodies checking themselves once decoded, they may also fill their computed fields
+type validator interface {
+	Validate() error
+}
+
+// Service getting its decoded request body and the request context, struct{} standing for no body.
+// The request gives the path values and the query parameters.
+type TypedService[Req, Resp any] func(ctx context.Context, req *http.Request, body Req) (Resp, error)
+
+// Adapts a typed service to a ServiceFunc: decodes and validates the body, answers successCode
+// with the typed response, or the status code of the error
+func typedService[Req, Resp any](successCode int, service TypedService[Req, Resp]) ServiceFunc {
+	return func(req *http.Request) (int, any) {
+		ctx, cancel := context.WithTimeout(req.Context(), serviceTimeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+
+		var body Req
+		if _, noBody := any(body).(struct{}); !noBody {
+			if err := decodeBody(req, &body); err != nil {
+				Logger.Info("Unable to parse the request body: ", err)
+				return bodyErrorResponse(err)
+			}
+			if check, ok := any(&body).(validator); ok {
+				if err := check.Validate(); err != nil {
+					Logger.Info("Invalid request body: ", err)
+					return errorResponse(err)
+				}
+			}
+		}
+		// The client may have given up while sending the body
+		if err := ctx.Err(); err != nil {
+			return errorResponse(err)
+		}
+
+		resp, err := service(ctx, req, body)
+		if err != nil {
+			return errorResponse(err)
+		}
+		if successCode == http.StatusNoContent {
+			return successCode, nil
+		}
+		return successCode, resp
+	}
+}
+
+// Makes the http.HandlerFunc of a typed service
+func Handle[Req, Resp any](successCode int, service TypedService[Req, Resp]) http.HandlerFunc {
+	return makeHandlerFunc(typedService(successCode, service))
+}
+
+// Response to a service error, the unexpected ones being logged and hidden from the client
+func errorResponse(err error) (int, any) {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.Code, apiErr.Message
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		Logger.Warn("Request abandoned: ", err)
+		return http.StatusServiceUnavailable, "Request timed out or canceled"
+	default:
+		Logger.Error("Unexpected service error: ", err)
+		return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+	}
+}
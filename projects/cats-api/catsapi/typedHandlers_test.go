@@ -0,0 +1,98 @@
+package catsapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testGreeting struct {
+	Name string `json:"name"`
+}
+
+func (greeting *testGreeting) Validate() error {
+	if greeting.Name == "" {
+		return badRequest("Missing the name")
+	}
+	greeting.Name = strings.TrimSpace(greeting.Name)
+	return nil
+}
+
+func greet(ctx context.Context, req *http.Request, greeting testGreeting) (string, error) {
+	switch greeting.Name {
+	case "nobody":
+		return "", notFound("Nobody to greet")
+	case "error":
+		return "", errors.New("database down")
+	case "panic":
+		panic("unexpected")
+	case "slow":
+		<-ctx.Done()
+		return "", fmt.Errorf("greeting abandoned: %w", ctx.Err())
+	}
+	return "Hello " + greeting.Name, nil
+}
+
+// Test the decoding, validation and the typed responses and errors
+func TestTypedService(t *testing.T) {
+	handler := Handle(http.StatusCreated, greet)
+
+	tests := []struct {
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{`{"name": " Felix "}`, http.StatusCreated, `"Hello Felix"`},
+		{`{"name": ""}`, http.StatusBadRequest, `"Missing the name"`},
+		{`{"name": `, http.StatusBadRequest, `"Invalid JSON input: unexpected end of JSON input at offset 9"`},
+		{`{"name": "nobody"}`, http.StatusNotFound, `"Nobody to greet"`},
+		{`{"name": "error"}`, http.StatusInternalServerError, `"Internal Server Error"`},
+		{`{"name": "panic"}`, http.StatusInternalServerError, `"Internal Server Error"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("POST", "/", strings.NewReader(tt.body)))
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if body := strings.TrimSpace(rr.Body.String()); body != tt.expectedBody {
+				t.Errorf("Expected %s, got %s", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+// Test the cancellation of the services past their timeout
+func TestTypedServiceTimeout(t *testing.T) {
+	originalTimeout := serviceTimeout
+	serviceTimeout = 10 * time.Millisecond
+	t.Cleanup(func() { serviceTimeout = originalTimeout })
+
+	code, body := typedService(http.StatusOK, greet)(httptest.NewRequest("POST", "/", strings.NewReader(`{"name": "slow"}`)))
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d: %v", http.StatusServiceUnavailable, code, body)
+	}
+}
+
+// Test the services without request nor response body
+func TestTypedServiceNoBody(t *testing.T) {
+	called := false
+	service := func(ctx context.Context, req *http.Request, _ struct{}) (struct{}, error) {
+		called = req.PathValue("id") == "42"
+		return struct{}{}, nil
+	}
+
+	req := httptest.NewRequest("DELETE", "/42", strings.NewReader("not JSON"))
+	req.SetPathValue("id", "42")
+	code, body := typedService(http.StatusNoContent, service)(req)
+	if code != http.StatusNoContent || body != nil || !called {
+		t.Errorf("Unexpected response %d %v, called: %v", code, body, called)
+	}
+}
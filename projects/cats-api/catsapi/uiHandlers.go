@@ -0,0 +1,290 @@
+package catsapi
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+// Pages of the UI, each one rendered into the layout
+var uiTemplates = map[string]*template.Template{
+	"list":   parsePage("list"),
+	"detail": parsePage("detail"),
+	"form":   parsePage("form"),
+}
+
+func parsePage(name string) *template.Template {
+	return template.Must(template.ParseFS(templatesFS, "templates/layout.html", "templates/"+name+".html"))
+}
+
+// Content shared by all the pages, the links starting with the Base path of the tenant
+type uiPage struct {
+	Base      string
+	Title     string
+	Version   string
+	Flash     *uiFlash
+	CSRFToken string
+	Data      any
+}
+
+type uiCatsList struct {
+	CatsV2Page
+	// Pages before this one, the page linking back to the first one
+	HasPrevious bool
+}
+
+type uiCatDetail struct {
+	Cat   CatV2
+	Owner *Owner
+}
+
+// Values of the create and edit forms, kept as typed when they are invalid
+type uiCatForm struct {
+	Action string
+	Cancel string
+	Error  string
+	Input  uiCatInput
+	Sexes  []string
+	Owners []Owner
+}
+
+type uiCatInput struct {
+	Name        string
+	BirthDate   string
+	Color       string
+	Sex         string
+	Breed       string
+	MicrochipID string
+	OwnerID     string
+}
+
+// Renders the page in full before sending it, for the template errors to be reported as such
+func renderPage(res http.ResponseWriter, req *http.Request, code int, name string, page uiPage) {
+	page.Base = tenantBasePath(req.Context())
+	page.Version = version
+	page.CSRFToken = csrfToken(res, req)
+	page.Flash = popFlash(res, req)
+
+	var html bytes.Buffer
+	if err := uiTemplates[name].ExecuteTemplate(&html, "layout", page); err != nil {
+		Logger.Error("Unable to render the page: ", err)
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "text/html; charset=utf-8")
+	res.WriteHeader(code)
+	res.Write(html.Bytes())
+}
+
+// Redirects to the page after a form submission, with the message to show there
+func redirectWithFlash(res http.ResponseWriter, req *http.Request, location, kind, message string) {
+	setFlash(res, req, kind, message)
+	http.Redirect(res, req, tenantBasePath(req.Context())+location, http.StatusSeeOther)
+}
+
+// Parses the submitted form, rejecting it without the CSRF token of the browser
+func parseUIForm(res http.ResponseWriter, req *http.Request) bool {
+	req.Body = http.MaxBytesReader(res, req.Body, maxBodySize)
+	if err := req.ParseForm(); err != nil {
+		http.Error(res, "Invalid form", http.StatusBadRequest)
+		return false
+	}
+	if !validCSRFToken(req) {
+		Logger.Warn("Rejecting a form without a valid CSRF token")
+		http.Error(res, "Invalid or missing CSRF token, reload the page and retry", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Lists the cats, the home page
+func uiListCats(res http.ResponseWriter, req *http.Request) {
+	afterID, valid := decodeCatCursor(req.URL.Query().Get("pageToken"))
+	if !valid {
+		afterID = ""
+	}
+	cats, total, hasNext := requestDB(req.Context()).pageCats(func(Cat) bool { return true }, afterID, defaultCatsPageSize)
+
+	today := time.Now()
+	list := uiCatsList{CatsV2Page: CatsV2Page{Cats: []CatV2{}, TotalSize: total}, HasPrevious: afterID != ""}
+	for _, cat := range cats {
+		list.Cats = append(list.Cats, catToV2(cat.ID, cat, today))
+	}
+	if hasNext {
+		list.NextPageToken = encodeCatCursor(cats[len(cats)-1].ID)
+	}
+	renderPage(res, req, http.StatusOK, "list", uiPage{Title: "Cats", Data: list})
+}
+
+func uiShowCat(res http.ResponseWriter, req *http.Request) {
+	catID := req.PathValue("catId")
+	db := requestDB(req.Context())
+
+	dbLock.RLock()
+	cat, found := db.findCat(catID)
+	owner, hasOwner := db.Owners[cat.OwnerID]
+	dbLock.RUnlock()
+
+	if !found {
+		redirectWithFlash(res, req, "/", "error", "Cat not found")
+		return
+	}
+	detail := uiCatDetail{Cat: catToV2(catID, cat, time.Now())}
+	if hasOwner {
+		detail.Owner = &owner
+	}
+	renderPage(res, req, http.StatusOK, "detail", uiPage{Title: cat.Name, Data: detail})
+}
+
+func uiNewCat(res http.ResponseWriter, req *http.Request) {
+	renderCatForm(res, req, http.StatusOK, "Add a cat", uiCatForm{Action: "/cats", Cancel: "/"})
+}
+
+func uiEditCat(res http.ResponseWriter, req *http.Request) {
+	catID := req.PathValue("catId")
+
+	dbLock.RLock()
+	cat, found := requestDB(req.Context()).findCat(catID)
+	dbLock.RUnlock()
+
+	if !found {
+		redirectWithFlash(res, req, "/", "error", "Cat not found")
+		return
+	}
+	form := uiCatForm{
+		Action: "/cats/" + url.PathEscape(catID),
+		Cancel: "/cats/" + url.PathEscape(catID),
+		Input: uiCatInput{
+			Name:        cat.Name,
+			BirthDate:   cat.BirthDate,
+			Color:       cat.Color,
+			Sex:         cat.Sex,
+			Breed:       cat.Breed,
+			MicrochipID: cat.MicrochipID,
+			OwnerID:     cat.OwnerID,
+		},
+	}
+	renderCatForm(res, req, http.StatusOK, "Edit "+cat.Name, form)
+}
+
+func renderCatForm(res http.ResponseWriter, req *http.Request, code int, title string, form uiCatForm) {
+	form.Sexes = catSexes
+	db := requestDB(req.Context())
+
+	dbLock.RLock()
+	for _, owner := range db.Owners {
+		form.Owners = append(form.Owners, owner)
+	}
+	dbLock.RUnlock()
+	slices.SortFunc(form.Owners, func(a, b Owner) int { return strings.Compare(a.Name, b.Name) })
+
+	renderPage(res, req, code, "form", uiPage{Title: title, Data: form})
+}
+
+// Reads the submitted cat, the form being shown again with the error when it is invalid
+func readCatForm(req *http.Request) (uiCatInput, CatV2Input, error) {
+	form := uiCatInput{
+		Name:        strings.TrimSpace(req.PostFormValue("name")),
+		BirthDate:   req.PostFormValue("birthDate"),
+		Color:       strings.TrimSpace(req.PostFormValue("color")),
+		Sex:         req.PostFormValue("sex"),
+		Breed:       strings.TrimSpace(req.PostFormValue("breed")),
+		MicrochipID: strings.TrimSpace(req.PostFormValue("microchipId")),
+		OwnerID:     req.PostFormValue("ownerId"),
+	}
+	input := CatV2Input{
+		Name:        form.Name,
+		Color:       form.Color,
+		Sex:         form.Sex,
+		Breed:       form.Breed,
+		MicrochipID: form.MicrochipID,
+		OwnerID:     form.OwnerID,
+	}
+	if form.BirthDate != "" {
+		birthDate, err := time.Parse(dateLayout, form.BirthDate)
+		if err != nil {
+			return form, input, badRequest("Invalid birth date, expecting YYYY-MM-DD")
+		}
+		input.BirthDate = &Date{birthDate}
+	}
+	return form, input, nil
+}
+
+func uiCreateCat(res http.ResponseWriter, req *http.Request) {
+	if !parseUIForm(res, req) {
+		return
+	}
+	form, input, err := readCatForm(req)
+	if err == nil {
+		var cat Cat
+		if cat, err = requestDB(req.Context()).insertCatV2(req.Context(), input, time.Now()); err == nil {
+			redirectWithFlash(res, req, "/cats/"+url.PathEscape(cat.ID), "success", "Cat "+cat.Name+" added")
+			return
+		}
+	}
+	renderCatFormError(res, req, "Add a cat", uiCatForm{Action: "/cats", Cancel: "/", Input: form}, err)
+}
+
+func uiUpdateCat(res http.ResponseWriter, req *http.Request) {
+	catID := req.PathValue("catId")
+	if !parseUIForm(res, req) {
+		return
+	}
+	form, input, err := readCatForm(req)
+	if err == nil {
+		var cat Cat
+		if cat, err = requestDB(req.Context()).replaceCat(req.Context(), catID, input, time.Now()); err == nil {
+			redirectWithFlash(res, req, "/cats/"+url.PathEscape(catID), "success", "Cat "+cat.Name+" saved")
+			return
+		}
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		redirectWithFlash(res, req, "/", "error", apiErr.Message)
+		return
+	}
+	path := "/cats/" + url.PathEscape(catID)
+	renderCatFormError(res, req, "Edit "+form.Name, uiCatForm{Action: path, Cancel: path, Input: form}, err)
+}
+
+func renderCatFormError(res http.ResponseWriter, req *http.Request, title string, form uiCatForm, err error) {
+	code, message := errorResponse(err)
+	form.Error, _ = message.(string)
+	renderCatForm(res, req, code, title, form)
+}
+
+// Moves the cat to the trash, like the API
+func uiDeleteCat(res http.ResponseWriter, req *http.Request) {
+	catID := req.PathValue("catId")
+	if !parseUIForm(res, req) {
+		return
+	}
+
+	db := requestDB(req.Context())
+	dbLock.Lock()
+	cat, _ := db.findCat(catID)
+	deleted, err := db.trashCat(req.Context(), catID, time.Now())
+	dbLock.Unlock()
+
+	if err != nil {
+		_, message := errorResponse(err)
+		text, _ := message.(string)
+		redirectWithFlash(res, req, "/", "error", text)
+		return
+	}
+	if !deleted {
+		redirectWithFlash(res, req, "/", "error", "Cat not found")
+		return
+	}
+	Logger.Infof("Cat '%s' moved to the trash", catID)
+	redirectWithFlash(res, req, "/", "success", "Cat "+cat.Name+" deleted")
+}
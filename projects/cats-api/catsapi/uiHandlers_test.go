@@ -0,0 +1,149 @@
+package catsapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Browser keeping the cookies of the UI
+type uiBrowser struct {
+	app     http.Handler
+	cookies map[string]*http.Cookie
+}
+
+func newUIBrowser(t *testing.T) *uiBrowser {
+	return &uiBrowser{app: setupOwnerTest(t), cookies: map[string]*http.Cookie{}}
+}
+
+func (browser *uiBrowser) do(req *http.Request) *httptest.ResponseRecorder {
+	for _, cookie := range browser.cookies {
+		req.AddCookie(cookie)
+	}
+	rr := httptest.NewRecorder()
+	browser.app.ServeHTTP(rr, req)
+	for _, cookie := range rr.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(browser.cookies, cookie.Name)
+		} else {
+			browser.cookies[cookie.Name] = cookie
+		}
+	}
+	return rr
+}
+
+func (browser *uiBrowser) get(path string) *httptest.ResponseRecorder {
+	return browser.do(httptest.NewRequest("GET", path, nil))
+}
+
+// Submits the form with the CSRF token of the browser
+func (browser *uiBrowser) post(path string, form url.Values) *httptest.ResponseRecorder {
+	if token, found := browser.cookies[csrfCookieName]; found {
+		form.Set(csrfFieldName, token.Value)
+	}
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return browser.do(req)
+}
+
+func expectPage(t *testing.T, rr *httptest.ResponseRecorder, code int, contents ...string) {
+	t.Helper()
+	if rr.Code != code {
+		t.Errorf("Expected status code %d, got %d: %s", code, rr.Code, rr.Body.String())
+	}
+	for _, content := range contents {
+		if !strings.Contains(rr.Body.String(), content) {
+			t.Errorf("Expected %q in the page: %s", content, rr.Body.String())
+		}
+	}
+}
+
+// Test the creation, edition and deletion of a cat through the forms, with their flash messages
+func TestUICatForms(t *testing.T) {
+	browser := newUIBrowser(t)
+	testDB().Owners["o1"] = Owner{ID: "o1", Name: "Alice"}
+
+	rr := browser.get("/cats/new")
+	expectPage(t, rr, http.StatusOK, `name="csrf_token"`, `<option value="o1">Alice</option>`)
+	if rr.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("Unexpected content type %q", rr.Header().Get("Content-Type"))
+	}
+
+	rr = browser.post("/cats", url.Values{"name": {"<Felix>"}, "sex": {"male"}, "birthDate": {"2020-05-01"}, "ownerId": {"o1"}})
+	location := rr.Header().Get("Location")
+	if rr.Code != http.StatusSeeOther || !strings.HasPrefix(location, "/cats/") {
+		t.Fatalf("Expected a redirection to the cat, got %d %q", rr.Code, location)
+	}
+	expectPage(t, browser.get(location), http.StatusOK, "Cat &lt;Felix&gt; added", "Alice", "2020-05-01")
+	if strings.Contains(browser.get(location).Body.String(), "added") {
+		t.Error("Expected the flash message to be shown once")
+	}
+
+	rr = browser.post(location, url.Values{"name": {"Felix"}, "sex": {"tomcat"}})
+	expectPage(t, rr, http.StatusBadRequest, "Invalid sex", `value="Felix"`)
+
+	rr = browser.post(location, url.Values{"name": {"Felix"}, "sex": {"male"}, "color": {"Grey"}})
+	expectPage(t, browser.get(rr.Header().Get("Location")), http.StatusOK, "Cat Felix saved", "Grey")
+
+	rr = browser.post(location+"/delete", url.Values{})
+	expectPage(t, browser.get(rr.Header().Get("Location")), http.StatusOK, "Cat Felix deleted", "0 cat(s)")
+	expectPage(t, browser.get(location), http.StatusSeeOther)
+}
+
+// Test that the forms are rejected without the CSRF token of the browser
+func TestUICSRF(t *testing.T) {
+	browser := newUIBrowser(t)
+	browser.get("/")
+
+	req := httptest.NewRequest("POST", "/cats", strings.NewReader("name=Felix&csrf_token=forged"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	expectPage(t, browser.do(req), http.StatusForbidden, "CSRF token")
+
+	// Without the cookie, like a form submitted from another site
+	req = httptest.NewRequest("POST", "/cats", strings.NewReader("name=Felix&csrf_token="+browser.cookies[csrfCookieName].Value))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	browser.app.ServeHTTP(rr, req)
+	expectPage(t, rr, http.StatusForbidden)
+	if len(testDB().Cats) != 0 {
+		t.Errorf("No cat should have been created, got %v", testDB().Cats)
+	}
+}
+
+// Test the pages of the cats list
+func TestUIListPages(t *testing.T) {
+	browser := newUIBrowser(t)
+	for i := range defaultCatsPageSize + 5 {
+		catID := fmt.Sprintf("cat-%02d", i)
+		testDB().Cats[catID] = Cat{ID: catID, Name: "Cat " + catID}
+	}
+
+	rr := browser.get("/")
+	expectPage(t, rr, http.StatusOK, "25 cat(s)", "Cat cat-00", "Next page")
+	next := "/?pageToken=" + encodeCatCursor(fmt.Sprintf("cat-%02d", defaultCatsPageSize-1))
+	expectPage(t, browser.get(next), http.StatusOK, "Cat cat-24", "First page")
+	if strings.Contains(browser.get(next).Body.String(), "Cat cat-00") {
+		t.Error("Expected the second page to start after the first one")
+	}
+}
+
+// Test that the cookie forgetting the flash message keeps the attributes of the one setting it
+func TestUIFlashCookie(t *testing.T) {
+	req := httptest.NewRequest("GET", "https://localhost/", nil)
+	setting := httptest.NewRecorder()
+	setFlash(setting, req, "success", "Saved")
+
+	req.AddCookie(setting.Result().Cookies()[0])
+	clearing := httptest.NewRecorder()
+	if flash := popFlash(clearing, req); flash == nil || flash.Message != "Saved" {
+		t.Fatalf("Expected the flash message, got %+v", flash)
+	}
+
+	set, cleared := setting.Result().Cookies()[0], clearing.Result().Cookies()[0]
+	if !cleared.Secure || !cleared.HttpOnly || cleared.SameSite != set.SameSite || cleared.Path != set.Path || cleared.MaxAge >= 0 {
+		t.Errorf("Expected the attributes of %+v, got %+v", set, cleared)
+	}
+}
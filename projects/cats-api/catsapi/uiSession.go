@@ -0,0 +1,85 @@
+package catsapi
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// Cookies of the UI: the CSRF token, compared to the one of the forms (double-submit),
+// and the message shown by the page following a redirection
+const (
+	csrfCookieName  = "csrf_token"
+	csrfFieldName   = "csrf_token"
+	flashCookieName = "flash"
+)
+
+// Message of the UI, of kind success or error
+type uiFlash struct {
+	Kind    string
+	Message string
+}
+
+// Returns the CSRF token of the browser, creating it on its first visit
+func csrfToken(res http.ResponseWriter, req *http.Request) string {
+	if cookie, err := req.Cookie(csrfCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	random := make([]byte, 32)
+	rand.Read(random)
+	token := base64.RawURLEncoding.EncodeToString(random)
+	http.SetCookie(res, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   req.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+// Checks that the submitted form carries the CSRF token of the browser
+func validCSRFToken(req *http.Request) bool {
+	cookie, err := req.Cookie(csrfCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(req.PostFormValue(csrfFieldName))) == 1
+}
+
+// Keeps the message for the next page
+func setFlash(res http.ResponseWriter, req *http.Request, kind, message string) {
+	http.SetCookie(res, flashCookie(req, base64.RawURLEncoding.EncodeToString([]byte(kind+":"+message)), 0))
+}
+
+// Cookie of the message, the one forgetting it having the same attributes as the one setting it
+func flashCookie(req *http.Request, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     flashCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   req.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Returns the message kept by the previous page and forgets it, nil when there is none
+func popFlash(res http.ResponseWriter, req *http.Request) *uiFlash {
+	cookie, err := req.Cookie(flashCookieName)
+	if err != nil {
+		return nil
+	}
+	http.SetCookie(res, flashCookie(req, "", -1))
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	kind, message, found := strings.Cut(string(decoded), ":")
+	if err != nil || !found || (kind != "success" && kind != "error") {
+		return nil
+	}
+	return &uiFlash{Kind: kind, Message: message}
+}
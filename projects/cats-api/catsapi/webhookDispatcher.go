@@ -0,0 +1,436 @@
+package catsapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Integrator endpoint notified of the cat events of a tenant
+type Webhook struct {
+	Tenant    string    `json:"-"`
+	ID        string    `json:"id,omitempty"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Outcome of one delivery try
+type DeliveryAttempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// Sending of an event to a webhook, with its attempts
+type Delivery struct {
+	ID        string            `json:"id"`
+	WebhookID string            `json:"webhookId"`
+	EventID   int64             `json:"eventId"`
+	EventType string            `json:"eventType"`
+	Status    string            `json:"status"`
+	Attempts  []DeliveryAttempt `json:"attempts"`
+	tenant    string
+	payload   []byte
+}
+
+const (
+	deliveryPending   = "pending"
+	deliverySucceeded = "succeeded"
+	deliveryDead      = "dead"
+)
+
+// Number of deliveries kept in the log of each webhook, and in the dead-letter list
+const deliveryLogSize = 100
+
+// Signs the deliveries and sends them in the background, retrying the failures
+type webhookDispatcher struct {
+	lock        sync.Mutex
+	webhooks    map[string]Webhook
+	deliveries  map[string]*Delivery
+	logs        map[string][]string
+	deadLetters []string
+	queue       chan string
+
+	client      *http.Client
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+	// Lets the webhooks reach the loopback, private and link-local addresses, refused by default
+	allowPrivateTargets bool
+}
+
+func newWebhookDispatcher(maxAttempts int, retryBase time.Duration, timeout time.Duration, allowPrivateTargets bool) *webhookDispatcher {
+	dispatcher := &webhookDispatcher{
+		webhooks:            map[string]Webhook{},
+		deliveries:          map[string]*Delivery{},
+		logs:                map[string][]string{},
+		queue:               make(chan string, 1024),
+		maxAttempts:         maxAttempts,
+		retryBase:           retryBase,
+		retryMax:            time.Hour,
+		allowPrivateTargets: allowPrivateTargets,
+	}
+	dispatcher.client = &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dispatcher.dialTarget, TLSHandshakeTimeout: timeout},
+		// A redirect could lead to a refused address, the response of which would show in the deliveries
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return dispatcher
+}
+
+var webhooks = newWebhookDispatcher(
+	int(getEnvInt64("WEBHOOK_MAX_ATTEMPTS", 6)),
+	getEnvDuration("WEBHOOK_RETRY_BASE", 2*time.Second),
+	getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+	getEnvBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false),
+)
+
+// Ranges which are not reachable from the internet, on top of the loopback, private, link-local and multicast ones
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("fec0::/10"),
+}
+
+// Tells whether the address belongs to the internet, the webhooks must not reach the services of the deployment
+func isPublicAddress(address netip.Addr) bool {
+	address = address.Unmap()
+	if !address.IsGlobalUnicast() || address.IsPrivate() {
+		return false
+	}
+	return !slices.ContainsFunc(nonPublicPrefixes, func(prefix netip.Prefix) bool { return prefix.Contains(address) })
+}
+
+// Dials the receiver of a delivery, on the addresses its name resolves to once they are all checked, so that
+// a webhook cannot reach the internal services of the deployment (SSRF), even through a DNS name
+func (dispatcher *webhookDispatcher) dialTarget(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	if !dispatcher.allowPrivateTargets {
+		for _, resolved := range addresses {
+			if !isPublicAddress(resolved) {
+				return nil, fmt.Errorf("refusing to call %s, its address %s is not a public one", host, resolved)
+			}
+		}
+	}
+
+	var dialer net.Dialer
+	for _, resolved := range addresses {
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(resolved.Unmap().String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// Runs the delivery workers until the stop channel is closed
+func (dispatcher *webhookDispatcher) Run(workers int, stop <-chan struct{}) {
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				case deliveryID := <-dispatcher.queue:
+					dispatcher.attempt(deliveryID)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (dispatcher *webhookDispatcher) Add(webhook Webhook) Webhook {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	webhook.ID = uuid.New().String()
+	webhook.CreatedAt = time.Now().UTC()
+	dispatcher.webhooks[webhook.ID] = webhook
+	return webhook
+}
+
+// Removes all the webhooks, their deliveries and the dead letters
+func (dispatcher *webhookDispatcher) Reset() {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	dispatcher.webhooks = map[string]Webhook{}
+	dispatcher.deliveries = map[string]*Delivery{}
+	dispatcher.logs = map[string][]string{}
+	dispatcher.deadLetters = nil
+}
+
+// Gets a webhook of the tenant, the ones of the other tenants being never found
+func (dispatcher *webhookDispatcher) Get(tenant string, webhookID string) (Webhook, bool) {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	webhook, found := dispatcher.webhooks[webhookID]
+	if !found || webhook.Tenant != tenant {
+		return Webhook{}, false
+	}
+	return webhook, true
+}
+
+func (dispatcher *webhookDispatcher) List(tenant string) []Webhook {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	results := []Webhook{}
+	for _, webhook := range dispatcher.webhooks {
+		if webhook.Tenant == tenant {
+			results = append(results, webhook)
+		}
+	}
+	slices.SortFunc(results, func(a, b Webhook) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return results
+}
+
+// Removes the webhook and its log, its pending deliveries are abandoned
+func (dispatcher *webhookDispatcher) Remove(tenant string, webhookID string) bool {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	if webhook, found := dispatcher.webhooks[webhookID]; !found || webhook.Tenant != tenant {
+		return false
+	}
+	for _, deliveryID := range dispatcher.logs[webhookID] {
+		if !slices.Contains(dispatcher.deadLetters, deliveryID) {
+			delete(dispatcher.deliveries, deliveryID)
+		}
+	}
+	delete(dispatcher.logs, webhookID)
+	delete(dispatcher.webhooks, webhookID)
+	return true
+}
+
+// Lists the latest deliveries of a webhook of the tenant, most recent first
+func (dispatcher *webhookDispatcher) Deliveries(tenant string, webhookID string) []Delivery {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	return dispatcher.copyDeliveries(tenant, dispatcher.logs[webhookID])
+}
+
+// Lists the deliveries of the tenant given up after too many failures, most recent first
+func (dispatcher *webhookDispatcher) DeadLetters(tenant string) []Delivery {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	return dispatcher.copyDeliveries(tenant, dispatcher.deadLetters)
+}
+
+func (dispatcher *webhookDispatcher) copyDeliveries(tenant string, deliveryIDs []string) []Delivery {
+	results := []Delivery{}
+	for i := len(deliveryIDs) - 1; i >= 0; i-- {
+		stored, found := dispatcher.deliveries[deliveryIDs[i]]
+		if !found || stored.tenant != tenant {
+			continue
+		}
+		delivery := *stored
+		delivery.Attempts = slices.Clone(delivery.Attempts)
+		results = append(results, delivery)
+	}
+	return results
+}
+
+// Creates a delivery for every webhook of the tenant subscribed to the event, never blocks
+func (dispatcher *webhookDispatcher) Enqueue(tenant string, event CatEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		Logger.Error("Unable to encode the webhook payload: ", err)
+		return
+	}
+
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	for _, webhook := range dispatcher.webhooks {
+		if webhook.Tenant != tenant || !slices.Contains(webhook.Events, event.Type) {
+			continue
+		}
+		delivery := &Delivery{
+			ID:        uuid.New().String(),
+			WebhookID: webhook.ID,
+			EventID:   event.ID,
+			EventType: event.Type,
+			Status:    deliveryPending,
+			Attempts:  []DeliveryAttempt{},
+			tenant:    tenant,
+			payload:   payload,
+		}
+		dispatcher.deliveries[delivery.ID] = delivery
+		dispatcher.logs[webhook.ID] = dispatcher.trimLog(append(dispatcher.logs[webhook.ID], delivery.ID))
+		dispatcher.schedule(delivery.ID, 0)
+	}
+}
+
+// Keeps the latest deliveries of a log, forgetting the finished older ones
+func (dispatcher *webhookDispatcher) trimLog(log []string) []string {
+	for len(log) > deliveryLogSize {
+		oldest, found := dispatcher.deliveries[log[0]]
+		if found && oldest.Status == deliveryPending {
+			break
+		}
+		if !slices.Contains(dispatcher.deadLetters, log[0]) {
+			delete(dispatcher.deliveries, log[0])
+		}
+		log = log[1:]
+	}
+	return log
+}
+
+// Queues the delivery after the delay, without blocking the caller, which holds the lock
+func (dispatcher *webhookDispatcher) schedule(deliveryID string, delay time.Duration) {
+	if delay == 0 {
+		dispatcher.push(deliveryID)
+		return
+	}
+	time.AfterFunc(delay, func() {
+		dispatcher.lock.Lock()
+		defer dispatcher.lock.Unlock()
+		dispatcher.push(deliveryID)
+	})
+}
+
+// Hands the delivery to the workers, the caller must hold the lock. A full queue means that the workers are far
+// behind: the delivery is then dropped to the dead letters rather than waiting.
+func (dispatcher *webhookDispatcher) push(deliveryID string) {
+	select {
+	case dispatcher.queue <- deliveryID:
+	default:
+		delivery, found := dispatcher.deliveries[deliveryID]
+		if !found || delivery.Status != deliveryPending {
+			return
+		}
+		Logger.Warnf("Webhook delivery queue full, dropping the delivery '%s'", deliveryID)
+		delivery.Attempts = append(delivery.Attempts, DeliveryAttempt{Time: time.Now().UTC(), Error: "dropped, the delivery queue is full"})
+		dispatcher.bury(delivery)
+	}
+}
+
+// Exponential backoff with jitter: a random delay between half and all of base * 2^(attempts-1)
+func (dispatcher *webhookDispatcher) retryDelay(attempts int) time.Duration {
+	delay := dispatcher.retryBase << (attempts - 1)
+	if delay <= 0 || delay > dispatcher.retryMax {
+		delay = dispatcher.retryMax
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func (dispatcher *webhookDispatcher) attempt(deliveryID string) {
+	dispatcher.lock.Lock()
+	delivery, found := dispatcher.deliveries[deliveryID]
+	if !found || delivery.Status != deliveryPending {
+		dispatcher.lock.Unlock()
+		return
+	}
+	webhook, subscribed := dispatcher.webhooks[delivery.WebhookID]
+	if !subscribed {
+		dispatcher.lock.Unlock()
+		return
+	}
+	payload := delivery.payload
+	dispatcher.lock.Unlock()
+
+	result := dispatcher.send(webhook, delivery.ID, delivery.EventType, payload)
+
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	delivery.Attempts = append(delivery.Attempts, result)
+	switch {
+	case result.Error == "":
+		delivery.Status = deliverySucceeded
+		delivery.payload = nil
+	case len(delivery.Attempts) >= dispatcher.maxAttempts:
+		Logger.Warnf("Webhook delivery '%s' failed %d times, moving it to the dead letters", delivery.ID, len(delivery.Attempts))
+		dispatcher.bury(delivery)
+	default:
+		dispatcher.schedule(delivery.ID, dispatcher.retryDelay(len(delivery.Attempts)))
+	}
+}
+
+// Gives up the delivery, moving it to the dead letters, the caller must hold the lock
+func (dispatcher *webhookDispatcher) bury(delivery *Delivery) {
+	delivery.Status = deliveryDead
+	dispatcher.deadLetters = append(dispatcher.deadLetters, delivery.ID)
+	if len(dispatcher.deadLetters) > deliveryLogSize {
+		delete(dispatcher.deliveries, dispatcher.deadLetters[0])
+		dispatcher.deadLetters = dispatcher.deadLetters[1:]
+	}
+}
+
+// Posts the payload, signed with the webhook secret
+func (dispatcher *webhookDispatcher) send(webhook Webhook, deliveryID string, eventType string, payload []byte) DeliveryAttempt {
+	start := time.Now()
+	result := DeliveryAttempt{Time: start.UTC()}
+
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cats-api-webhooks/"+version)
+	req.Header.Set("X-Cats-Event", eventType)
+	req.Header.Set("X-Cats-Delivery", deliveryID)
+	req.Header.Set("X-Cats-Timestamp", timestamp)
+	req.Header.Set("X-Cats-Signature", "sha256="+signWebhookPayload(webhook.Secret, timestamp, payload))
+
+	res, err := dispatcher.client.Do(req)
+	result.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	res.Body.Close()
+
+	result.StatusCode = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		result.Error = "unexpected status " + res.Status
+	}
+	return result
+}
+
+// HMAC-SHA256 of "<timestamp>.<payload>", so that receivers can also reject replayed deliveries
+func signWebhookPayload(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,89 @@
+package catsapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+var webhookEventTypes = []string{"created", "updated", "deleted"}
+
+// Hides the secret, it is only known by the integrator and the dispatcher
+func redactWebhook(webhook Webhook) Webhook {
+	webhook.Secret = ""
+	return webhook
+}
+
+func (webhook Webhook) Validate() error {
+	target, err := url.Parse(webhook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return badRequest("Invalid URL, expecting an absolute http(s) URL")
+	}
+	if len(webhook.Events) == 0 {
+		return badRequest("Missing the events to subscribe to")
+	}
+	for _, eventType := range webhook.Events {
+		if !slices.Contains(webhookEventTypes, eventType) {
+			return badRequest("Invalid event type, expecting created, updated or deleted")
+		}
+	}
+	if len(webhook.Secret) < 16 {
+		return badRequest("The secret must be at least 16 characters long")
+	}
+	return nil
+}
+
+func createWebhook(ctx context.Context, req *http.Request, webhook Webhook) (Webhook, error) {
+	webhook.Tenant = requestDB(ctx).tenant
+	webhook = webhooks.Add(webhook)
+	Logger.Infof("Webhook '%s' registered for %v", webhook.ID, webhook.Events)
+	return redactWebhook(webhook), nil
+}
+
+func listWebhooks(ctx context.Context, req *http.Request, _ struct{}) ([]Webhook, error) {
+	Logger.Info("Listing the webhooks")
+
+	results := []Webhook{}
+	for _, webhook := range webhooks.List(requestDB(ctx).tenant) {
+		results = append(results, redactWebhook(webhook))
+	}
+	return results, nil
+}
+
+func getWebhook(ctx context.Context, req *http.Request, _ struct{}) (Webhook, error) {
+	webhookID := req.PathValue("webhookId")
+	Logger.Info("Getting the webhook: ", webhookID)
+
+	webhook, found := webhooks.Get(requestDB(ctx).tenant, webhookID)
+	if !found {
+		return Webhook{}, notFound("Webhook not found")
+	}
+	return redactWebhook(webhook), nil
+}
+
+func deleteWebhook(ctx context.Context, req *http.Request, _ struct{}) (struct{}, error) {
+	webhookID := req.PathValue("webhookId")
+	Logger.Info("Deleting the webhook: ", webhookID)
+
+	if !webhooks.Remove(requestDB(ctx).tenant, webhookID) {
+		return struct{}{}, notFound("Webhook not found")
+	}
+	return struct{}{}, nil
+}
+
+func listWebhookDeliveries(ctx context.Context, req *http.Request, _ struct{}) ([]Delivery, error) {
+	webhookID := req.PathValue("webhookId")
+	Logger.Info("Listing the deliveries of the webhook: ", webhookID)
+
+	tenant := requestDB(ctx).tenant
+	if _, found := webhooks.Get(tenant, webhookID); !found {
+		return nil, notFound("Webhook not found")
+	}
+	return webhooks.Deliveries(tenant, webhookID), nil
+}
+
+func listDeadLetters(ctx context.Context, req *http.Request, _ struct{}) ([]Delivery, error) {
+	Logger.Info("Listing the webhook dead letters")
+	return webhooks.DeadLetters(requestDB(ctx).tenant), nil
+}
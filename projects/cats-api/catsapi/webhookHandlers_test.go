@@ -0,0 +1,224 @@
+package catsapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+// Swaps the webhook dispatcher for one retrying quickly, with running workers.
+// It reaches the local addresses, the receivers of the tests listening on the loopback.
+func setupWebhookTest(t *testing.T, maxAttempts int) http.Handler {
+	app := setupOwnerTest(t)
+
+	originalWebhooks := webhooks
+	webhooks = newWebhookDispatcher(maxAttempts, time.Millisecond, time.Second, true)
+	stop := make(chan struct{})
+	go webhooks.Run(2, stop)
+
+	t.Cleanup(func() {
+		close(stop)
+		webhooks = originalWebhooks
+	})
+	return app
+}
+
+// Polls the condition until it is true, failing the test after a while
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatalf("Timeout waiting for %s", what)
+}
+
+// Test the signed deliveries, retried until the receiver accepts them
+func TestWebhookDelivery(t *testing.T) {
+	app := setupWebhookTest(t, 5)
+
+	var calls atomic.Int32
+	received := make(chan CatEvent, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		expected := "sha256=" + signWebhookPayload(testWebhookSecret, req.Header.Get("X-Cats-Timestamp"), body)
+		if req.Header.Get("X-Cats-Signature") != expected {
+			t.Errorf("Invalid signature %s", req.Header.Get("X-Cats-Signature"))
+		}
+
+		// Fails twice before accepting
+		if calls.Add(1) <= 2 {
+			res.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event CatEvent
+		json.Unmarshal(body, &event)
+		received <- event
+	}))
+	defer receiver.Close()
+
+	var webhook Webhook
+	code := doJSON(t, app, "POST", "/api/webhooks", `{"url": "`+receiver.URL+`", "events": ["created"], "secret": "`+testWebhookSecret+`"}`, &webhook)
+	if code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, code)
+	}
+	if webhook.Secret != "" {
+		t.Error("The secret should not be returned")
+	}
+
+	var catID string
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Toto"}`, &catID)
+	doJSON(t, app, "DELETE", "/api/cats/"+catID, "", nil)
+
+	select {
+	case event := <-received:
+		if event.Type != "created" || event.CatID != catID {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the delivery")
+	}
+
+	var deliveries []Delivery
+	waitFor(t, "the delivery log", func() bool {
+		doJSON(t, app, "GET", "/api/webhooks/"+webhook.ID+"/deliveries", "", &deliveries)
+		return len(deliveries) == 1 && deliveries[0].Status == deliverySucceeded
+	})
+	if attempts := deliveries[0].Attempts; len(attempts) != 3 || attempts[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 2 failed attempts before the success, got %+v", attempts)
+	}
+}
+
+// Test the dead-lettering of the deliveries failing too many times
+func TestWebhookDeadLetters(t *testing.T) {
+	app := setupWebhookTest(t, 3)
+
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		res.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	var webhook Webhook
+	doJSON(t, app, "POST", "/api/webhooks", `{"url": "`+receiver.URL+`", "events": ["created", "deleted"], "secret": "`+testWebhookSecret+`"}`, &webhook)
+	doJSON(t, app, "POST", "/api/cats", `{"name": "Toto"}`, nil)
+
+	var deadLetters []Delivery
+	waitFor(t, "the dead letter", func() bool {
+		doJSON(t, app, "GET", "/api/webhooks/dead-letters", "", &deadLetters)
+		return len(deadLetters) == 1
+	})
+	if deadLetters[0].Status != deliveryDead || len(deadLetters[0].Attempts) != 3 || calls.Load() != 3 {
+		t.Errorf("Expected 3 failed attempts, got %+v", deadLetters[0])
+	}
+
+	if code := doJSON(t, app, "DELETE", "/api/webhooks/"+webhook.ID, "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, code)
+	}
+	doJSON(t, app, "GET", "/api/webhooks/dead-letters", "", &deadLetters)
+	if len(deadLetters) != 1 {
+		t.Errorf("The dead letters should outlive their webhook, got %d", len(deadLetters))
+	}
+}
+
+// Test the rejected webhook registrations
+func TestCreateWebhookErrors(t *testing.T) {
+	app := setupWebhookTest(t, 1)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"relative URL", `{"url": "/hook", "events": ["created"], "secret": "` + testWebhookSecret + `"}`},
+		{"unsupported scheme", `{"url": "ftp://example.com", "events": ["created"], "secret": "` + testWebhookSecret + `"}`},
+		{"no events", `{"url": "https://example.com", "events": [], "secret": "` + testWebhookSecret + `"}`},
+		{"unknown event", `{"url": "https://example.com", "events": ["renamed"], "secret": "` + testWebhookSecret + `"}`},
+		{"short secret", `{"url": "https://example.com", "events": ["created"], "secret": "abc"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := doJSON(t, app, "POST", "/api/webhooks", tt.body, nil); code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, code)
+			}
+		})
+	}
+}
+
+// Test the retry delays growth and jitter
+func TestWebhookRetryDelay(t *testing.T) {
+	dispatcher := newWebhookDispatcher(10, time.Second, time.Second, false)
+
+	for attempts, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 60: time.Hour} {
+		delay := dispatcher.retryDelay(attempts)
+		if delay < expected/2 || delay > expected {
+			t.Errorf("Attempt %d: expected a delay between %v and %v, got %v", attempts, expected/2, expected, delay)
+		}
+	}
+}
+
+// Test that the deliveries only reach the public addresses, without following the redirects
+func TestWebhookTargets(t *testing.T) {
+	var calls atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	webhook := Webhook{Secret: testWebhookSecret}
+	refusing := newWebhookDispatcher(1, time.Second, time.Second, false)
+	for _, url := range []string{target.URL, strings.Replace(target.URL, "127.0.0.1", "localhost", 1)} {
+		webhook.URL = url
+		if result := refusing.send(webhook, "d1", "created", []byte("{}")); !strings.Contains(result.Error, "not a public one") {
+			t.Errorf("Expected the address of %s to be refused, got %+v", url, result)
+		}
+	}
+
+	webhook.URL = redirect.URL
+	result := newWebhookDispatcher(1, time.Second, time.Second, true).send(webhook, "d2", "created", []byte("{}"))
+	if result.StatusCode != http.StatusFound || result.Error == "" {
+		t.Errorf("Expected the redirect to fail the delivery, got %+v", result)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("Expected the target never to be called, got %d calls", calls.Load())
+	}
+
+	for address, public := range map[string]bool{
+		"93.184.215.14": true, "2606:4700::1111": true, "127.0.0.1": false, "10.1.2.3": false, "192.168.1.1": false,
+		"169.254.169.254": false, "100.64.0.1": false, "0.0.0.0": false, "::1": false, "fd00::1": false, "::ffff:10.1.2.3": false,
+	} {
+		if isPublicAddress(netip.MustParseAddr(address)) != public {
+			t.Errorf("Expected %s to be public: %v", address, public)
+		}
+	}
+}
+
+// Test that the deliveries are dropped to the dead letters when the queue is full, rather than waiting
+func TestWebhookQueueFull(t *testing.T) {
+	dispatcher := newWebhookDispatcher(3, time.Second, time.Second, false)
+	dispatcher.queue = make(chan string, 1)
+	dispatcher.Add(Webhook{Tenant: DefaultTenant, URL: "https://example.com", Events: []string{"created"}, Secret: testWebhookSecret})
+
+	dispatcher.Enqueue(DefaultTenant, CatEvent{ID: 1, Type: "created"})
+	dispatcher.Enqueue(DefaultTenant, CatEvent{ID: 2, Type: "created"})
+
+	deadLetters := dispatcher.DeadLetters(DefaultTenant)
+	if len(dispatcher.queue) != 1 || len(deadLetters) != 1 || deadLetters[0].EventID != 2 {
+		t.Fatalf("Expected the second delivery to be dropped, got %+v", deadLetters)
+	}
+	if attempts := deadLetters[0].Attempts; len(attempts) != 1 || !strings.Contains(attempts[0].Error, "queue is full") {
+		t.Errorf("Expected the drop to be recorded, got %+v", attempts)
+	}
+}
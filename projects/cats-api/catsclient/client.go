@@ -0,0 +1,199 @@
+// Package catsclient is the Go client of the cats API, working with the cats of its v2 routes.
+//
+//	client := catsclient.New("http://localhost:8080/api", catsclient.WithBearerToken(token))
+//	cat, err := client.CreateCat(ctx, catsclient.CatInput{Name: "Felix"})
+//	if errors.Is(err, catsclient.ErrBadRequest) {
+//		...
+//	}
+package catsclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client of the cats API, safe for a concurrent use
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	headers    http.Header
+	maxRetries int
+	retryDelay time.Duration
+}
+
+// Option changes the default settings of the client
+type Option func(client *Client)
+
+// WithHTTPClient sets the HTTP client sending the requests, for its timeout or transport
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(client *Client) { client.httpClient = httpClient }
+}
+
+// WithBearerToken authenticates the requests with the token
+func WithBearerToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithHeader adds a header to all the requests, like an API key
+func WithHeader(key, value string) Option {
+	return func(client *Client) { client.headers.Set(key, value) }
+}
+
+// WithRetries sets how many times the idempotent calls are retried on the network and
+// unavailability errors, the delay doubling after each attempt
+func WithRetries(maxRetries int, firstDelay time.Duration) Option {
+	return func(client *Client) {
+		client.maxRetries = maxRetries
+		client.retryDelay = firstDelay
+	}
+}
+
+// New creates a client of the API served at the base URL, like http://localhost:8080/api
+func New(baseURL string, options ...Option) *Client {
+	client := &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		headers:    http.Header{},
+		maxRetries: 3,
+		retryDelay: 100 * time.Millisecond,
+	}
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// Cat returned by the API
+type Cat struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	BirthDate   string     `json:"birthDate,omitempty"` // YYYY-MM-DD
+	AgeMonths   *int       `json:"ageMonths,omitempty"`
+	Color       string     `json:"color,omitempty"`
+	Sex         string     `json:"sex"`
+	Breed       string     `json:"breed,omitempty"`
+	MicrochipID string     `json:"microchipId,omitempty"`
+	OwnerID     string     `json:"ownerId,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// CatInput holds the writable fields of a cat
+type CatInput struct {
+	Name        string `json:"name"`
+	BirthDate   string `json:"birthDate,omitempty"` // YYYY-MM-DD
+	Color       string `json:"color,omitempty"`
+	Sex         string `json:"sex,omitempty"`
+	Breed       string `json:"breed,omitempty"`
+	MicrochipID string `json:"microchipId,omitempty"`
+	OwnerID     string `json:"ownerId,omitempty"`
+}
+
+// CreateCat stores a new cat, it is not retried to not create it twice
+func (client *Client) CreateCat(ctx context.Context, input CatInput) (*Cat, error) {
+	var cat Cat
+	if err := client.call(ctx, http.MethodPost, "/v2/cats", input, &cat); err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+// GetCat returns the cat, or an error matching ErrNotFound
+func (client *Client) GetCat(ctx context.Context, catID string) (*Cat, error) {
+	var cat Cat
+	if err := client.call(ctx, http.MethodGet, "/v2/cats/"+url.PathEscape(catID), nil, &cat); err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+// DeleteCat moves the cat to the trash of the API
+func (client *Client) DeleteCat(ctx context.Context, catID string) error {
+	return client.call(ctx, http.MethodDelete, "/v2/cats/"+url.PathEscape(catID), nil, nil)
+}
+
+// Sends the request, retrying the idempotent ones, and decodes the JSON response into result when not nil
+func (client *Client) call(ctx context.Context, method, path string, body any, result any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	idempotent := method != http.MethodPost && method != http.MethodPatch
+	delay := client.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := client.send(ctx, method, path, payload, result)
+		if !idempotent || attempt >= client.maxRetries || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (client *Client) send(ctx context.Context, method, path string, payload []byte, result any) error {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, client.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	for key, values := range client.headers {
+		req.Header[key] = values
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return newAPIError(res)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("invalid response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// The network errors and the unavailability of the server are transient, unlike the canceled contexts
+func retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+			return true
+		}
+		return false
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
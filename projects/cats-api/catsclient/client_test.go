@@ -0,0 +1,97 @@
+package catsclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test the typed errors, with the message and request ID of the server
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("X-Request-ID", "req-42")
+		res.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(res, `"Cat not found"`)
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL).GetCat(context.Background(), "unknown")
+	var apiErr *APIError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &apiErr) {
+		t.Fatalf("Expected a not found error, got %v", err)
+	}
+	if apiErr.Message != "Cat not found" || apiErr.RequestID != "req-42" || errors.Is(err, ErrBadRequest) {
+		t.Errorf("Unexpected error %+v", apiErr)
+	}
+}
+
+// Test that only the idempotent calls are retried, and until the context ends
+func TestRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) < 3 {
+			res.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(res, `{"id": "c1", "name": "Felix"}`)
+	}))
+	defer server.Close()
+	client := New(server.URL, WithRetries(3, time.Millisecond))
+
+	cat, err := client.GetCat(context.Background(), "c1")
+	if err != nil || cat.Name != "Felix" || calls.Load() != 3 {
+		t.Errorf("Expected the cat after 3 calls, got %v %v after %d", cat, err, calls.Load())
+	}
+
+	calls.Store(0)
+	if _, err := client.CreateCat(context.Background(), CatInput{Name: "Felix"}); !errors.Is(err, ErrUnavailable) || calls.Load() != 1 {
+		t.Errorf("Expected the creation to be sent once, got %v after %d", err, calls.Load())
+	}
+
+	calls.Store(-100)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client = New(server.URL, WithRetries(100, 20*time.Millisecond))
+	if _, err := client.GetCat(ctx, "c1"); !errors.Is(err, ErrUnavailable) || calls.Load() > -90 {
+		t.Errorf("Expected the retries to stop with the context, got %v after %d", err, calls.Load()+100)
+	}
+}
+
+// Test the iteration over the pages, with the authentication header
+func TestListCats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Query().Get("pageToken") {
+		case "":
+			fmt.Fprintln(res, `{"cats": [{"id": "c1"}, {"id": "c2"}], "nextPageToken": "p2", "totalSize": 3}`)
+		case "p2":
+			fmt.Fprintln(res, `{"cats": [{"id": "c3"}], "totalSize": 3}`)
+		}
+	}))
+	defer server.Close()
+
+	ids := []string{}
+	for cat, err := range New(server.URL, WithBearerToken("secret")).ListCats(context.Background(), ListOptions{PageSize: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, cat.ID)
+	}
+	if fmt.Sprint(ids) != "[c1 c2 c3]" {
+		t.Errorf("Expected the cats of both pages, got %v", ids)
+	}
+
+	for _, err := range New(server.URL).ListCats(context.Background(), ListOptions{}) {
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Expected an unauthorized error, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package catsclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Errors matched by the API errors of their status code, with errors.Is
+var (
+	ErrBadRequest           = errors.New("bad request")
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrRequestTooLarge      = errors.New("request too large")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrTooManyRequests      = errors.New("too many requests")
+	ErrUnavailable          = errors.New("service unavailable")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrForbidden            = errors.New("forbidden")
+	ErrInternal             = errors.New("internal server error")
+)
+
+var errorsByStatus = map[int]error{
+	http.StatusBadRequest:            ErrBadRequest,
+	http.StatusUnauthorized:          ErrUnauthorized,
+	http.StatusForbidden:             ErrForbidden,
+	http.StatusNotFound:              ErrNotFound,
+	http.StatusConflict:              ErrConflict,
+	http.StatusPreconditionFailed:    ErrPreconditionFailed,
+	http.StatusRequestEntityTooLarge: ErrRequestTooLarge,
+	http.StatusUnsupportedMediaType:  ErrUnsupportedMediaType,
+	http.StatusTooManyRequests:       ErrTooManyRequests,
+	http.StatusServiceUnavailable:    ErrUnavailable,
+	http.StatusInternalServerError:   ErrInternal,
+}
+
+// APIError is an error response of the API, with the message of the server
+type APIError struct {
+	StatusCode int
+	Message    string
+	RequestID  string
+}
+
+func (err *APIError) Error() string {
+	return fmt.Sprintf("cats API error %d: %s", err.StatusCode, err.Message)
+}
+
+// Is matches the error of the status code, like ErrNotFound
+func (err *APIError) Is(target error) bool {
+	return errorsByStatus[err.StatusCode] == target
+}
+
+// The server responds with the message as a JSON string, the proxies may send plain text
+func newAPIError(res *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: res.StatusCode, RequestID: res.Header.Get("X-Request-ID")}
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 4<<10))
+	if json.Unmarshal(data, &apiErr.Message) != nil {
+		apiErr.Message = string(data)
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(res.StatusCode)
+	}
+	return apiErr
+}
@@ -0,0 +1,73 @@
+package catsclient
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CatsPage is a page of the cats list
+type CatsPage struct {
+	Cats          []Cat  `json:"cats"`
+	NextPageToken string `json:"nextPageToken,omitempty"`
+	TotalSize     int    `json:"totalSize"`
+}
+
+// ListOptions selects the page of the cats list
+type ListOptions struct {
+	PageSize  int    // 1 to 100, the server default when 0
+	PageToken string // NextPageToken of the previous page, empty for the first one
+}
+
+// ListCatsPage returns a single page of the cats, in their ID order
+func (client *Client) ListCatsPage(ctx context.Context, options ListOptions) (*CatsPage, error) {
+	query := url.Values{}
+	if options.PageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(options.PageSize))
+	}
+	if options.PageToken != "" {
+		query.Set("pageToken", options.PageToken)
+	}
+	path := "/v2/cats"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var page CatsPage
+	if err := client.call(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ListCats iterates over all the cats, fetching the pages as needed.
+// The iteration stops after yielding an error.
+//
+//	for cat, err := range client.ListCats(ctx, catsclient.ListOptions{PageSize: 50}) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (client *Client) ListCats(ctx context.Context, options ListOptions) iter.Seq2[Cat, error] {
+	return func(yield func(Cat, error) bool) {
+		for {
+			page, err := client.ListCatsPage(ctx, options)
+			if err != nil {
+				yield(Cat{}, err)
+				return
+			}
+			for _, cat := range page.Cats {
+				if !yield(cat, nil) {
+					return
+				}
+			}
+			if page.NextPageToken == "" {
+				return
+			}
+			options.PageToken = page.NextPageToken
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package catstest runs the real cats API in-process, for the tests of the services depending on it.
+//
+//	server := catstest.NewServer(t)
+//	felix := server.SeedCats(catsapi.Cat{Name: "Felix"})[0]
+//	server.InjectFault("GET /api/v2/cats/{catId}", catstest.Fault{StatusCode: 503, Times: 1})
+//
+//	// ... exercise the code under test with server.URL or server.Client()
+//
+//	server.AssertRequested(t, "GET /api/v2/cats/{catId}", 2)
+//
+// The servers are not isolated from each other: the API keeps its data, audit trails, events and webhooks in
+// package variables, which all the servers of a test binary share. A server empties them when it starts and
+// puts the previous data back when it is closed, and it holds a package lock meanwhile, so the servers run one
+// at a time: NewServer waits until the previous server is closed, at the end of its test. The tests calling
+// t.Parallel are therefore still run one after the other while they have a server, and a test and its
+// subtests cannot run two servers at once, the second NewServer failing the test. The code under test must
+// not reach the catsapi package variables directly either, since the server owns them until it is closed.
+package catstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/catsapi"
+	"backend/catsclient"
+
+	"github.com/google/uuid"
+)
+
+// Held by the running server until the cleanup of its test, which also runs when the test panics
+var storeLock sync.Mutex
+
+// Name of the test running the server, guarded by ownerLock
+var (
+	ownerLock sync.Mutex
+	owner     string
+)
+
+// Server is the cats API served on a local port, over the package data of catsapi emptied for it
+type Server struct {
+	URL string
+
+	httpServer *httptest.Server
+	lock       sync.Mutex
+	faults     []*routeFault
+	requests   []Request
+}
+
+// Fault changes the responses of a route: Latency delays them, and a non-zero StatusCode replaces
+// them by an error with the Message. Times limits the number of faulty requests, 0 for all of them.
+type Fault struct {
+	Latency    time.Duration
+	StatusCode int
+	Message    string
+	Times      int
+}
+
+type routeFault struct {
+	Fault
+	matcher *http.ServeMux
+	used    int
+}
+
+// Request received by the server
+type Request struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// NewServer starts the API with empty databases, no audit trail, events nor webhooks, closed at the end of the test.
+// It waits for the server of another test to be closed first, the data of catsapi being shared.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	// Its server would only be closed after this one, waiting for it would never end
+	ownerLock.Lock()
+	running := owner
+	ownerLock.Unlock()
+	if running != "" && (t.Name() == running || strings.HasPrefix(t.Name(), running+"/")) {
+		t.Fatalf("catstest: the server of %s is still running, a test and its subtests can only run one server at a time", running)
+	}
+
+	storeLock.Lock()
+	ownerLock.Lock()
+	owner = t.Name()
+	ownerLock.Unlock()
+	original := catsapi.TakeSnapshot()
+	catsapi.ResetStore()
+	catsapi.RestoreSnapshot(catsapi.Snapshot{Tenants: map[string]catsapi.TenantData{catsapi.DefaultTenant: {}}})
+
+	server := &Server{}
+	server.httpServer = httptest.NewServer(server.intercept(catsapi.NewApp()))
+	server.URL = server.httpServer.URL
+
+	t.Cleanup(func() {
+		server.httpServer.Close()
+		catsapi.ResetStore()
+		catsapi.RestoreSnapshot(original)
+		ownerLock.Lock()
+		owner = ""
+		ownerLock.Unlock()
+		storeLock.Unlock()
+	})
+	return server
+}
+
+// Client returns a client of the API, without retries unless set by the options
+func (server *Server) Client(options ...catsclient.Option) *catsclient.Client {
+	options = append([]catsclient.Option{catsclient.WithRetries(0, 0)}, options...)
+	return catsclient.New(server.URL+"/api", options...)
+}
+
+// SeedCats stores the cats of the default tenant, setting the missing IDs and change times, and returns them
+func (server *Server) SeedCats(cats ...catsapi.Cat) []catsapi.Cat {
+	snapshot := catsapi.TakeSnapshot()
+	store := snapshot.Tenants[catsapi.DefaultTenant]
+	now := time.Now().UTC()
+	for i := range cats {
+		if cats[i].ID == "" {
+			cats[i].ID = uuid.New().String()
+		}
+		if cats[i].CreatedAt == nil {
+			cats[i].CreatedAt = &now
+		}
+		if cats[i].UpdatedAt == nil {
+			cats[i].UpdatedAt = cats[i].CreatedAt
+		}
+		store.Cats[cats[i].ID] = cats[i]
+	}
+	catsapi.RestoreSnapshot(snapshot)
+	return cats
+}
+
+// SeedOwners stores the owners of the default tenant, setting the missing IDs, and returns them
+func (server *Server) SeedOwners(owners ...catsapi.Owner) []catsapi.Owner {
+	snapshot := catsapi.TakeSnapshot()
+	store := snapshot.Tenants[catsapi.DefaultTenant]
+	for i := range owners {
+		if owners[i].ID == "" {
+			owners[i].ID = uuid.New().String()
+		}
+		store.Owners[owners[i].ID] = owners[i]
+	}
+	catsapi.RestoreSnapshot(snapshot)
+	return owners
+}
+
+// Store returns a copy of the data of the default tenant, the one of the requests which do not tell their tenant
+func (server *Server) Store() catsapi.TenantData {
+	return catsapi.TakeSnapshot().Tenants[catsapi.DefaultTenant]
+}
+
+// InjectFault makes the requests matching the pattern faulty, the patterns being the ones of
+// http.ServeMux like "GET /api/v2/cats/{catId}". The first matching fault applies.
+func (server *Server) InjectFault(pattern string, fault Fault) {
+	matcher := http.NewServeMux()
+	matcher.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.faults = append(server.faults, &routeFault{Fault: fault, matcher: matcher})
+}
+
+// ClearFaults removes all the injected faults
+func (server *Server) ClearFaults() {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.faults = nil
+}
+
+// Requests returns the received requests matching the pattern, all of them when it is empty
+func (server *Server) Requests(pattern string) []Request {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	if pattern == "" {
+		return append([]Request{}, server.requests...)
+	}
+	matcher := http.NewServeMux()
+	matcher.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+
+	matching := []Request{}
+	for _, request := range server.requests {
+		if matches(matcher, request.Method, request.URL) {
+			matching = append(matching, request)
+		}
+	}
+	return matching
+}
+
+// AssertRequested fails the test when the number of requests matching the pattern differs
+func (server *Server) AssertRequested(t testing.TB, pattern string, expected int) {
+	t.Helper()
+	if requests := server.Requests(pattern); len(requests) != expected {
+		t.Errorf("Expected %d requests matching %q, got %d", expected, pattern, len(requests))
+	}
+}
+
+// Records the requests and applies the faults, before the API handles the requests
+func (server *Server) intercept(app http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		server.lock.Lock()
+		server.requests = append(server.requests, Request{
+			Method: req.Method,
+			URL:    req.URL,
+			Header: req.Header.Clone(),
+			Body:   body,
+		})
+		fault := server.findFault(req)
+		server.lock.Unlock()
+
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-req.Context().Done():
+				return
+			}
+		}
+		if fault.StatusCode != 0 {
+			message := fault.Message
+			if message == "" {
+				message = http.StatusText(fault.StatusCode)
+			}
+			res.Header().Set("Content-Type", "application/json")
+			res.WriteHeader(fault.StatusCode)
+			json.NewEncoder(res).Encode(message)
+			return
+		}
+		app.ServeHTTP(res, req)
+	})
+}
+
+// Returns the first fault of the request, counting its use. The caller must hold the server lock.
+func (server *Server) findFault(req *http.Request) Fault {
+	for _, fault := range server.faults {
+		if fault.Times > 0 && fault.used >= fault.Times {
+			continue
+		}
+		if matches(fault.matcher, req.Method, req.URL) {
+			fault.used++
+			return fault.Fault
+		}
+	}
+	return Fault{}
+}
+
+func matches(matcher *http.ServeMux, method string, target *url.URL) bool {
+	_, pattern := matcher.Handler(&http.Request{Method: method, URL: target, Host: "localhost"})
+	return pattern != ""
+}
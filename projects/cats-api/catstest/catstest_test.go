@@ -0,0 +1,162 @@
+package catstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"backend/catsapi"
+	"backend/catsclient"
+)
+
+// Test the seeding of the isolated store, and its content after the calls
+func TestServerStore(t *testing.T) {
+	server := NewServer(t)
+	owner := server.SeedOwners(catsapi.Owner{Name: "Alice"})[0]
+	felix := server.SeedCats(catsapi.Cat{Name: "Felix", OwnerID: owner.ID})[0]
+
+	cat, err := server.Client().GetCat(context.Background(), felix.ID)
+	if err != nil || cat.Name != "Felix" || cat.OwnerID != owner.ID {
+		t.Fatalf("Expected the seeded cat, got %+v: %v", cat, err)
+	}
+
+	created, err := server.Client().CreateCat(context.Background(), catsclient.CatInput{Name: "Zaza"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store := server.Store(); len(store.Cats) != 2 || store.Cats[created.ID].Name != "Zaza" {
+		t.Errorf("Expected the 2 cats in the store, got %+v", store.Cats)
+	}
+}
+
+// Test that the servers do not see the data, the audit trail nor the webhooks of the previous ones
+func TestServerReset(t *testing.T) {
+	t.Run("first", func(t *testing.T) {
+		server := NewServer(t)
+		if _, err := server.Client().CreateCat(context.Background(), catsclient.CatInput{Name: "Felix"}); err != nil {
+			t.Fatal(err)
+		}
+		webhook := `{"url": "https://example.com/hook", "events": ["created"], "secret": "0123456789abcdef"}`
+		if res, err := http.Post(server.URL+"/api/webhooks", "application/json", strings.NewReader(webhook)); err != nil || res.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected the webhook to be registered, got %v: %v", res, err)
+		}
+	})
+	t.Run("second", func(t *testing.T) {
+		server := NewServer(t)
+		page, err := server.Client().ListCatsPage(context.Background(), catsclient.ListOptions{})
+		if err != nil || page.TotalSize != 0 {
+			t.Errorf("Expected an empty store, got %+v: %v", page, err)
+		}
+		for _, path := range []string{"/api/audit", "/api/webhooks"} {
+			var items []any
+			res, err := http.Get(server.URL + path)
+			if err == nil {
+				err = json.NewDecoder(res.Body).Decode(&items)
+				res.Body.Close()
+			}
+			if err != nil || len(items) != 0 {
+				t.Errorf("Expected %s to be empty, got %v: %v", path, items, err)
+			}
+		}
+	})
+}
+
+// Test that the parallel tests having a server run one after the other
+func TestServerSerialized(t *testing.T) {
+	var running, overlaps atomic.Int32
+	t.Run("group", func(t *testing.T) {
+		for _, name := range []string{"a", "b", "c"} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				NewServer(t)
+				if running.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				time.Sleep(10 * time.Millisecond)
+				running.Add(-1)
+			})
+		}
+	})
+	if overlaps.Load() != 0 {
+		t.Errorf("Expected the servers to run one at a time, %d overlapped", overlaps.Load())
+	}
+}
+
+// Records the failure of the test instead of failing it
+type fatalRecorder struct {
+	testing.TB
+	name    string
+	message string
+}
+
+func (recorder *fatalRecorder) Name() string {
+	return recorder.name
+}
+
+func (recorder *fatalRecorder) Helper() {}
+
+func (recorder *fatalRecorder) Fatalf(format string, args ...any) {
+	recorder.message = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// Test that a subtest starting a second server fails rather than waiting forever for the first one
+func TestServerNested(t *testing.T) {
+	NewServer(t)
+
+	recorder := &fatalRecorder{TB: t, name: t.Name() + "/nested"}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewServer(recorder)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the second server")
+	}
+	if !strings.Contains(recorder.message, "still running") {
+		t.Errorf("Expected the second server to fail the test, got %q", recorder.message)
+	}
+}
+
+// Test the errors and latency injected on a route, and the recorded requests
+func TestServerFaults(t *testing.T) {
+	server := NewServer(t)
+	felix := server.SeedCats(catsapi.Cat{Name: "Felix"})[0]
+	client := server.Client(catsclient.WithRetries(2, time.Millisecond))
+	ctx := context.Background()
+
+	server.InjectFault("GET /api/v2/cats/{catId}", Fault{StatusCode: 503, Message: "Maintenance", Times: 2})
+	if cat, err := client.GetCat(ctx, felix.ID); err != nil || cat.Name != "Felix" {
+		t.Errorf("Expected the cat after the retries, got %+v: %v", cat, err)
+	}
+	server.AssertRequested(t, "GET /api/v2/cats/{catId}", 3)
+
+	server.InjectFault("POST /api/v2/cats", Fault{StatusCode: 500})
+	var apiErr *catsclient.APIError
+	if _, err := client.CreateCat(ctx, catsclient.CatInput{Name: "Zaza"}); !errors.As(err, &apiErr) || apiErr.Message != "Internal Server Error" {
+		t.Errorf("Expected the injected error, got %v", err)
+	}
+	if requests := server.Requests("POST /api/v2/cats"); len(requests) != 1 || string(requests[0].Body) != `{"name":"Zaza"}` {
+		t.Errorf("Expected the creation request to be recorded, got %+v", requests)
+	}
+
+	server.ClearFaults()
+	server.InjectFault("/api/v2/cats", Fault{Latency: time.Second})
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.ListCatsPage(timeoutCtx, catsclient.ListOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the call to time out, got %v", err)
+	}
+	if len(server.Requests("")) != 5 {
+		t.Errorf("Expected 5 requests, got %d", len(server.Requests("")))
+	}
+}
@@ -0,0 +1,39 @@
+//go:build integration
+
+package apitests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"backend/catsclient"
+)
+
+func TestClientCRUDWorkflow(t *testing.T) {
+	client := catsclient.New(baseUrl)
+	ctx := context.Background()
+
+	cat, err := client.CreateCat(ctx, catsclient.CatInput{Name: "Garfield", Color: "Orange", BirthDate: "2022-06-19"})
+	if err != nil {
+		t.Fatal("Create error", err)
+	}
+
+	found := false
+	for listed, err := range client.ListCats(ctx, catsclient.ListOptions{PageSize: 1}) {
+		if err != nil {
+			t.Fatal("List error", err)
+		}
+		found = found || listed.ID == cat.ID
+	}
+	if !found {
+		t.Error("Expected the created cat in the list", cat.ID)
+	}
+
+	if err := client.DeleteCat(ctx, cat.ID); err != nil {
+		t.Fatal("Delete error", err)
+	}
+	if _, err := client.GetCat(ctx, cat.ID); !errors.Is(err, catsclient.ErrNotFound) {
+		t.Error("Expected the deleted cat to be not found, got", err)
+	}
+}
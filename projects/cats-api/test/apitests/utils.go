@@ -21,7 +21,8 @@ var baseUrl = "http://localhost:8080/api"
 // Global client with a proper timeout
 var client = &http.Client{Timeout: 10 * time.Second}
 
-// Wrapper to HTTP API calls, does the error handling and JSON decoding
+// Wrapper to the v1 HTTP API calls, does the error handling and JSON decoding.
+// The v2 cats are tested through the catsclient package.
 func call(method, path string, reqBody any, code *int, result any) error {
 
 	jsonBody, err := json.Marshal(reqBody)
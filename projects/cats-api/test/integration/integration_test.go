@@ -68,11 +68,11 @@ func TestYml2JsonWithRealFile(t *testing.T) {
 	// Test the actual yml2json function with the real openapi.yml file
 	root := getProjectRoot()
 
-	// Change to project directory
+	// Change to the directory of the API package, holding the specification
 	originalDir, _ := os.Getwd()
 	defer os.Chdir(originalDir)
 
-	err := os.Chdir(root)
+	err := os.Chdir(filepath.Join(root, "catsapi"))
 	if err != nil {
 		t.Fatalf("Failed to change directory: %v", err)
 	}
@@ -0,0 +1,151 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const replicationSecret = "integration-secret"
+
+// Replica of the cluster, a server process on localhost
+type replicaProcess struct {
+	address string
+	cmd     *exec.Cmd
+}
+
+// Starts the replicas on consecutive ports, the first one being the given port
+func startReplicas(t *testing.T, binary string, count, port int) []*replicaProcess {
+	peers := []string{}
+	for i := range count {
+		peers = append(peers, fmt.Sprintf("127.0.0.1:%d", port+i))
+	}
+
+	stateDir := t.TempDir()
+	replicas := []*replicaProcess{}
+	for i, peer := range peers {
+		cmd := exec.Command(binary, "serve", "-empty")
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("PORT=%d", port+i),
+			fmt.Sprintf("GRPC_PORT=%d", port+100+i),
+			"REPLICATION_PEERS="+strings.Join(peers, ","),
+			"REPLICATION_SECRET="+replicationSecret,
+			"REPLICATION_ELECTION_TIMEOUT=300ms",
+			"REPLICATION_STATE_FILE="+filepath.Join(stateDir, fmt.Sprintf("replica-%d.json", i)),
+		)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Failed to start the replica %s: %v", peer, err)
+		}
+		replica := &replicaProcess{peer, cmd}
+		t.Cleanup(func() { replica.stop() })
+		replicas = append(replicas, replica)
+	}
+	return replicas
+}
+
+func (replica *replicaProcess) stop() {
+	if replica.cmd.ProcessState == nil {
+		replica.cmd.Process.Kill()
+		replica.cmd.Wait()
+	}
+}
+
+// Waits for one of the running replicas to lead
+func waitForLeader(t *testing.T, replicas []*replicaProcess) *replicaProcess {
+	t.Helper()
+	for deadline := time.Now().Add(15 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		for _, replica := range replicas {
+			if replica.cmd.ProcessState != nil {
+				continue
+			}
+			req, _ := http.NewRequest("GET", "http://"+replica.address+"/internal/replication/status", nil)
+			req.Header.Set("X-Replication-Secret", replicationSecret)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				continue
+			}
+			var status struct {
+				Role string `json:"role"`
+			}
+			json.NewDecoder(res.Body).Decode(&status)
+			res.Body.Close()
+			if status.Role == "leader" {
+				return replica
+			}
+		}
+	}
+	t.Fatal("No leader elected")
+	return nil
+}
+
+func createCat(t *testing.T, replica *replicaProcess, name string) string {
+	t.Helper()
+	res, err := http.Post("http://"+replica.address+"/api/v2/cats", "application/json", strings.NewReader(`{"name": "`+name+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var cat struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&cat); err != nil || res.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected the cat to be created through %s, got %d: %v", replica.address, res.StatusCode, err)
+	}
+	return cat.ID
+}
+
+func expectCat(t *testing.T, replica *replicaProcess, catID string) {
+	t.Helper()
+	res, err := http.Get("http://" + replica.address + "/api/v2/cats/" + catID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected the cat %s on %s, got %d", catID, replica.address, res.StatusCode)
+	}
+}
+
+// Test that the replicas agree on the cats, through the forwarding of the writes and a failover
+func TestReplicatedCluster(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "backend")
+	build := exec.Command("go", "build", "-o", binary, ".")
+	build.Dir = getProjectRoot()
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build the application: %v\n%s", err, output)
+	}
+
+	replicas := startReplicas(t, binary, 3, 18481)
+	leader := waitForLeader(t, replicas)
+	var follower *replicaProcess
+	for _, replica := range replicas {
+		if replica != leader {
+			follower = replica
+		}
+	}
+
+	// Written through a follower, read right away from all the replicas
+	catID := createCat(t, follower, "Felix")
+	for _, replica := range replicas {
+		expectCat(t, replica, catID)
+	}
+
+	leader.stop()
+	newLeader := waitForLeader(t, replicas)
+	for _, replica := range replicas {
+		if replica != leader && replica != newLeader {
+			follower = replica
+		}
+	}
+	otherID := createCat(t, follower, "Zaza")
+	for _, replica := range []*replicaProcess{newLeader, follower} {
+		expectCat(t, replica, catID)
+		expectCat(t, replica, otherID)
+	}
+}